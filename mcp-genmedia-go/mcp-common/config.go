@@ -3,9 +3,12 @@
 package common
 
 import (
+	"encoding/json"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +18,110 @@ type Config struct {
 	Location       string
 	GenmediaBucket string
 	ApiEndpoint    string // New field
+	// PollInterval is how often long-running operations (e.g., Veo) are polled.
+	PollInterval time.Duration
+	// MaxWait is the maximum time to wait for a long-running operation before giving up.
+	MaxWait time.Duration
+	// MaxRetryAttempts is the maximum number of attempts for API calls that fail with a
+	// transient error. A value of 1 disables retries.
+	MaxRetryAttempts int
+	// MaxConcurrency is the maximum number of long-running generation operations (e.g., Veo)
+	// a server starts and polls at the same time. Further requests wait for a free slot.
+	MaxConcurrency int
+	// VeoPricePerSecond maps a Veo model name or alias, optionally suffixed with
+	// ":<resolution>" (e.g., "veo-3.0-fast-generate-001:1080p"), to a price per generated
+	// second of video. Servers pass it to ApplyModelPrices at startup to override the default
+	// prices in SupportedVeoModels. It is used for rough cost estimates only.
+	VeoPricePerSecond map[string]float64
+	// ImagenPricePerImage maps an Imagen model name or alias to a price per generated image,
+	// overriding the default prices in SupportedImagenModels through ApplyModelPrices.
+	ImagenPricePerImage map[string]float64
+	// ClampNumVideos controls what happens when more videos are requested than a model
+	// supports: if true the count is reduced to the model's maximum, otherwise the request
+	// is rejected.
+	ClampNumVideos bool
+	// ClampNumImages is the Imagen equivalent of ClampNumVideos.
+	ClampNumImages bool
+	// StrictFrameMimeTypes makes interpolation requests whose first and last frames have
+	// different MIME types fail instead of proceeding with a warning.
+	StrictFrameMimeTypes bool
+	// RequireMimeTypes makes requests with GCS input, frame, or reference images fail when the
+	// MIME type is not passed explicitly, instead of inferring it from the extension.
+	RequireMimeTypes bool
+	// CheckInputImages makes image-to-video and interpolation requests check that input
+	// images given as GCS URIs exist and are readable before calling the API, so that a bad
+	// URI fails with a clear error. Disable it to save the extra GCS request.
+	CheckInputImages bool
+	// CheckFrameDimensions makes interpolation requests read the image headers of the first
+	// and last frames (from GCS if needed) and compare their aspect ratios. It is off by
+	// default because fetching the headers adds latency.
+	CheckFrameDimensions bool
+	// StrictFrameDimensions makes interpolation requests whose first and last frames have
+	// different aspect ratios fail instead of proceeding with a warning. It only applies
+	// when CheckFrameDimensions is enabled.
+	StrictFrameDimensions bool
+	// KeepUploadedInputs controls what happens to local input images that are uploaded to
+	// GCS before generation: if true they are kept and reused by later requests for the same
+	// image, otherwise they are deleted once the request finishes.
+	KeepUploadedInputs bool
+	// GCSDatePrefix inserts a YYYY/MM/DD folder, computed in UTC when each request is made,
+	// between the bucket and the rest of the GCS output path (e.g., gs://bucket/2025/01/15/veo_outputs/).
+	GCSDatePrefix bool
+	// VeoDefaultModel is the Veo model, by name or alias, used when a request does not
+	// specify one. Servers validate it at startup; empty means the built-in default.
+	VeoDefaultModel string
+	// RecentOutputs is how many of the most recent generations a server remembers for its
+	// recent outputs resource.
+	RecentOutputs int
+	// ResultCache selects where the results of generation requests are cached so that an
+	// identical request can return the earlier outputs without calling the API: "memory"
+	// for an in-process cache, or empty (or "none") to disable caching.
+	ResultCache string
+	// ResultCacheTTL is how long a cached result is reused.
+	ResultCacheTTL time.Duration
+	// DownloadChunkSize is the number of bytes requested per range read when downloading
+	// outputs from GCS to a local directory.
+	DownloadChunkSize int
+	// DownloadMaxAttempts is the maximum number of attempts for each chunk of a download. A
+	// value of 1 disables retries.
+	DownloadMaxAttempts int
+	// ModelAllowlist lists the models, by canonical name or alias, that servers expose. Servers
+	// pass it to ApplyModelAllowlist at startup; empty means every supported model.
+	ModelAllowlist []string
+	// MaxInputImageBytes is the largest input image, in bytes, that servers accept as a local
+	// file or inline data. Larger images are rejected before calling the API.
+	MaxInputImageBytes int
+	// PromptStyles maps a style tag (e.g., "cinematic") to the text appended to a prompt when
+	// a request asks for that style. Tags are lowercase.
+	PromptStyles map[string]string
+	// IdempotencyTTL is how long the result of a completed request made with an idempotency
+	// key is returned to later requests with the same key.
+	IdempotencyTTL time.Duration
+	// VideoThumbnails controls whether a JPEG poster frame is extracted from each video saved
+	// to a local directory. It is off by default because it needs ffmpeg and extra processing.
+	VideoThumbnails bool
+	// ModeratePrompts makes the Veo generation tools screen each prompt with a Gemini model
+	// before generating, and refuse prompts that it flags.
+	ModeratePrompts bool
+	// ModerationModel is the Gemini model, by name or alias, that screens prompts.
+	ModerationModel string
+	// ModerationLocation is the location of the client that calls ModerationModel. It is
+	// separate from Location because Gemini models may not be served where Veo runs.
+	ModerationLocation string
+}
+
+// DefaultMaxInputImageBytes is the default for MaxInputImageBytes.
+const DefaultMaxInputImageBytes = 10 << 20
+
+// DefaultPromptStyles are the built-in PromptStyles. GENMEDIA_PROMPT_STYLES can override, add,
+// or remove styles.
+var DefaultPromptStyles = map[string]string{
+	"anime":       "Anime style: hand-drawn 2D animation, cel shading, vibrant colors, and expressive characters.",
+	"cinematic":   "Cinematic style: dramatic lighting, shallow depth of field, anamorphic lens flares, and subtle film grain.",
+	"claymation":  "Claymation style: stop-motion animation of handmade clay figures with visible textures.",
+	"documentary": "Documentary style: natural lighting, handheld camera, and realistic observational framing.",
+	"noir":        "Film noir style: black and white, high-contrast low-key lighting, deep shadows, and a moody atmosphere.",
+	"watercolor":  "Watercolor style: soft painted textures, bleeding edges, and a muted pastel palette.",
 }
 
 func LoadConfig() *Config {
@@ -39,10 +146,39 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		ProjectID:      projectID,
-		Location:       GetEnv("LOCATION", "us-central1"),
-		GenmediaBucket: genmediaBucket,
-		ApiEndpoint:    os.Getenv("VERTEX_API_ENDPOINT"), // Use os.Getenv for optional value
+		ProjectID:             projectID,
+		Location:              GetEnv("LOCATION", "us-central1"),
+		GenmediaBucket:        genmediaBucket,
+		ApiEndpoint:           os.Getenv("VERTEX_API_ENDPOINT"), // Use os.Getenv for optional value
+		PollInterval:          GetEnvDuration("GENMEDIA_POLL_INTERVAL", 15*time.Second),
+		MaxWait:               GetEnvDuration("GENMEDIA_MAX_WAIT", 5*time.Minute),
+		MaxRetryAttempts:      GetEnvInt("GENMEDIA_MAX_RETRY_ATTEMPTS", 3),
+		MaxConcurrency:        GetEnvInt("GENMEDIA_MAX_CONCURRENCY", 4),
+		VeoPricePerSecond:     GetEnvFloatMap("GENMEDIA_VEO_PRICE_PER_SECOND"),
+		ImagenPricePerImage:   GetEnvFloatMap("GENMEDIA_IMAGEN_PRICE_PER_IMAGE"),
+		ClampNumVideos:        GetEnvBool("GENMEDIA_CLAMP_NUM_VIDEOS", true),
+		ClampNumImages:        GetEnvBool("GENMEDIA_CLAMP_NUM_IMAGES", true),
+		StrictFrameMimeTypes:  GetEnvBool("GENMEDIA_STRICT_FRAME_MIME_TYPES", false),
+		RequireMimeTypes:      GetEnvBool("GENMEDIA_REQUIRE_MIME_TYPES", false),
+		CheckInputImages:      GetEnvBool("GENMEDIA_CHECK_INPUT_IMAGES", true),
+		CheckFrameDimensions:  GetEnvBool("GENMEDIA_CHECK_FRAME_DIMENSIONS", false),
+		StrictFrameDimensions: GetEnvBool("GENMEDIA_STRICT_FRAME_DIMENSIONS", false),
+		KeepUploadedInputs:    GetEnvBool("GENMEDIA_KEEP_UPLOADED_INPUTS", false),
+		GCSDatePrefix:         GetEnvBool("GENMEDIA_GCS_DATE_PREFIX", false),
+		RecentOutputs:         GetEnvInt("GENMEDIA_RECENT_OUTPUTS", 20),
+		VeoDefaultModel:       os.Getenv("GENMEDIA_VEO_DEFAULT_MODEL"),
+		ResultCache:           os.Getenv("GENMEDIA_RESULT_CACHE"),
+		ResultCacheTTL:        GetEnvDuration("GENMEDIA_RESULT_CACHE_TTL", 24*time.Hour),
+		DownloadChunkSize:     GetEnvInt("GENMEDIA_DOWNLOAD_CHUNK_SIZE", int(DefaultDownloadOptions.ChunkSize)),
+		DownloadMaxAttempts:   GetEnvInt("GENMEDIA_DOWNLOAD_MAX_ATTEMPTS", DefaultDownloadOptions.MaxAttempts),
+		ModelAllowlist:        GetEnvList("GENMEDIA_MODEL_ALLOWLIST"),
+		MaxInputImageBytes:    GetEnvInt("GENMEDIA_MAX_INPUT_IMAGE_BYTES", DefaultMaxInputImageBytes),
+		PromptStyles:          loadPromptStyles("GENMEDIA_PROMPT_STYLES"),
+		IdempotencyTTL:        GetEnvDuration("GENMEDIA_IDEMPOTENCY_TTL", time.Hour),
+		VideoThumbnails:       GetEnvBool("GENMEDIA_VIDEO_THUMBNAILS", false),
+		ModeratePrompts:       GetEnvBool("GENMEDIA_MODERATE_PROMPTS", false),
+		ModerationModel:       GetEnv("GENMEDIA_MODERATION_MODEL", "gemini-3-pro-preview"),
+		ModerationLocation:    GetEnv("GENMEDIA_MODERATION_LOCATION", "global"),
 	}
 }
 
@@ -59,4 +195,121 @@ func GetEnv(key, fallback string) string {
 	    log.Printf("Environment variable %s not set or empty, using empty fallback.", key)
 	}
 	return fallback
-}
\ No newline at end of file
+}
+
+// GetEnvDuration retrieves an environment variable as a time.Duration (e.g., "30s", "10m").
+// If the variable is not set, cannot be parsed, or is not positive, it returns the fallback value.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Environment variable %s has invalid duration '%s', using fallback: %v", key, value, fallback)
+		return fallback
+	}
+	log.Printf("%s set to: %v", key, d)
+	return d
+}
+
+// GetEnvInt retrieves an environment variable as a positive integer.
+// If the variable is not set, cannot be parsed, or is not positive, it returns the fallback value.
+func GetEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Environment variable %s has invalid value '%s', using fallback: %d", key, value, fallback)
+		return fallback
+	}
+	log.Printf("%s set to: %d", key, n)
+	return n
+}
+
+// GetEnvFloatMap retrieves an environment variable holding a JSON object of numbers
+// (e.g., {"a": 0.5, "b": 1}). If the variable is not set or cannot be parsed, it returns an empty map.
+func GetEnvFloatMap(key string) map[string]float64 {
+	values := make(map[string]float64)
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return values
+	}
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		log.Printf("Environment variable %s is not a valid JSON object of numbers: %v. Ignoring it.", key, err)
+		return make(map[string]float64)
+	}
+	log.Printf("%s set with %d entries", key, len(values))
+	return values
+}
+
+// GetEnvStringMap retrieves an environment variable holding a JSON object of strings
+// (e.g., {"a": "x", "b": "y"}). If the variable is not set or cannot be parsed, it returns an empty map.
+func GetEnvStringMap(key string) map[string]string {
+	values := make(map[string]string)
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return values
+	}
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		log.Printf("Environment variable %s is not a valid JSON object of strings: %v. Ignoring it.", key, err)
+		return make(map[string]string)
+	}
+	log.Printf("%s set with %d entries", key, len(values))
+	return values
+}
+
+// loadPromptStyles returns DefaultPromptStyles updated with the JSON object of style tags to
+// prompt text in the given environment variable. Tags are trimmed and lowercased, and an
+// entry with empty text removes the style.
+func loadPromptStyles(key string) map[string]string {
+	styles := make(map[string]string, len(DefaultPromptStyles))
+	for tag, text := range DefaultPromptStyles {
+		styles[tag] = text
+	}
+	for tag, text := range GetEnvStringMap(key) {
+		tag, text = strings.ToLower(strings.TrimSpace(tag)), strings.TrimSpace(text)
+		if tag == "" {
+			continue
+		}
+		if text == "" {
+			delete(styles, tag)
+		} else {
+			styles[tag] = text
+		}
+	}
+	return styles
+}
+
+// GetEnvList retrieves an environment variable holding a comma-separated list (e.g., "a, b").
+// Entries are trimmed and empty entries are dropped. If the variable is not set, it returns nil.
+func GetEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) > 0 {
+		log.Printf("%s set to: %s", key, strings.Join(values, ", "))
+	}
+	return values
+}
+
+// GetEnvBool retrieves an environment variable as a boolean (e.g., "true", "false", "1", "0").
+// If the variable is not set or cannot be parsed, it returns the fallback value.
+func GetEnvBool(key string, fallback bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Environment variable %s has invalid boolean '%s', using fallback: %t", key, value, fallback)
+		return fallback
+	}
+	log.Printf("%s set to: %t", key, b)
+	return b
+}
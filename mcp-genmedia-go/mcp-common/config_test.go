@@ -2,7 +2,9 @@ package common
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -46,3 +48,167 @@ func TestLoadConfig(t *testing.T) {
 		
 	})
 }
+
+func TestGetEnvDuration(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		set      bool
+		expected time.Duration
+	}{
+		{"unset", "", false, 15 * time.Second},
+		{"valid", "30s", true, 30 * time.Second},
+		{"minutes", "10m", true, 10 * time.Minute},
+		{"invalid", "soon", true, 15 * time.Second},
+		{"negative", "-5s", true, 15 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv("TEST_DURATION")
+			if tc.set {
+				os.Setenv("TEST_DURATION", tc.value)
+				defer os.Unsetenv("TEST_DURATION")
+			}
+			actual := GetEnvDuration("TEST_DURATION", 15*time.Second)
+			if actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		set      bool
+		expected int
+	}{
+		{"unset", "", false, 3},
+		{"valid", "5", true, 5},
+		{"invalid", "many", true, 3},
+		{"zero", "0", true, 3},
+		{"negative", "-2", true, 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv("TEST_INT")
+			if tc.set {
+				os.Setenv("TEST_INT", tc.value)
+				defer os.Unsetenv("TEST_INT")
+			}
+			actual := GetEnvInt("TEST_INT", 3)
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetEnvFloatMap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		set      bool
+		expected map[string]float64
+	}{
+		{"unset", "", false, map[string]float64{}},
+		{"valid", `{"veo-2.0-generate-001": 0.5, "veo-3.0-fast-generate-001:1080p": 0.25}`, true, map[string]float64{"veo-2.0-generate-001": 0.5, "veo-3.0-fast-generate-001:1080p": 0.25}},
+		{"invalid", "not json", true, map[string]float64{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv("TEST_FLOAT_MAP")
+			if tc.set {
+				os.Setenv("TEST_FLOAT_MAP", tc.value)
+				defer os.Unsetenv("TEST_FLOAT_MAP")
+			}
+			actual := GetEnvFloatMap("TEST_FLOAT_MAP")
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		set      bool
+		expected bool
+	}{
+		{"unset", "", false, true},
+		{"false", "false", true, false},
+		{"zero", "0", true, false},
+		{"true", "TRUE", true, true},
+		{"invalid", "maybe", true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv("TEST_BOOL")
+			if tc.set {
+				os.Setenv("TEST_BOOL", tc.value)
+				defer os.Unsetenv("TEST_BOOL")
+			}
+			actual := GetEnvBool("TEST_BOOL", true)
+			if actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		set      bool
+		expected []string
+	}{
+		{"unset", "", false, nil},
+		{"single", "veo-3.0-generate-001", true, []string{"veo-3.0-generate-001"}},
+		{"trimmed with empty entries", " Veo 3 , ,imagen-4.0-generate-001,", true, []string{"Veo 3", "imagen-4.0-generate-001"}},
+		{"only separators", " , ", true, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Unsetenv("TEST_LIST")
+			if tc.set {
+				os.Setenv("TEST_LIST", tc.value)
+				defer os.Unsetenv("TEST_LIST")
+			}
+			actual := GetEnvList("TEST_LIST")
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadPromptStyles(t *testing.T) {
+	os.Setenv("TEST_PROMPT_STYLES", `{" Pixel Art ": "Pixel art style.", "cinematic": "Widescreen cinematic style.", "noir": ""}`)
+	defer os.Unsetenv("TEST_PROMPT_STYLES")
+
+	styles := loadPromptStyles("TEST_PROMPT_STYLES")
+	if styles["pixel art"] != "Pixel art style." {
+		t.Errorf("expected the added style to be lowercased and trimmed, but got %v", styles)
+	}
+	if styles["cinematic"] != "Widescreen cinematic style." {
+		t.Errorf("expected the built-in style to be overridden, but got '%s'", styles["cinematic"])
+	}
+	if _, ok := styles["noir"]; ok {
+		t.Errorf("expected an empty text to remove the style")
+	}
+	if styles["anime"] != DefaultPromptStyles["anime"] {
+		t.Errorf("expected the other built-in styles to be kept, but got '%s'", styles["anime"])
+	}
+	if _, ok := DefaultPromptStyles["pixel art"]; ok {
+		t.Errorf("expected DefaultPromptStyles to be left unchanged")
+	}
+}
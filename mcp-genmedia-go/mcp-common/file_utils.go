@@ -159,6 +159,22 @@ func ProcessOutputAfterFFmpeg(ctx context.Context, ffmpegOutputActualPath, final
 	return finalLocalPath, finalGCSPath, nil
 }
 
+// EnsureWritableDir creates the given directory if it does not exist and verifies
+// that files can be written to it. It is used to fail fast with a clear error before
+// downloading generated media to a local output directory.
+func EnsureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	probe, err := os.CreateTemp(dir, ".write_check_")
+	if err != nil {
+		return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
 // GetTail returns the last n lines of a string.
 func GetTail(s string, n int) string {
 	lines := strings.Split(s, "\n")
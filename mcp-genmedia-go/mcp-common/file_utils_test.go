@@ -2,6 +2,8 @@ package common
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -49,3 +51,31 @@ func TestGetTail(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureWritableDir(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "writable_dir_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Run("creates missing directory", func(t *testing.T) {
+		dir := filepath.Join(tempDir, "nested", "output")
+		if err := EnsureWritableDir(dir); err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("expected directory %s to exist, but got: %v", dir, err)
+		}
+	})
+
+	t.Run("path is a file", func(t *testing.T) {
+		filePath := filepath.Join(tempDir, "not_a_dir")
+		if err := os.WriteFile(filePath, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := EnsureWritableDir(filePath); err == nil {
+			t.Errorf("expected an error for a path that is a file, but got nil")
+		}
+	})
+}
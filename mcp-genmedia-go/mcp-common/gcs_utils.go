@@ -16,14 +16,48 @@ import (
 	"cloud.google.com/go/storage"
 )
 
-// DownloadFromGCS downloads a file from a GCS bucket to a local path.
-// It parses the GCS URI, creates a GCS client, and then reads the object's contents,
-// writing them to a new local file. It also creates the destination directory if it doesn't exist.
+// DownloadOptions controls how DownloadFromGCSWithOptions fetches an object.
+type DownloadOptions struct {
+	// ChunkSize is the number of bytes requested per range read.
+	ChunkSize int64
+	// MaxAttempts is the maximum number of attempts for each chunk. A value of 1 disables
+	// retries.
+	MaxAttempts int
+}
+
+// DefaultDownloadOptions are used by DownloadFromGCS.
+var DefaultDownloadOptions = DownloadOptions{ChunkSize: 16 << 20, MaxAttempts: 3}
+
+// downloadChunkTimeout bounds each range read of a download.
+const downloadChunkTimeout = 2 * time.Minute
+
+// downloadRetryDelay is the wait before the first retry of a failed chunk. It doubles with
+// each further attempt.
+var downloadRetryDelay = time.Second
+
+// DownloadFromGCS downloads a file from a GCS bucket to a local path using
+// DefaultDownloadOptions. See DownloadFromGCSWithOptions.
 func DownloadFromGCS(ctx context.Context, gcsURI, localDestPath string) error {
+	return DownloadFromGCSWithOptions(ctx, gcsURI, localDestPath, DefaultDownloadOptions)
+}
+
+// DownloadFromGCSWithOptions downloads a file from a GCS bucket to a local path, creating the
+// destination directory if it doesn't exist. The object is read in ranges of opts.ChunkSize
+// bytes, pinned to the generation found when the download starts; a range that fails
+// mid-stream is resumed from the last byte written, up to opts.MaxAttempts times. The data is
+// written to a ".part" file that is renamed to localDestPath only once its size matches the
+// object's, and removed if the download fails.
+func DownloadFromGCSWithOptions(ctx context.Context, gcsURI, localDestPath string, opts DownloadOptions) error {
 	bucketName, objectName, err := ParseGCSPath(gcsURI)
 	if err != nil {
 		return err
 	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultDownloadOptions.ChunkSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultDownloadOptions.MaxAttempts
+	}
 
 	client, err := storage.NewClient(ctx)
 	if err != nil {
@@ -31,32 +65,97 @@ func DownloadFromGCS(ctx context.Context, gcsURI, localDestPath string) error {
 	}
 	defer client.Close()
 
-	gcsOpCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-	defer cancel()
-	rc, err := client.Bucket(bucketName).Object(objectName).NewReader(gcsOpCtx)
+	attrsCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	attrs, err := client.Bucket(bucketName).Object(objectName).Attrs(attrsCtx)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("Object(%q).NewReader: %w", objectName, err)
+		return fmt.Errorf("Object(%q).Attrs: %w", objectName, err)
 	}
-	defer rc.Close()
+	obj := client.Bucket(bucketName).Object(objectName).Generation(attrs.Generation)
 
 	destDir := filepath.Dir(localDestPath)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return fmt.Errorf("os.MkdirAll for directory %s: %w", destDir, err)
 	}
 
-	f, err := os.Create(localDestPath)
+	partPath := localDestPath + ".part"
+	f, err := os.Create(partPath)
 	if err != nil {
 		return fmt.Errorf("os.Create: %w", err)
 	}
-	defer f.Close()
+	openRange := func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return obj.NewRangeReader(ctx, offset, length)
+	}
+	err = downloadChunks(ctx, f, attrs.Size, opts, openRange)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("closing %s: %w", partPath, closeErr)
+	}
+	if err == nil {
+		err = os.Rename(partPath, localDestPath)
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("downloading %s: %w", gcsURI, err)
+	}
+	log.Printf("Successfully downloaded %s (%d bytes) to %s", gcsURI, attrs.Size, localDestPath)
+	return nil
+}
+
+// downloadChunks copies size bytes into f, reading ranges of at most opts.ChunkSize bytes with
+// openRange. A failed range is retried from the last byte written, up to opts.MaxAttempts
+// attempts in a row, waiting downloadRetryDelay (doubled after each retry) between attempts.
+// It fails if the number of bytes written does not match size.
+func downloadChunks(ctx context.Context, f *os.File, size int64, opts DownloadOptions, openRange func(ctx context.Context, offset, length int64) (io.ReadCloser, error)) error {
+	var offset int64
+	attempt := 1
+	delay := downloadRetryDelay
+	for offset < size {
+		length := min(opts.ChunkSize, size-offset)
+		written, err := copyRange(ctx, f, offset, length, openRange)
+		offset += written
+		if err == nil && written < length {
+			err = fmt.Errorf("range at offset %d ended after %d of %d bytes", offset-written, written, length)
+		}
+		if err == nil {
+			attempt, delay = 1, downloadRetryDelay
+			continue
+		}
+		if ctx.Err() != nil || errors.Is(err, storage.ErrObjectNotExist) || attempt >= opts.MaxAttempts {
+			return fmt.Errorf("reading at offset %d (attempt %d/%d): %w", offset, attempt, opts.MaxAttempts, err)
+		}
+		log.Printf("Download failed at offset %d of %d bytes, retrying in %v (attempt %d/%d): %v", offset, size, delay, attempt, opts.MaxAttempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		attempt++
+		delay *= 2
+	}
 
-	if _, err := io.Copy(f, rc); err != nil {
-		return fmt.Errorf("io.Copy: %w", err)
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if info.Size() != size {
+		return fmt.Errorf("downloaded %d bytes, but the object is %d bytes", info.Size(), size)
 	}
-	log.Printf("Successfully downloaded %s to %s", gcsURI, localDestPath)
 	return nil
 }
 
+// copyRange writes length bytes read from offset into f at the same offset, returning the
+// number of bytes written even if the read fails partway.
+func copyRange(ctx context.Context, f *os.File, offset, length int64, openRange func(ctx context.Context, offset, length int64) (io.ReadCloser, error)) (int64, error) {
+	chunkCtx, cancel := context.WithTimeout(ctx, downloadChunkTimeout)
+	defer cancel()
+	rc, err := openRange(chunkCtx, offset, length)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(io.NewOffsetWriter(f, offset), io.LimitReader(rc, length))
+}
+
 func DownloadFromGCSAsBytes(ctx context.Context, gcsURI string) ([]byte, error) {
 	bucketName, objectName, err := ParseGCSPath(gcsURI)
 	if err != nil {
@@ -155,6 +254,222 @@ func UploadToGCS(ctx context.Context, bucketName, objectName, contentType string
 	return nil
 }
 
+// SetGCSObjectMetadata adds the given key/value pairs to the custom metadata of an
+// existing GCS object. Existing metadata keys that are not in the map are preserved.
+func SetGCSObjectMetadata(ctx context.Context, gcsURI string, metadata map[string]string) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := client.Bucket(bucketName).Object(objectName).Update(gcsOpCtx, storage.ObjectAttrsToUpdate{Metadata: metadata}); err != nil {
+		return fmt.Errorf("Object(%q).Update: %w", objectName, err)
+	}
+	log.Printf("Set %d metadata entries on %s", len(metadata), gcsURI)
+	return nil
+}
+
+// SupportedStorageClasses lists the GCS storage classes accepted by SetGCSObjectStorageClass.
+var SupportedStorageClasses = []string{"STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"}
+
+// SetGCSObjectStorageClass changes the storage class of an existing GCS object. GCS can only
+// change an object's class by rewriting it, so the object is rewritten in place; its content
+// type and custom metadata are carried over to the rewritten object.
+func SetGCSObjectStorageClass(ctx context.Context, gcsURI, storageClass string) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	// Rewriting a large video can take a while, so allow more time than a metadata update.
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	obj := client.Bucket(bucketName).Object(objectName)
+	attrs, err := obj.Attrs(gcsOpCtx)
+	if err != nil {
+		return fmt.Errorf("Object(%q).Attrs: %w", objectName, err)
+	}
+	if attrs.StorageClass == storageClass {
+		return nil
+	}
+	copier := obj.CopierFrom(obj)
+	copier.StorageClass = storageClass
+	copier.ContentType = attrs.ContentType
+	copier.Metadata = attrs.Metadata
+	if _, err := copier.Run(gcsOpCtx); err != nil {
+		return fmt.Errorf("Object(%q).CopierFrom.Run: %w", objectName, err)
+	}
+	log.Printf("Set storage class of %s to %s", gcsURI, storageClass)
+	return nil
+}
+
+// SetGCSObjectCustomTime sets the Custom-Time of an existing GCS object. Bucket lifecycle
+// rules can use the "daysSinceCustomTime" condition to act on objects once it has passed.
+// GCS does not allow an object's Custom-Time to be moved earlier once set.
+func SetGCSObjectCustomTime(ctx context.Context, gcsURI string, customTime time.Time) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := client.Bucket(bucketName).Object(objectName).Update(gcsOpCtx, storage.ObjectAttrsToUpdate{CustomTime: customTime}); err != nil {
+		return fmt.Errorf("Object(%q).Update: %w", objectName, err)
+	}
+	log.Printf("Set Custom-Time of %s to %s", gcsURI, customTime.Format(time.RFC3339))
+	return nil
+}
+
+// GCSObjectExists reports whether an object exists at the given GCS URI.
+func GCSObjectExists(ctx context.Context, gcsURI string) (bool, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return false, err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := client.Bucket(bucketName).Object(objectName).Attrs(gcsOpCtx); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Object(%q).Attrs: %w", objectName, err)
+	}
+	return true, nil
+}
+
+// GCSObjectSize returns the size in bytes of the object at the given GCS URI.
+func GCSObjectSize(ctx context.Context, gcsURI string) (int64, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	attrs, err := client.Bucket(bucketName).Object(objectName).Attrs(gcsOpCtx)
+	if err != nil {
+		return 0, fmt.Errorf("Object(%q).Attrs: %w", objectName, err)
+	}
+	return attrs.Size, nil
+}
+
+// ReadGCSObjectHeader returns up to the first maxBytes bytes of the object at the given GCS
+// URI, such as an image header, without downloading the rest of the object.
+func ReadGCSObjectHeader(ctx context.Context, gcsURI string, maxBytes int64) ([]byte, error) {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	rc, err := client.Bucket(bucketName).Object(objectName).NewRangeReader(gcsOpCtx, 0, maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Object(%q).NewRangeReader: %w", objectName, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll: %w", err)
+	}
+	return data, nil
+}
+
+// DeleteGCSObject deletes the object at the given GCS URI.
+func DeleteGCSObject(ctx context.Context, gcsURI string) error {
+	bucketName, objectName, err := ParseGCSPath(gcsURI)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := client.Bucket(bucketName).Object(objectName).Delete(gcsOpCtx); err != nil {
+		return fmt.Errorf("Object(%q).Delete: %w", objectName, err)
+	}
+	log.Printf("Deleted %s", gcsURI)
+	return nil
+}
+
+// MoveGCSObject moves (renames) an object within GCS by copying it to dstURI and then
+// deleting the source. The content type and custom metadata are carried over by the copy.
+func MoveGCSObject(ctx context.Context, srcURI, dstURI string) error {
+	srcBucket, srcObject, err := ParseGCSPath(srcURI)
+	if err != nil {
+		return err
+	}
+	dstBucket, dstObject, err := ParseGCSPath(dstURI)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+	defer client.Close()
+
+	// Copying a large video can take a while, so allow more time than a metadata update.
+	gcsOpCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	src := client.Bucket(srcBucket).Object(srcObject)
+	if _, err := client.Bucket(dstBucket).Object(dstObject).CopierFrom(src).Run(gcsOpCtx); err != nil {
+		return fmt.Errorf("Object(%q).CopierFrom(%q).Run: %w", dstObject, srcObject, err)
+	}
+	if err := src.Delete(gcsOpCtx); err != nil {
+		return fmt.Errorf("Object(%q).Delete: %w", srcObject, err)
+	}
+	log.Printf("Moved %s to %s", srcURI, dstURI)
+	return nil
+}
+
 // ParseGCSPath extracts the bucket and object names from a GCS URI.
 // It validates that the URI has the correct format (gs://bucket/object)
 // and returns the two components. This is a helper function to make working
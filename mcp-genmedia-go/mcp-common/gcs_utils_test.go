@@ -1,11 +1,17 @@
 package common
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestParseGCSPath(t *testing.T) {
@@ -91,3 +97,73 @@ func TestDownloadFromGCS(t *testing.T) {
 		t.Errorf("expected downloaded content to be '%s', but got '%s'", string(content), string(downloadedContent))
 	}
 }
+
+// flakyRangeReader serves ranges of data, failing after failAfter bytes on the reads listed
+// in failReads (by zero-based read number).
+type flakyRangeReader struct {
+	data      []byte
+	failAfter int64
+	failReads map[int]bool
+	reads     int
+	offsets   []int64
+}
+
+func (r *flakyRangeReader) open(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	read := r.reads
+	r.reads++
+	r.offsets = append(r.offsets, offset)
+	chunk := r.data[offset:min(offset+length, int64(len(r.data)))]
+	if r.failReads[read] {
+		return io.NopCloser(io.MultiReader(bytes.NewReader(chunk[:min(r.failAfter, int64(len(chunk)))]), iotest.ErrReader(errors.New("connection reset")))), nil
+	}
+	return io.NopCloser(bytes.NewReader(chunk)), nil
+}
+
+func TestDownloadChunks(t *testing.T) {
+	downloadRetryDelay = 0
+	defer func() { downloadRetryDelay = time.Second }()
+	data := []byte("0123456789abcdefghij")
+
+	testCases := []struct {
+		name            string
+		size            int64
+		maxAttempts     int
+		failReads       map[int]bool
+		expectError     bool
+		expectedOffsets []int64
+	}{
+		{"no failures", 20, 3, nil, false, []int64{0, 8, 16}},
+		{"resumes after a mid-stream failure", 20, 3, map[int]bool{1: true}, false, []int64{0, 8, 11, 19}},
+		{"gives up after max attempts", 20, 2, map[int]bool{1: true, 2: true}, true, []int64{0, 8, 11}},
+		{"object shorter than expected", 24, 1, nil, true, []int64{0, 8, 16}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "video.mp4.part"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			reader := &flakyRangeReader{data: data, failAfter: 3, failReads: tc.failReads}
+
+			err = downloadChunks(t.Context(), f, tc.size, DownloadOptions{ChunkSize: 8, MaxAttempts: tc.maxAttempts}, reader.open)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectError, err)
+			}
+			if !slices.Equal(reader.offsets, tc.expectedOffsets) {
+				t.Errorf("expected reads at offsets %v, but got %v", tc.expectedOffsets, reader.offsets)
+			}
+			if tc.expectError {
+				return
+			}
+			written, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(written, data) {
+				t.Errorf("expected '%s', but got '%s'", data, written)
+			}
+		})
+	}
+}
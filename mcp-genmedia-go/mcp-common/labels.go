@@ -0,0 +1,35 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxLabels is the maximum number of labels allowed on a Google Cloud resource.
+const MaxLabels = 64
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+)
+
+// ValidateLabels checks that labels follow the Google Cloud label constraints:
+// at most 64 labels, keys of 1-63 characters starting with a lowercase letter, and
+// keys and values containing only lowercase letters, digits, underscores, and dashes.
+// Values may be empty.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("too many labels: %d provided, but at most %d are allowed", len(labels), MaxLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key '%s': keys must be 1-63 characters, start with a lowercase letter, and contain only lowercase letters, digits, underscores, and dashes", key)
+		}
+		if !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value '%s' for label '%s': values must be at most 63 characters and contain only lowercase letters, digits, underscores, and dashes", value, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLabels(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxLabels; i++ {
+		tooMany["key"+strings.Repeat("a", i)] = "value"
+	}
+
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		expectError bool
+	}{
+		{"empty", map[string]string{}, false},
+		{"valid", map[string]string{"team": "creative-studio", "cost_center": "1234"}, false},
+		{"empty value", map[string]string{"team": ""}, false},
+		{"uppercase key", map[string]string{"Team": "a"}, true},
+		{"key starting with digit", map[string]string{"1team": "a"}, true},
+		{"empty key", map[string]string{"": "a"}, true},
+		{"invalid value character", map[string]string{"team": "a.b"}, true},
+		{"key too long", map[string]string{strings.Repeat("a", 64): "a"}, true},
+		{"value too long", map[string]string{"team": strings.Repeat("a", 64)}, true},
+		{"too many", tooMany, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLabels(tc.labels)
+			if (err != nil) != tc.expectError {
+				t.Errorf("expected error: %v, but got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package common provides shared utilities for the MCP Genmedia servers.
+
+package common
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mimeTypesByExtension maps lowercase file extensions to the MIME types of the media the
+// genmedia servers accept as inputs.
+var mimeTypesByExtension = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".bmp":  "image/bmp",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+}
+
+// InferMimeTypeFromPath returns the MIME type of a local path or URI based on its file
+// extension, or an empty string if the extension is not recognized.
+func InferMimeTypeFromPath(path string) string {
+	return mimeTypesByExtension[strings.ToLower(filepath.Ext(path))]
+}
+
+// DetectImageMimeType returns the MIME type of image data based on its content. It extends
+// http.DetectContentType, which recognizes PNG, JPEG, GIF, WebP, and BMP, with TIFF.
+func DetectImageMimeType(data []byte) string {
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+	return http.DetectContentType(data)
+}
+
+// IsAllowedMimeType reports whether mimeType is in allowed. Each tool passes its own
+// allowlist, since the accepted input types differ between APIs.
+func IsAllowedMimeType(mimeType string, allowed []string) bool {
+	return slices.Contains(allowed, mimeType)
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestInferMimeTypeFromPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"gs://bucket/image.png", "image/png"},
+		{"/tmp/image.JPG", "image/jpeg"},
+		{"image.jpeg", "image/jpeg"},
+		{"image.webp", "image/webp"},
+		{"image.gif", "image/gif"},
+		{"gs://bucket/scan.tif", "image/tiff"},
+		{"scan.TIFF", "image/tiff"},
+		{"image.bmp", "image/bmp"},
+		{"clip.mov", "video/quicktime"},
+		{"image.heic", ""},
+		{"gs://bucket/image", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if actual := InferMimeTypeFromPath(tc.path); actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDetectImageMimeType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n0000"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0"), "image/jpeg"},
+		{"bmp", []byte("BM0000"), "image/bmp"},
+		{"tiff little-endian", []byte("II*\x000000"), "image/tiff"},
+		{"tiff big-endian", []byte("MM\x00*0000"), "image/tiff"},
+		{"text", []byte("hello"), "text/plain; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := DetectImageMimeType(tc.data); actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsAllowedMimeType(t *testing.T) {
+	allowed := []string{"image/jpeg", "image/png"}
+	if !IsAllowedMimeType("image/png", allowed) {
+		t.Error("expected image/png to be allowed")
+	}
+	if IsAllowedMimeType("image/tiff", allowed) {
+		t.Error("expected image/tiff not to be allowed")
+	}
+	if IsAllowedMimeType("image/png", nil) {
+		t.Error("expected nothing to be allowed by an empty allowlist")
+	}
+}
@@ -18,10 +18,72 @@ package common
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+// --- Model Name Normalization ---
+
+// normalizeModelKey lowercases a model name or alias and strips spaces, dashes, dots, and
+// underscores, so that "nano-banana", "nano banana", and "NanoBanana" share a key. All
+// alias maps are keyed by it and every Resolve* lookup normalizes its input the same way.
+func normalizeModelKey(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch r {
+		case ' ', '-', '.', '_':
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// --- Model Deprecation ---
+
+// deprecationNote returns the marker appended to a deprecated model's entry in the
+// Build*Description output, or an empty string for models that are not deprecated.
+func deprecationNote(deprecated bool, replacedBy string) string {
+	if !deprecated {
+		return ""
+	}
+	if replacedBy != "" {
+		return fmt.Sprintf(" [DEPRECATED: use *%s*]", replacedBy)
+	}
+	return " [DEPRECATED]"
+}
+
+// deprecationWarning builds the warning included in tool results when a deprecated model is used.
+func deprecationWarning(modelName string, deprecated bool, replacedBy string) string {
+	if !deprecated {
+		return ""
+	}
+	if replacedBy != "" {
+		return fmt.Sprintf("model %s is deprecated and will be retired; migrate to %s", modelName, replacedBy)
+	}
+	return fmt.Sprintf("model %s is deprecated and will be retired", modelName)
+}
+
+// --- Prompt Length ---
+
+// CheckPromptLength returns an error if prompt is longer than maxChars characters, the
+// MaxPromptChars of modelName. The backend silently truncates overlong prompts, so handlers
+// reject them instead. A maxChars of zero or less means the model has no known limit.
+func CheckPromptLength(modelName, prompt string, maxChars int) error {
+	if maxChars <= 0 {
+		return nil
+	}
+	if length := utf8.RuneCountInString(prompt); length > maxChars {
+		return fmt.Errorf("prompt is %d characters long, which exceeds the limit of %d characters for model %s", length, maxChars, modelName)
+	}
+	return nil
+}
+
 // --- Imagen Model Configuration ---
 
 // ImagenModelInfo holds the details for a specific Imagen model.
@@ -31,6 +93,23 @@ type ImagenModelInfo struct {
 	Aliases               []string
 	SupportedAspectRatios []string
 	SupportedImageSizes   []string
+	// SupportsEdit marks models that can edit an existing image (inpainting and outpainting).
+	SupportsEdit bool
+	// SupportsUpscale marks models that can upscale an existing image.
+	SupportsUpscale bool
+	// EditOnly marks models that can only edit images and cannot generate them from text.
+	EditOnly bool
+	// MaxPromptChars is the longest prompt, in characters, that the model accepts without
+	// truncating it.
+	MaxPromptChars int
+	// PricePerImage is the price, in USD, per generated image, used for cost hints. It
+	// defaults to the Vertex AI list price and can be overridden with ApplyModelPrices. Zero
+	// means the price is unknown.
+	PricePerImage float64
+	// Deprecated marks models that are scheduled for retirement. ReplacedBy optionally
+	// names the canonical model to migrate to.
+	Deprecated bool
+	ReplacedBy string
 }
 
 // SupportedImagenModels is the single source of truth for all supported Imagen models.
@@ -41,6 +120,11 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{},
+		SupportsEdit:          false,
+		SupportsUpscale:       false,
+		MaxPromptChars:        1920,
+		Deprecated:            true,
+		ReplacedBy:            "imagen-3.0-generate-002",
 	},
 	"imagen-3.0-fast-generate-001": {
 		CanonicalName:         "imagen-3.0-fast-generate-001",
@@ -48,6 +132,10 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{"Imagen 3 Fast"},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{},
+		SupportsEdit:          false,
+		SupportsUpscale:       false,
+		MaxPromptChars:        1920,
+		PricePerImage:         0.02,
 	},
 	"imagen-3.0-generate-002": {
 		CanonicalName:         "imagen-3.0-generate-002",
@@ -55,6 +143,21 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{"Imagen 3"},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{},
+		SupportsEdit:          false,
+		SupportsUpscale:       true,
+		MaxPromptChars:        1920,
+		PricePerImage:         0.04,
+	},
+	"imagen-3.0-capability-001": {
+		CanonicalName:         "imagen-3.0-capability-001",
+		MaxImages:             4,
+		Aliases:               []string{"Imagen 3 Edit", "Imagen 3 Capability"},
+		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
+		SupportedImageSizes:   []string{},
+		SupportsEdit:          true,
+		SupportsUpscale:       false,
+		EditOnly:              true,
+		MaxPromptChars:        1920,
 	},
 	"imagen-4.0-generate-001": {
 		CanonicalName:         "imagen-4.0-generate-001",
@@ -62,6 +165,10 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{"Imagen 4", "Imagen4"},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{"1K", "2K"},
+		SupportsEdit:          false,
+		SupportsUpscale:       false,
+		MaxPromptChars:        1920,
+		PricePerImage:         0.04,
 	},
 	"imagen-4.0-fast-generate-001": {
 		CanonicalName:         "imagen-4.0-fast-generate-001",
@@ -69,6 +176,10 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{"Imagen 4 Fast", "Imagen4 Fast"},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{"1K", "2K"},
+		SupportsEdit:          false,
+		SupportsUpscale:       false,
+		MaxPromptChars:        1920,
+		PricePerImage:         0.02,
 	},
 	"imagen-4.0-ultra-generate-001": {
 		CanonicalName:         "imagen-4.0-ultra-generate-001",
@@ -76,6 +187,10 @@ var SupportedImagenModels = map[string]ImagenModelInfo{
 		Aliases:               []string{"Imagen 4 Ultra", "Imagen4 Ultra"},
 		SupportedAspectRatios: []string{"1:1", "3:4", "4:3", "9:16", "16:9"},
 		SupportedImageSizes:   []string{"1K", "2K"},
+		SupportsEdit:          false,
+		SupportsUpscale:       false,
+		MaxPromptChars:        1920,
+		PricePerImage:         0.06,
 	},
 }
 
@@ -83,19 +198,103 @@ var imagenAliasMap = make(map[string]string)
 
 func init() {
 	for canonicalName, info := range SupportedImagenModels {
-		imagenAliasMap[strings.ToLower(canonicalName)] = canonicalName
+		imagenAliasMap[normalizeModelKey(canonicalName)] = canonicalName
 		for _, alias := range info.Aliases {
-			imagenAliasMap[strings.ToLower(alias)] = canonicalName
+			imagenAliasMap[normalizeModelKey(alias)] = canonicalName
+		}
+	}
+}
+
+// DeprecationWarning returns a warning to include in tool results when the model is
+// deprecated, or an empty string otherwise.
+func (info ImagenModelInfo) DeprecationWarning() string {
+	return deprecationWarning(info.CanonicalName, info.Deprecated, info.ReplacedBy)
+}
+
+// ImagenCapability names an image operation, besides generation, that only some Imagen
+// models support.
+type ImagenCapability string
+
+const (
+	ImagenCapabilityEdit    ImagenCapability = "edit"
+	ImagenCapabilityUpscale ImagenCapability = "upscale"
+)
+
+// Supports reports whether the model supports the given capability.
+func (info ImagenModelInfo) Supports(capability ImagenCapability) bool {
+	switch capability {
+	case ImagenCapabilityEdit:
+		return info.SupportsEdit
+	case ImagenCapabilityUpscale:
+		return info.SupportsUpscale
+	default:
+		return false
+	}
+}
+
+// capabilities lists the image operations the model supports besides generation.
+func (info ImagenModelInfo) capabilities() []string {
+	var capabilities []string
+	for _, capability := range []ImagenCapability{ImagenCapabilityEdit, ImagenCapabilityUpscale} {
+		if info.Supports(capability) {
+			capabilities = append(capabilities, string(capability))
+		}
+	}
+	return capabilities
+}
+
+// CheckImagenCapability returns an error if the canonical model does not support the
+// capability. The error lists the models that do, so handlers can consult it before
+// attempting an edit or upscale operation.
+func CheckImagenCapability(modelName string, capability ImagenCapability) error {
+	info, ok := SupportedImagenModels[modelName]
+	if !ok {
+		return fmt.Errorf("model '%s' is not a supported Imagen model", modelName)
+	}
+	if info.Supports(capability) {
+		return nil
+	}
+	var capable []string
+	for name, candidate := range SupportedImagenModels {
+		if candidate.Supports(capability) {
+			capable = append(capable, name)
 		}
 	}
+	sort.Strings(capable)
+	return fmt.Errorf("model %s does not support the %s operation. Models that do: [%s]", modelName, capability, strings.Join(capable, ", "))
 }
 
 // ResolveImagenModel finds the canonical model name from a user-provided name or alias.
 func ResolveImagenModel(modelInput string) (string, bool) {
-	canonicalName, found := imagenAliasMap[strings.ToLower(modelInput)]
+	canonicalName, _, found := ResolveModel(ModelFamilyImagen, modelInput)
 	return canonicalName, found
 }
 
+func resolveImagenModel(modelInput string) (string, bool) {
+	canonicalName, found := imagenAliasMap[normalizeModelKey(modelInput)]
+	return canonicalName, found
+}
+
+// ValidateImagenImageSize checks a requested image size against the model's
+// SupportedImageSizes. An empty size (use the model default) is always valid. Models with
+// no supported sizes, such as Imagen 3, reject any size. The error lists the valid sizes.
+func ValidateImagenImageSize(modelName, imageSize string) error {
+	if imageSize == "" {
+		return nil
+	}
+	info, ok := SupportedImagenModels[modelName]
+	if !ok {
+		return fmt.Errorf("model '%s' is not a supported Imagen model", modelName)
+	}
+	if len(info.SupportedImageSizes) == 0 {
+		return fmt.Errorf("image size '%s' is not supported by model %s: this model does not support image size selection", imageSize, modelName)
+	}
+	if !slices.Contains(info.SupportedImageSizes, imageSize) {
+		return fmt.Errorf("image size '%s' is not supported by model %s. Supported image sizes are: [%s]", imageSize, modelName, strings.Join(info.SupportedImageSizes, ", "))
+	}
+	return nil
+}
+
 // BuildImagenModelDescription generates a formatted string for the tool description.
 func BuildImagenModelDescription() string {
 	var sb strings.Builder
@@ -113,9 +312,19 @@ func BuildImagenModelDescription() string {
 		if len(info.SupportedImageSizes) > 0 {
 			sb.WriteString(fmt.Sprintf(" (Sizes: %s)", strings.Join(info.SupportedImageSizes, ", ")))
 		}
+		if info.EditOnly {
+			sb.WriteString(" (Editing only, use imagen_edit)")
+		}
+		if capabilities := info.capabilities(); len(capabilities) > 0 {
+			sb.WriteString(fmt.Sprintf(" (Supports: %s)", strings.Join(capabilities, ", ")))
+		}
+		if info.PricePerImage > 0 {
+			sb.WriteString(fmt.Sprintf(" (Price: ~%s/image)", FormatPrice(info.PricePerImage)))
+		}
 		if len(info.Aliases) > 0 {
 			sb.WriteString(fmt.Sprintf(" Aliases: *%s*", strings.Join(info.Aliases, "*, *")))
 		}
+		sb.WriteString(deprecationNote(info.Deprecated, info.ReplacedBy))
 		sb.WriteString("\n")
 	}
 	return sb.String()
@@ -125,64 +334,139 @@ func BuildImagenModelDescription() string {
 
 // VeoModelInfo holds the details for a specific Veo model.
 type VeoModelInfo struct {
-	CanonicalName           string
-	Aliases                 []string
-	DefaultDuration         int32
-	SupportedDurations      []int32
-	MaxVideos               int32
-	SupportedAspectRatios   []string
+	CanonicalName      string
+	Aliases            []string
+	DefaultDuration    int32
+	SupportedDurations []int32
+	// MinDuration and MaxDuration bound the duration, in seconds, of models that accept any
+	// whole number of seconds in a range rather than discrete values. They are only used
+	// when SupportedDurations is empty.
+	MinDuration           int32
+	MaxDuration           int32
+	MaxVideos             int32
+	SupportedAspectRatios []string
+	// DefaultAspectRatio is used when the request does not specify an aspect ratio.
+	// It must be one of SupportedAspectRatios.
+	DefaultAspectRatio string
+	// SupportedResolutions lists the output resolutions the model accepts.
+	// The first entry is the model's native resolution and is used by default.
+	SupportedResolutions []string
+	// SupportedOutputFormats lists the video container formats the model produces (e.g.,
+	// "mp4"), as reported by list_veo_models. The genai SDK has no option to choose the
+	// container, so every model currently lists only "mp4".
+	SupportedOutputFormats  []string
 	SupportsGenerateAudio   bool
 	SupportsLastFrame       bool
 	SupportsReferenceImages bool
+	// ReferenceImageMimeTypes lists the MIME types accepted for reference images.
+	// It is only meaningful when SupportsReferenceImages is true.
+	ReferenceImageMimeTypes []string
+	// MaxReferenceImages is the most reference images the model accepts in one request.
+	// It is only meaningful when SupportsReferenceImages is true.
+	MaxReferenceImages int
+	// SupportsUpscale reports whether existing videos generated by the model can be
+	// upscaled to a higher resolution. veo_upscale rejects models without it, and is only
+	// registered when some model has it. No model currently supports this through the genai SDK.
+	SupportsUpscale bool
+	// SupportsExtend reports whether the model can extend an existing video. The lengths
+	// an extension may add are listed in SupportedExtendDurations, and MaxTotalDuration is
+	// the longest video, in seconds, that extensions can build up to.
+	SupportsExtend           bool
+	SupportedExtendDurations []int32
+	MaxTotalDuration         int32
+	// MaxPromptChars is the longest prompt, in characters, that the model accepts without
+	// truncating it.
+	MaxPromptChars int
+	// PricePerSecond is the price, in USD, per generated second of video, used for cost hints
+	// and veo_estimate_cost. ResolutionPricePerSecond optionally overrides it for specific
+	// resolutions. Both default to the Vertex AI list prices and can be overridden with
+	// ApplyModelPrices. Zero means the price is unknown.
+	PricePerSecond           float64
+	ResolutionPricePerSecond map[string]float64
+	// Deprecated marks models that are scheduled for retirement. ReplacedBy optionally
+	// names the canonical model to migrate to.
+	Deprecated bool
+	ReplacedBy string
 }
 
 // SupportedVeoModels is the single source of truth for all supported Veo models.
 var SupportedVeoModels = map[string]VeoModelInfo{
 	"veo-2.0-generate-001": {
-		CanonicalName:         "veo-2.0-generate-001",
-		Aliases:               []string{"Veo 2"},
-		DefaultDuration:       8,
-		SupportedDurations:    []int32{5, 6, 7, 8},
-		MaxVideos:             4,
-		SupportedAspectRatios: []string{"16:9", "9:16"},
-		SupportsGenerateAudio: false,
+		CanonicalName:            "veo-2.0-generate-001",
+		Aliases:                  []string{"Veo 2"},
+		DefaultDuration:          8,
+		SupportedDurations:       []int32{5, 6, 7, 8},
+		MaxVideos:                4,
+		SupportedAspectRatios:    []string{"16:9", "9:16"},
+		DefaultAspectRatio:       "16:9",
+		SupportedResolutions:     []string{"720p"},
+		SupportedOutputFormats:   []string{"mp4"},
+		SupportsGenerateAudio:    false,
+		SupportsExtend:           true,
+		SupportedExtendDurations: []int32{4, 5, 6, 7},
+		MaxTotalDuration:         148,
+		MaxPromptChars:           4096,
+		PricePerSecond:           0.50,
 	},
 	"veo-2.0-generate-exp": {
-		CanonicalName:         "veo-2.0-generate-exp",
-		Aliases:               []string{"Veo 2.0 Exp"},
-		DefaultDuration:       8,
-		SupportedDurations:    []int32{5, 6, 7, 8},
-		MaxVideos:             4,
-		SupportedAspectRatios: []string{"16:9", "9:16"},
-		SupportsGenerateAudio: false,
+		CanonicalName:          "veo-2.0-generate-exp",
+		Aliases:                []string{"Veo 2.0 Exp"},
+		DefaultDuration:        8,
+		SupportedDurations:     []int32{5, 6, 7, 8},
+		MaxVideos:              4,
+		SupportedAspectRatios:  []string{"16:9", "9:16"},
+		DefaultAspectRatio:     "16:9",
+		SupportedResolutions:   []string{"720p"},
+		SupportedOutputFormats: []string{"mp4"},
+		SupportsGenerateAudio:  false,
+		MaxPromptChars:         4096,
+		Deprecated:             true,
+		ReplacedBy:             "veo-2.0-generate-001",
 	},
 	"veo-2.0-generate-preview": {
-		CanonicalName:         "veo-2.0-generate-preview",
-		Aliases:               []string{"Veo 2.0 Preview"},
-		DefaultDuration:       8,
-		SupportedDurations:    []int32{5, 6, 7, 8},
-		MaxVideos:             4,
-		SupportedAspectRatios: []string{"16:9", "9:16"},
-		SupportsGenerateAudio: false,
+		CanonicalName:          "veo-2.0-generate-preview",
+		Aliases:                []string{"Veo 2.0 Preview"},
+		DefaultDuration:        8,
+		SupportedDurations:     []int32{5, 6, 7, 8},
+		MaxVideos:              4,
+		SupportedAspectRatios:  []string{"16:9", "9:16"},
+		DefaultAspectRatio:     "16:9",
+		SupportedResolutions:   []string{"720p"},
+		SupportedOutputFormats: []string{"mp4"},
+		SupportsGenerateAudio:  false,
+		MaxPromptChars:         4096,
+		Deprecated:             true,
+		ReplacedBy:             "veo-2.0-generate-001",
 	},
 
-	// "veo-3.0-generate-preview": {
-	// 	CanonicalName:         "veo-3.0-generate-preview",
-	// 	Aliases:               []string{"Veo 3 preview"},
-	// 	MinDuration:           8,
-	// 	MaxDuration:           8,
-	// 	DefaultDuration:       8,
-	// 	MaxVideos:             2,
-	// 	SupportedAspectRatios: []string{"16:9"},
-	// },
+	"veo-3.0-generate-preview": {
+		CanonicalName:          "veo-3.0-generate-preview",
+		Aliases:                []string{"Veo 3 preview"},
+		DefaultDuration:        8,
+		SupportedDurations:     []int32{8},
+		MaxVideos:              2,
+		SupportedAspectRatios:  []string{"16:9"},
+		DefaultAspectRatio:     "16:9",
+		SupportedResolutions:   []string{"720p", "1080p"},
+		SupportedOutputFormats: []string{"mp4"},
+		SupportsGenerateAudio:  true,
+		SupportsLastFrame:      true,
+		MaxPromptChars:         4096,
+		PricePerSecond:         0.40,
+	},
 	"veo-3.0-fast-generate-001": {
-		CanonicalName:         "veo-3.0-fast-generate-001",
-		Aliases:               []string{"Veo 3 Fast"},
-		DefaultDuration:       8,
-		SupportedDurations:    []int32{4, 6, 8},
-		MaxVideos:             2,
-		SupportedAspectRatios: []string{"16:9"},
-		SupportsGenerateAudio: true,
+		CanonicalName:          "veo-3.0-fast-generate-001",
+		Aliases:                []string{"Veo 3 Fast"},
+		DefaultDuration:        8,
+		SupportedDurations:     []int32{4, 6, 8},
+		MaxVideos:              2,
+		SupportedAspectRatios:  []string{"16:9"},
+		DefaultAspectRatio:     "16:9",
+		SupportedResolutions:   []string{"720p", "1080p"},
+		SupportedOutputFormats: []string{"mp4"},
+		SupportsGenerateAudio:  true,
+		MaxPromptChars:         4096,
+		PricePerSecond:         0.15,
 	},
 
 	// "veo-3.0-fast-generate-preview": {
@@ -195,44 +479,226 @@ var SupportedVeoModels = map[string]VeoModelInfo{
 	// 	SupportedAspectRatios: []string{"16:9"},
 	// },
 	"veo-3.1-generate-preview": {
-		CanonicalName:           "veo-3.1-generate-preview",
-		Aliases:                 []string{"Veo 3.1 preview"},
-		SupportedDurations:      []int32{8},
-		DefaultDuration:         8,
-		MaxVideos:               2,
-		SupportedAspectRatios:   []string{"16:9", "9:16"},
-		SupportsLastFrame:       true,
-		SupportsReferenceImages: true,
+		CanonicalName:            "veo-3.1-generate-preview",
+		Aliases:                  []string{"Veo 3.1 preview"},
+		SupportedDurations:       []int32{8},
+		DefaultDuration:          8,
+		MaxVideos:                2,
+		SupportedAspectRatios:    []string{"16:9", "9:16"},
+		DefaultAspectRatio:       "16:9",
+		SupportedResolutions:     []string{"720p", "1080p"},
+		SupportedOutputFormats:   []string{"mp4"},
+		SupportsLastFrame:        true,
+		SupportsReferenceImages:  true,
+		ReferenceImageMimeTypes:  []string{"image/jpeg", "image/png", "image/webp"},
+		MaxReferenceImages:       3,
+		SupportsExtend:           true,
+		SupportedExtendDurations: []int32{7},
+		MaxTotalDuration:         148,
+		MaxPromptChars:           4096,
+		PricePerSecond:           0.40,
 	},
 	"veo-3.1-fast-generate-preview": {
-		CanonicalName:           "veo-3.1-fast-generate-preview",
-		Aliases:                 []string{"Veo 3.1 Fast preview"},
-		SupportedDurations:      []int32{8},
-		DefaultDuration:         8,
-		MaxVideos:               2,
-		SupportedAspectRatios:   []string{"16:9", "9:16"},
-		SupportsLastFrame:       true,
-		SupportsReferenceImages: false,
+		CanonicalName:            "veo-3.1-fast-generate-preview",
+		Aliases:                  []string{"Veo 3.1 Fast preview"},
+		SupportedDurations:       []int32{8},
+		DefaultDuration:          8,
+		MaxVideos:                2,
+		SupportedAspectRatios:    []string{"16:9", "9:16"},
+		DefaultAspectRatio:       "16:9",
+		SupportedResolutions:     []string{"720p", "1080p"},
+		SupportedOutputFormats:   []string{"mp4"},
+		SupportsLastFrame:        true,
+		SupportsReferenceImages:  false,
+		SupportsExtend:           true,
+		SupportedExtendDurations: []int32{7},
+		MaxTotalDuration:         148,
+		MaxPromptChars:           4096,
+		PricePerSecond:           0.15,
 	},
 }
 
 var veoAliasMap = make(map[string]string)
 
+// maxVeoModelEditDistance is the largest edit distance between normalized names
+// that ResolveVeoModel will still treat as a match.
+const maxVeoModelEditDistance = 2
+
 func init() {
 	for canonicalName, info := range SupportedVeoModels {
-		veoAliasMap[strings.ToLower(canonicalName)] = canonicalName
+		veoAliasMap[normalizeModelKey(canonicalName)] = canonicalName
 		for _, alias := range info.Aliases {
-			veoAliasMap[strings.ToLower(alias)] = canonicalName
+			veoAliasMap[normalizeModelKey(alias)] = canonicalName
 		}
 	}
 }
 
+// SupportsDuration reports whether the model can generate videos of the given length in
+// seconds: one of SupportedDurations if it is set, or otherwise any duration between
+// MinDuration and MaxDuration.
+func (info VeoModelInfo) SupportsDuration(seconds int32) bool {
+	if len(info.SupportedDurations) > 0 {
+		return slices.Contains(info.SupportedDurations, seconds)
+	}
+	return seconds > 0 && seconds >= info.MinDuration && seconds <= info.MaxDuration
+}
+
+// DurationsDescription describes the durations the model supports, as a list such as
+// "[4, 6, 8]" or, for models with a duration range, a range such as "4-8".
+func (info VeoModelInfo) DurationsDescription() string {
+	if len(info.SupportedDurations) == 0 && info.MaxDuration > 0 {
+		return fmt.Sprintf("%d-%d", max(info.MinDuration, 1), info.MaxDuration)
+	}
+	durationsStr := make([]string, len(info.SupportedDurations))
+	for i, d := range info.SupportedDurations {
+		durationsStr[i] = fmt.Sprintf("%d", d)
+	}
+	return "[" + strings.Join(durationsStr, ", ") + "]"
+}
+
+// DeprecationWarning returns a warning to include in tool results when the model is
+// deprecated, or an empty string otherwise.
+func (info VeoModelInfo) DeprecationWarning() string {
+	return deprecationWarning(info.CanonicalName, info.Deprecated, info.ReplacedBy)
+}
+
+// PriceFor returns the price per generated second of video at the given resolution: the
+// resolution's entry in ResolutionPricePerSecond if there is one, or PricePerSecond. It
+// reports false if the price is unknown.
+func (info VeoModelInfo) PriceFor(resolution string) (float64, bool) {
+	if price, ok := info.ResolutionPricePerSecond[resolution]; ok {
+		return price, true
+	}
+	return info.PricePerSecond, info.PricePerSecond > 0
+}
+
 // ResolveVeoModel finds the canonical model name from a user-provided name or alias.
+// The input is compared with case, spaces, dashes, dots, and underscores ignored (so
+// "veo3 fast" or "Veo-3-Fast" resolve to "Veo 3 Fast"). If that fails, a small edit
+// distance is tolerated to absorb typos in the words of a name. A fuzzy match must carry the
+// same version numbers as the input and be unambiguous, so that an unknown version such as
+// "veo-3.0-generate-001" is rejected rather than resolved to a different model.
 func ResolveVeoModel(modelInput string) (string, bool) {
-	canonicalName, found := veoAliasMap[strings.ToLower(modelInput)]
+	canonicalName, _, found := ResolveModel(ModelFamilyVeo, modelInput)
 	return canonicalName, found
 }
 
+func resolveVeoModel(modelInput string) (string, bool) {
+	normalizedInput := normalizeModelKey(modelInput)
+	if normalizedInput == "" {
+		return "", false
+	}
+	if canonicalName, found := veoAliasMap[normalizedInput]; found {
+		return canonicalName, true
+	}
+
+	bestDistance := maxVeoModelEditDistance + 1
+	bestMatch := ""
+	ambiguous := false
+	inputNumbers := modelKeyNumbers(normalizedInput)
+	for normalizedName, canonicalName := range veoAliasMap {
+		if !slices.Equal(modelKeyNumbers(normalizedName), inputNumbers) {
+			continue
+		}
+		d := levenshteinDistance(normalizedInput, normalizedName)
+		switch {
+		case d < bestDistance:
+			bestDistance, bestMatch, ambiguous = d, canonicalName, false
+		case d == bestDistance && canonicalName != bestMatch:
+			ambiguous = true
+		}
+	}
+	if bestMatch == "" || ambiguous {
+		return "", false
+	}
+	return bestMatch, true
+}
+
+// modelKeyNumbers returns the runs of digits in a normalized model key without leading zeros,
+// e.g. ["30", "1"] for "veo30fastgenerate001", so that names that differ in a version or
+// revision number never fuzzy-match while "01" still matches "001".
+func modelKeyNumbers(key string) []string {
+	var numbers []string
+	start := -1
+	for i := 0; i <= len(key); i++ {
+		isDigit := i < len(key) && key[i] >= '0' && key[i] <= '9'
+		switch {
+		case isDigit && start < 0:
+			start = i
+		case !isDigit && start >= 0:
+			number := strings.TrimLeft(key[start:i], "0")
+			if number == "" {
+				number = "0"
+			}
+			numbers = append(numbers, number)
+			start = -1
+		}
+	}
+	return numbers
+}
+
+// SuggestVeoModels returns up to limit canonical model names that are closest to an
+// unresolved model input, for use in "did you mean" error messages. Names and aliases
+// are compared in normalized form; candidates further than half their own length from
+// the input are not considered similar and are omitted. Results are ordered by
+// distance, then by name.
+func SuggestVeoModels(modelInput string, limit int) []string {
+	normalizedInput := normalizeModelKey(modelInput)
+	if normalizedInput == "" || limit <= 0 {
+		return nil
+	}
+
+	distances := make(map[string]int)
+	for normalizedName, canonicalName := range veoAliasMap {
+		d := levenshteinDistance(normalizedInput, normalizedName)
+		if d > len(normalizedName)/2 {
+			continue
+		}
+		if best, ok := distances[canonicalName]; !ok || d < best {
+			distances[canonicalName] = d
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for canonicalName := range distances {
+		suggestions = append(suggestions, canonicalName)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		di, dj := distances[suggestions[i]], distances[suggestions[j]]
+		if di != dj {
+			return di < dj
+		}
+		return suggestions[i] < suggestions[j]
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// levenshteinDistance returns the number of single-character insertions, deletions,
+// or substitutions needed to turn a into b.
+func levenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
 // BuildVeoModelDescription generates a formatted string for the tool description.
 func BuildVeoModelDescription() string {
 	var sb strings.Builder
@@ -245,15 +711,25 @@ func BuildVeoModelDescription() string {
 
 	for _, name := range sortedNames {
 		info := SupportedVeoModels[name]
-		durationsStr := make([]string, len(info.SupportedDurations))
-		for i, d := range info.SupportedDurations {
-			durationsStr[i] = fmt.Sprintf("%d", d)
+		sb.WriteString(fmt.Sprintf("- *%s* (Durations: %ss, Max Videos: %d, Ratios: %s, Resolutions: %s)",
+			info.CanonicalName, info.DurationsDescription(), info.MaxVideos, strings.Join(info.SupportedAspectRatios, ", "), strings.Join(info.SupportedResolutions, ", ")))
+		if info.SupportsExtend {
+			extendStr := make([]string, len(info.SupportedExtendDurations))
+			for i, d := range info.SupportedExtendDurations {
+				extendStr[i] = fmt.Sprintf("%d", d)
+			}
+			sb.WriteString(fmt.Sprintf(" (Extend: +[%s]s, up to %ds total)", strings.Join(extendStr, ", "), info.MaxTotalDuration))
+		}
+		if info.SupportsReferenceImages {
+			sb.WriteString(fmt.Sprintf(" (Reference images: up to %d)", info.MaxReferenceImages))
+		}
+		if hint := info.priceHint(); hint != "" {
+			sb.WriteString(fmt.Sprintf(" (Price: %s)", hint))
 		}
-		sb.WriteString(fmt.Sprintf("- *%s* (Durations: [%s]s, Max Videos: %d, Ratios: %s)",
-			info.CanonicalName, strings.Join(durationsStr, ", "), info.MaxVideos, strings.Join(info.SupportedAspectRatios, ", ")))
 		if len(info.Aliases) > 0 {
 			sb.WriteString(fmt.Sprintf(" Aliases: *%s*", strings.Join(info.Aliases, "*, *")))
 		}
+		sb.WriteString(deprecationNote(info.Deprecated, info.ReplacedBy))
 		sb.WriteString("\n")
 	}
 	return sb.String()
@@ -263,27 +739,40 @@ func BuildVeoModelDescription() string {
 
 // GeminiModelInfo holds the details for a specific Gemini model.
 type GeminiModelInfo struct {
-	CanonicalName string
-	Aliases       []string
-	Description   string
+	CanonicalName   string
+	Aliases         []string
+	Description     string
+	ImageGeneration bool // Whether the model can return generated images.
+	// MaxPromptChars is the longest prompt, in characters, that the model accepts without
+	// truncating it.
+	MaxPromptChars int
+	// Deprecated marks models that are scheduled for retirement. ReplacedBy optionally
+	// names the canonical model to migrate to.
+	Deprecated bool
+	ReplacedBy string
 }
 
 // SupportedGeminiModels is the single source of truth for all supported Gemini models.
 var SupportedGeminiModels = map[string]GeminiModelInfo{
 	"gemini-2.5-flash-image": {
-		CanonicalName: "gemini-2.5-flash-image",
-		Aliases:       []string{"nano-banana", "nano banana"},
-		Description:   "Gemini 2.5 Flash Image generation model.",
+		CanonicalName:   "gemini-2.5-flash-image",
+		Aliases:         []string{"nano-banana", "nano banana"},
+		Description:     "Gemini 2.5 Flash Image generation model.",
+		ImageGeneration: true,
+		MaxPromptChars:  131072,
 	},
 	"gemini-3-pro-preview": {
-		CanonicalName: "gemini-3-pro-preview",
-		Aliases:       []string{"Gemini 3 Pro"},
-		Description:   "Gemini 3 Pro Preview model.",
+		CanonicalName:  "gemini-3-pro-preview",
+		Aliases:        []string{"Gemini 3 Pro"},
+		Description:    "Gemini 3 Pro Preview model.",
+		MaxPromptChars: 4194304,
 	},
 	"gemini-3-pro-image-preview": {
-		CanonicalName: "gemini-3-pro-image-preview",
-		Aliases:       []string{"Gemini 3 Pro Image", "nano banana pro", "nano-banana-pro"},
-		Description:   "Gemini 3 Pro Image Preview model.",
+		CanonicalName:   "gemini-3-pro-image-preview",
+		Aliases:         []string{"Gemini 3 Pro Image", "nano banana pro", "nano-banana-pro"},
+		Description:     "Gemini 3 Pro Image Preview model.",
+		ImageGeneration: true,
+		MaxPromptChars:  262144,
 	},
 }
 
@@ -291,16 +780,27 @@ var geminiAliasMap = make(map[string]string)
 
 func init() {
 	for canonicalName, info := range SupportedGeminiModels {
-		geminiAliasMap[strings.ToLower(canonicalName)] = canonicalName
+		geminiAliasMap[normalizeModelKey(canonicalName)] = canonicalName
 		for _, alias := range info.Aliases {
-			geminiAliasMap[strings.ToLower(alias)] = canonicalName
+			geminiAliasMap[normalizeModelKey(alias)] = canonicalName
 		}
 	}
 }
 
+// DeprecationWarning returns a warning to include in tool results when the model is
+// deprecated, or an empty string otherwise.
+func (info GeminiModelInfo) DeprecationWarning() string {
+	return deprecationWarning(info.CanonicalName, info.Deprecated, info.ReplacedBy)
+}
+
 // ResolveGeminiModel finds the canonical model name from a user-provided name or alias.
 func ResolveGeminiModel(modelInput string) (string, bool) {
-	canonicalName, found := geminiAliasMap[strings.ToLower(modelInput)]
+	canonicalName, _, found := ResolveModel(ModelFamilyGemini, modelInput)
+	return canonicalName, found
+}
+
+func resolveGeminiModel(modelInput string) (string, bool) {
+	canonicalName, found := geminiAliasMap[normalizeModelKey(modelInput)]
 	return canonicalName, found
 }
 
@@ -320,7 +820,161 @@ func BuildGeminiModelDescription() string {
 		if len(info.Aliases) > 0 {
 			sb.WriteString(fmt.Sprintf(" (Aliases: *%s*)", strings.Join(info.Aliases, "*, *")))
 		}
+		sb.WriteString(deprecationNote(info.Deprecated, info.ReplacedBy))
 		sb.WriteString("\n")
 	}
 	return sb.String()
 }
+
+// --- Model Allowlist ---
+
+// ApplyModelAllowlist restricts SupportedVeoModels and SupportedImagenModels to the models
+// named in allowlist, by canonical name or alias, so that other models fail resolution and
+// are left out of the model descriptions. A family is only restricted if the allowlist names
+// at least one of its models, so one list can be shared by the Veo and Imagen servers.
+// Entries are matched exactly (ignoring case and punctuation, as for aliases) rather than
+// fuzzily. It returns the entries that match no Veo or Imagen model, which are ignored.
+// Servers call it once at startup, before handling requests.
+func ApplyModelAllowlist(allowlist []string) []string {
+	allowedVeo := make(map[string]bool)
+	allowedImagen := make(map[string]bool)
+	var unknown []string
+	for _, entry := range allowlist {
+		key := normalizeModelKey(entry)
+		if canonicalName, found := veoAliasMap[key]; found {
+			allowedVeo[canonicalName] = true
+		} else if canonicalName, found := imagenAliasMap[key]; found {
+			allowedImagen[canonicalName] = true
+		} else {
+			unknown = append(unknown, entry)
+		}
+	}
+	if len(allowedVeo) > 0 {
+		restrictModels(SupportedVeoModels, veoAliasMap, allowedVeo)
+	}
+	if len(allowedImagen) > 0 {
+		restrictModels(SupportedImagenModels, imagenAliasMap, allowedImagen)
+	}
+	return unknown
+}
+
+// restrictModels removes the models that are not in allowed from a family's model map and
+// alias map.
+func restrictModels[T any](models map[string]T, aliases map[string]string, allowed map[string]bool) {
+	for canonicalName := range models {
+		if !allowed[canonicalName] {
+			delete(models, canonicalName)
+		}
+	}
+	for key, canonicalName := range aliases {
+		if !allowed[canonicalName] {
+			delete(aliases, key)
+		}
+	}
+}
+
+// --- Model Prices ---
+
+// FormatPrice formats a price in USD with at least two decimals, e.g. "$0.40" or "$0.035". It is
+// shared by the model descriptions and the cost estimates of the servers.
+func FormatPrice(price float64) string {
+	if cents := price * 100; math.Abs(cents-math.Round(cents)) < 1e-9 {
+		return fmt.Sprintf("$%.2f", price)
+	}
+	return "$" + strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// priceHint describes the model's price per second for BuildVeoModelDescription, e.g.
+// "~$0.40/s" or "~$0.40/s, 1080p ~$0.50/s", or returns "" if no price is known.
+func (info VeoModelInfo) priceHint() string {
+	var parts []string
+	if info.PricePerSecond > 0 {
+		parts = append(parts, fmt.Sprintf("~%s/s", FormatPrice(info.PricePerSecond)))
+	}
+	for _, resolution := range slices.Sorted(maps.Keys(info.ResolutionPricePerSecond)) {
+		parts = append(parts, fmt.Sprintf("%s ~%s/s", resolution, FormatPrice(info.ResolutionPricePerSecond[resolution])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ApplyModelPrices overrides the default prices of SupportedVeoModels and
+// SupportedImagenModels, so that the model descriptions and cost estimates use the prices a
+// deployment actually pays. veoPricePerSecond is keyed by Veo model, optionally suffixed with
+// ":<resolution>" to set the price of one resolution, and imagenPricePerImage by Imagen model;
+// models are named by canonical name or alias, matched exactly as for ApplyModelAllowlist. It
+// returns the entries that match no model, which are ignored. Servers call it once at startup,
+// before building tool descriptions.
+func ApplyModelPrices(veoPricePerSecond, imagenPricePerImage map[string]float64) []string {
+	var unknown []string
+	for _, entry := range slices.Sorted(maps.Keys(veoPricePerSecond)) {
+		modelName, resolution, _ := strings.Cut(entry, ":")
+		canonicalName, found := veoAliasMap[normalizeModelKey(modelName)]
+		if !found {
+			unknown = append(unknown, entry)
+			continue
+		}
+		info := SupportedVeoModels[canonicalName]
+		if resolution == "" {
+			info.PricePerSecond = veoPricePerSecond[entry]
+		} else {
+			info.ResolutionPricePerSecond = maps.Clone(info.ResolutionPricePerSecond)
+			if info.ResolutionPricePerSecond == nil {
+				info.ResolutionPricePerSecond = make(map[string]float64)
+			}
+			info.ResolutionPricePerSecond[strings.TrimSpace(resolution)] = veoPricePerSecond[entry]
+		}
+		SupportedVeoModels[canonicalName] = info
+	}
+	for _, entry := range slices.Sorted(maps.Keys(imagenPricePerImage)) {
+		canonicalName, found := imagenAliasMap[normalizeModelKey(entry)]
+		if !found {
+			unknown = append(unknown, entry)
+			continue
+		}
+		info := SupportedImagenModels[canonicalName]
+		info.PricePerImage = imagenPricePerImage[entry]
+		SupportedImagenModels[canonicalName] = info
+	}
+	return unknown
+}
+
+// --- Unified Model Resolution ---
+
+// ModelFamily identifies a group of models that share a ModelInfo struct and alias map.
+type ModelFamily string
+
+const (
+	// ModelFamilyAny makes ResolveModel search every family.
+	ModelFamilyAny    ModelFamily = ""
+	ModelFamilyImagen ModelFamily = "imagen"
+	ModelFamilyVeo    ModelFamily = "veo"
+	ModelFamilyGemini ModelFamily = "gemini"
+)
+
+// modelFamilyResolvers lists the per-family resolvers in the order ModelFamilyAny searches
+// them. Families that only accept exact aliases come first, so Veo's fuzzy matching cannot
+// claim a name that is an exact Imagen or Gemini alias.
+var modelFamilyResolvers = []struct {
+	family  ModelFamily
+	resolve func(string) (string, bool)
+}{
+	{ModelFamilyImagen, resolveImagenModel},
+	{ModelFamilyGemini, resolveGeminiModel},
+	{ModelFamilyVeo, resolveVeoModel},
+}
+
+// ResolveModel finds the canonical model name for a user-provided name or alias and
+// reports which family it belongs to. Pass ModelFamilyAny to search every family; any
+// other family restricts the lookup to that family's models. An unknown family never
+// resolves.
+func ResolveModel(family ModelFamily, modelInput string) (string, ModelFamily, bool) {
+	for _, r := range modelFamilyResolvers {
+		if family != ModelFamilyAny && family != r.family {
+			continue
+		}
+		if canonicalName, found := r.resolve(modelInput); found {
+			return canonicalName, r.family, true
+		}
+	}
+	return "", ModelFamilyAny, false
+}
@@ -0,0 +1,596 @@
+package common
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveVeoModel(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expected      string
+		expectedFound bool
+	}{
+		{"veo-2.0-generate-001", "veo-2.0-generate-001", true},
+		{"Veo 2", "veo-2.0-generate-001", true},
+		{"VEO 3 FAST", "veo-3.0-fast-generate-001", true},
+		{"veo3 fast", "veo-3.0-fast-generate-001", true},
+		{"Veo-3-Fast", "veo-3.0-fast-generate-001", true},
+		{"veo-3.0-generate-preview", "veo-3.0-generate-preview", true},
+		{"Veo 3 preview", "veo-3.0-generate-preview", true},
+		{"veo3-preview", "veo-3.0-generate-preview", true},
+		{"veo_3.1_preview", "veo-3.1-generate-preview", true},
+		{"veo-3.0-fast-generate-01", "veo-3.0-fast-generate-001", true},
+		{"veo-3.0-fast-genrate-001", "veo-3.0-fast-generate-001", true},
+		{"veo-3.0-generate-001", "", false},
+		{"veo-3.1-generate-001", "", false},
+		{"veo-2.1-generate-001", "", false},
+		{"veo-2.0-generate-002", "", false},
+		{"not-a-model", "", false},
+		{"", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, found := ResolveVeoModel(tc.input)
+			if found != tc.expectedFound {
+				t.Errorf("expected found to be %v, but got %v", tc.expectedFound, found)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestModelKeyNumbers(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected []string
+	}{
+		{"veo30fastgenerate001", []string{"30", "1"}},
+		{"veo20generate001", []string{"20", "1"}},
+		{"veo3fast", []string{"3"}},
+		{"veo20generate000", []string{"20", "0"}},
+		{"nanobanana", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if actual := modelKeyNumbers(tc.key); !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSuggestVeoModels(t *testing.T) {
+	testCases := []struct {
+		input    string
+		limit    int
+		expected []string
+	}{
+		{"veo-3-fast-gen", 3, []string{"veo-3.0-fast-generate-001", "veo-3.1-fast-generate-preview"}},
+		{"veo-3-fast-gen", 1, []string{"veo-3.0-fast-generate-001"}},
+		{"gpt-4", 3, []string{}},
+		{"", 3, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual := SuggestVeoModels(tc.input, tc.limit)
+			if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"veo3fast", "veo3fast", 0},
+		{"veo3fsat", "veo3fast", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			actual := levenshteinDistance(tc.a, tc.b)
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSupportedVeoModelsVeo3Preview(t *testing.T) {
+	info, ok := SupportedVeoModels["veo-3.0-generate-preview"]
+	if !ok {
+		t.Fatalf("expected veo-3.0-generate-preview to be supported")
+	}
+	if !info.SupportsGenerateAudio || !info.SupportsLastFrame {
+		t.Errorf("expected audio and last frame support, but got %+v", info)
+	}
+	if !slices.Contains(info.SupportedDurations, info.DefaultDuration) {
+		t.Errorf("expected the default duration %d to be one of %v", info.DefaultDuration, info.SupportedDurations)
+	}
+	if info.Deprecated {
+		t.Errorf("expected veo-3.0-generate-preview not to be deprecated")
+	}
+}
+
+func TestVeoModelInfoSupportsDuration(t *testing.T) {
+	discrete := VeoModelInfo{SupportedDurations: []int32{4, 6, 8}, MinDuration: 1, MaxDuration: 10}
+	ranged := VeoModelInfo{MinDuration: 4, MaxDuration: 8}
+	testCases := []struct {
+		name     string
+		info     VeoModelInfo
+		seconds  int32
+		expected bool
+	}{
+		{"discrete supported", discrete, 6, true},
+		{"discrete unsupported ignores range", discrete, 5, false},
+		{"range minimum", ranged, 4, true},
+		{"range middle", ranged, 5, true},
+		{"range maximum", ranged, 8, true},
+		{"below range", ranged, 3, false},
+		{"above range", ranged, 9, false},
+		{"no durations", VeoModelInfo{}, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.info.SupportsDuration(tc.seconds); actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	if actual := discrete.DurationsDescription(); actual != "[4, 6, 8]" {
+		t.Errorf("expected '[4, 6, 8]', but got '%s'", actual)
+	}
+	if actual := ranged.DurationsDescription(); actual != "4-8" {
+		t.Errorf("expected '4-8', but got '%s'", actual)
+	}
+}
+
+func TestSupportedVeoModelsResolutions(t *testing.T) {
+	for name, info := range SupportedVeoModels {
+		if len(info.SupportedResolutions) == 0 {
+			t.Errorf("model %s has no supported resolutions", name)
+		}
+	}
+}
+
+func TestSupportedVeoModelsDefaultAspectRatio(t *testing.T) {
+	for name, info := range SupportedVeoModels {
+		if !slices.Contains(info.SupportedAspectRatios, info.DefaultAspectRatio) {
+			t.Errorf("model %s has default aspect ratio '%s', which is not in %v", name, info.DefaultAspectRatio, info.SupportedAspectRatios)
+		}
+	}
+}
+
+func TestBuildVeoModelDescriptionIncludesResolutions(t *testing.T) {
+	description := BuildVeoModelDescription()
+	if !strings.Contains(description, "Resolutions: 720p, 1080p") {
+		t.Errorf("expected description to list resolutions, but got '%s'", description)
+	}
+}
+
+func TestSupportedVeoModelsReferenceImages(t *testing.T) {
+	description := BuildVeoModelDescription()
+	for name, info := range SupportedVeoModels {
+		if !info.SupportsReferenceImages {
+			continue
+		}
+		if info.MaxReferenceImages <= 0 {
+			t.Errorf("model %s supports reference images but has no maximum reference image count", name)
+		}
+		if len(info.ReferenceImageMimeTypes) == 0 {
+			t.Errorf("model %s supports reference images but accepts no MIME types", name)
+		}
+	}
+	if !strings.Contains(description, "Reference images: up to 3") {
+		t.Errorf("expected description to list the reference image cap, but got '%s'", description)
+	}
+}
+
+func TestSupportedVeoModelsExtend(t *testing.T) {
+	for name, info := range SupportedVeoModels {
+		if !info.SupportsExtend {
+			continue
+		}
+		if len(info.SupportedExtendDurations) == 0 {
+			t.Errorf("model %s supports extension but has no supported extension durations", name)
+		}
+		if info.MaxTotalDuration < info.DefaultDuration {
+			t.Errorf("model %s has a max total duration of %ds, shorter than its default duration of %ds", name, info.MaxTotalDuration, info.DefaultDuration)
+		}
+	}
+}
+
+func TestBuildVeoModelDescriptionIncludesExtend(t *testing.T) {
+	description := BuildVeoModelDescription()
+	if !strings.Contains(description, "*veo-2.0-generate-001* (Durations: [5, 6, 7, 8]s, Max Videos: 4, Ratios: 16:9, 9:16, Resolutions: 720p) (Extend: +[4, 5, 6, 7]s, up to 148s total)") {
+		t.Errorf("expected description to list extension limits, but got '%s'", description)
+	}
+	if strings.Contains(description, "*veo-3.0-fast-generate-001* (Durations: [4, 6, 8]s, Max Videos: 2, Ratios: 16:9, Resolutions: 720p, 1080p) (Extend") {
+		t.Errorf("expected no extension limits for a model without extension support, but got '%s'", description)
+	}
+}
+
+func TestValidateImagenImageSize(t *testing.T) {
+	testCases := []struct {
+		name          string
+		model         string
+		imageSize     string
+		expectedError string
+	}{
+		{"no size requested", "imagen-3.0-generate-002", "", ""},
+		{"supported size", "imagen-4.0-generate-001", "2K", ""},
+		{"model without size selection", "imagen-3.0-generate-002", "1K", "does not support image size selection"},
+		{"unsupported size", "imagen-4.0-generate-001", "4K", "Supported image sizes are: [1K, 2K]"},
+		{"unknown model", "not-a-model", "1K", "not a supported Imagen model"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateImagenImageSize(tc.model, tc.imageSize)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got '%v'", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestModelDeprecationWarning(t *testing.T) {
+	testCases := []struct {
+		name     string
+		warning  string
+		expected string
+	}{
+		{"deprecated veo model", SupportedVeoModels["veo-2.0-generate-exp"].DeprecationWarning(), "model veo-2.0-generate-exp is deprecated and will be retired; migrate to veo-2.0-generate-001"},
+		{"current veo model", SupportedVeoModels["veo-3.1-generate-preview"].DeprecationWarning(), ""},
+		{"deprecated imagen model", SupportedImagenModels["imagen-3.0-generate-001"].DeprecationWarning(), "model imagen-3.0-generate-001 is deprecated and will be retired; migrate to imagen-3.0-generate-002"},
+		{"deprecated without replacement", GeminiModelInfo{CanonicalName: "gemini-old", Deprecated: true}.DeprecationWarning(), "model gemini-old is deprecated and will be retired"},
+		{"current gemini model", SupportedGeminiModels["gemini-3-pro-preview"].DeprecationWarning(), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.warning != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, tc.warning)
+			}
+		})
+	}
+}
+
+func TestBuildModelDescriptionsMarkDeprecated(t *testing.T) {
+	if desc := BuildVeoModelDescription(); !strings.Contains(desc, "*veo-2.0-generate-exp*") || !strings.Contains(desc, "[DEPRECATED: use *veo-2.0-generate-001*]") {
+		t.Errorf("expected Veo description to mark deprecated models, but got '%s'", desc)
+	}
+	if desc := BuildImagenModelDescription(); !strings.Contains(desc, "[DEPRECATED: use *imagen-3.0-generate-002*]") {
+		t.Errorf("expected Imagen description to mark deprecated models, but got '%s'", desc)
+	}
+	if desc := BuildGeminiModelDescription(); strings.Contains(desc, "DEPRECATED") {
+		t.Errorf("expected no deprecated Gemini models, but got '%s'", desc)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	testCases := []struct {
+		name           string
+		family         ModelFamily
+		input          string
+		expectedModel  string
+		expectedFamily ModelFamily
+		expectedFound  bool
+	}{
+		{"imagen alias", ModelFamilyImagen, "Imagen 4", "imagen-4.0-generate-001", ModelFamilyImagen, true},
+		{"veo fuzzy alias", ModelFamilyVeo, "veo3 fast", "veo-3.0-fast-generate-001", ModelFamilyVeo, true},
+		{"gemini alias", ModelFamilyGemini, "nano banana", "gemini-2.5-flash-image", ModelFamilyGemini, true},
+		{"any family finds imagen", ModelFamilyAny, "imagen-3.0-generate-002", "imagen-3.0-generate-002", ModelFamilyImagen, true},
+		{"any family finds veo", ModelFamilyAny, "Veo 3.1 preview", "veo-3.1-generate-preview", ModelFamilyVeo, true},
+		{"any family finds gemini", ModelFamilyAny, "nano-banana-pro", "gemini-3-pro-image-preview", ModelFamilyGemini, true},
+		{"wrong family", ModelFamilyImagen, "nano banana", "", ModelFamilyAny, false},
+		{"unknown family", ModelFamily("lyria"), "Imagen 4", "", ModelFamilyAny, false},
+		{"unknown model", ModelFamilyAny, "not-a-model", "", ModelFamilyAny, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, family, found := ResolveModel(tc.family, tc.input)
+			if found != tc.expectedFound {
+				t.Fatalf("expected found to be %v, but got %v", tc.expectedFound, found)
+			}
+			if model != tc.expectedModel {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedModel, model)
+			}
+			if family != tc.expectedFamily {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedFamily, family)
+			}
+		})
+	}
+}
+
+func TestNormalizeModelKey(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"nano-banana", "nanobanana"},
+		{"nano banana", "nanobanana"},
+		{"NanoBanana", "nanobanana"},
+		{"Veo3Fast", "veo3fast"},
+		{"veo-3.0-fast_generate-001", "veo30fastgenerate001"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := normalizeModelKey(tc.input); got != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolveModelNormalizedAliases(t *testing.T) {
+	testCases := []struct {
+		family        ModelFamily
+		input         string
+		expectedModel string
+	}{
+		{ModelFamilyGemini, "nano-banana", "gemini-2.5-flash-image"},
+		{ModelFamilyGemini, "nano banana", "gemini-2.5-flash-image"},
+		{ModelFamilyGemini, "NanoBanana", "gemini-2.5-flash-image"},
+		{ModelFamilyGemini, "NanoBananaPro", "gemini-3-pro-image-preview"},
+		{ModelFamilyVeo, "Veo3Fast", "veo-3.0-fast-generate-001"},
+		{ModelFamilyImagen, "imagen4ultra", "imagen-4.0-ultra-generate-001"},
+		{ModelFamilyImagen, "Imagen-3.0-Generate-002", "imagen-3.0-generate-002"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			model, _, found := ResolveModel(tc.family, tc.input)
+			if !found || model != tc.expectedModel {
+				t.Errorf("expected '%s', but got '%s' (found: %v)", tc.expectedModel, model, found)
+			}
+		})
+	}
+}
+
+func TestModelAliasKeysAreUnambiguous(t *testing.T) {
+	for name, info := range SupportedImagenModels {
+		for _, alias := range append([]string{name}, info.Aliases...) {
+			if got, _ := ResolveImagenModel(alias); got != name {
+				t.Errorf("expected Imagen alias '%s' to resolve to '%s', but got '%s'", alias, name, got)
+			}
+		}
+	}
+	for name, info := range SupportedVeoModels {
+		for _, alias := range append([]string{name}, info.Aliases...) {
+			if got, _ := ResolveVeoModel(alias); got != name {
+				t.Errorf("expected Veo alias '%s' to resolve to '%s', but got '%s'", alias, name, got)
+			}
+		}
+	}
+	for name, info := range SupportedGeminiModels {
+		for _, alias := range append([]string{name}, info.Aliases...) {
+			if got, _ := ResolveGeminiModel(alias); got != name {
+				t.Errorf("expected Gemini alias '%s' to resolve to '%s', but got '%s'", alias, name, got)
+			}
+		}
+	}
+}
+
+func TestCheckImagenCapability(t *testing.T) {
+	testCases := []struct {
+		name          string
+		model         string
+		capability    ImagenCapability
+		expectedError string
+	}{
+		{"edit supported", "imagen-3.0-capability-001", ImagenCapabilityEdit, ""},
+		{"upscale supported", "imagen-3.0-generate-002", ImagenCapabilityUpscale, ""},
+		{"edit unsupported", "imagen-4.0-generate-001", ImagenCapabilityEdit, "Models that do: [imagen-3.0-capability-001]"},
+		{"upscale unsupported", "imagen-3.0-capability-001", ImagenCapabilityUpscale, "Models that do: [imagen-3.0-generate-002]"},
+		{"unknown model", "imagen-9", ImagenCapabilityEdit, "not a supported Imagen model"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckImagenCapability(tc.model, tc.capability)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("expected error containing '%s', but got: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestBuildImagenModelDescriptionListsCapabilities(t *testing.T) {
+	description := BuildImagenModelDescription()
+	for _, expected := range []string{
+		"*imagen-3.0-capability-001*",
+		"(Supports: edit)",
+		"(Supports: upscale)",
+	} {
+		if !strings.Contains(description, expected) {
+			t.Errorf("expected description to contain '%s', but got:\n%s", expected, description)
+		}
+	}
+}
+
+func TestCheckPromptLength(t *testing.T) {
+	testCases := []struct {
+		name          string
+		prompt        string
+		maxChars      int
+		expectedError string
+	}{
+		{"within limit", "a cat", 10, ""},
+		{"at limit", "0123456789", 10, ""},
+		{"over limit", "0123456789a", 10, "prompt is 11 characters long, which exceeds the limit of 10 characters for model test-model"},
+		{"multibyte characters counted once", "ééééé", 5, ""},
+		{"no limit", strings.Repeat("a", 100000), 0, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckPromptLength("test-model", tc.prompt, tc.maxChars)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got '%v'", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("expected error '%s', but got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestSupportedModelsMaxPromptChars(t *testing.T) {
+	for name, info := range SupportedVeoModels {
+		if info.MaxPromptChars <= 0 {
+			t.Errorf("Veo model %s has no prompt length limit", name)
+		}
+	}
+	for name, info := range SupportedImagenModels {
+		if info.MaxPromptChars <= 0 {
+			t.Errorf("Imagen model %s has no prompt length limit", name)
+		}
+	}
+	for name, info := range SupportedGeminiModels {
+		if info.MaxPromptChars <= 0 {
+			t.Errorf("Gemini model %s has no prompt length limit", name)
+		}
+	}
+}
+
+// restoreModelMaps restores the Veo and Imagen model and alias maps after a test that
+// restricts them.
+func restoreModelMaps(t *testing.T) {
+	veoModels, veoAliases := maps.Clone(SupportedVeoModels), maps.Clone(veoAliasMap)
+	imagenModels, imagenAliases := maps.Clone(SupportedImagenModels), maps.Clone(imagenAliasMap)
+	t.Cleanup(func() {
+		SupportedVeoModels, veoAliasMap = veoModels, veoAliases
+		SupportedImagenModels, imagenAliasMap = imagenModels, imagenAliases
+	})
+}
+
+func TestApplyModelAllowlist(t *testing.T) {
+	restoreModelMaps(t)
+	imagenCount := len(SupportedImagenModels)
+
+	unknown := ApplyModelAllowlist([]string{"Veo 3 Fast", "veo-2.0-generate-001", "veo-9.9-generate", "veo2 prevew"})
+	if !slices.Equal(unknown, []string{"veo-9.9-generate", "veo2 prevew"}) {
+		t.Errorf("expected the unknown entries to be reported, but got %v", unknown)
+	}
+	if len(SupportedVeoModels) != 2 {
+		t.Errorf("expected 2 Veo models to remain, but got %d", len(SupportedVeoModels))
+	}
+	if name, found := ResolveVeoModel("veo 3 fast"); !found || name != "veo-3.0-fast-generate-001" {
+		t.Errorf("expected an allowed alias to resolve, but got '%s' (found: %v)", name, found)
+	}
+	if name, found := ResolveVeoModel("veo-3.1-generate-preview"); found {
+		t.Errorf("expected a disallowed model not to resolve, but got '%s'", name)
+	}
+	description := BuildVeoModelDescription()
+	if strings.Contains(description, "veo-3.1-generate-preview") || !strings.Contains(description, "veo-2.0-generate-001") {
+		t.Errorf("expected the description to list only allowed models, but got '%s'", description)
+	}
+	if len(SupportedImagenModels) != imagenCount {
+		t.Errorf("expected Imagen models to be unrestricted when none are listed, but %d of %d remain", len(SupportedImagenModels), imagenCount)
+	}
+}
+
+func TestApplyModelAllowlistEmpty(t *testing.T) {
+	restoreModelMaps(t)
+	veoCount := len(SupportedVeoModels)
+
+	if unknown := ApplyModelAllowlist(nil); len(unknown) != 0 {
+		t.Errorf("expected no unknown entries, but got %v", unknown)
+	}
+	if len(SupportedVeoModels) != veoCount {
+		t.Errorf("expected every Veo model to remain, but %d of %d do", len(SupportedVeoModels), veoCount)
+	}
+}
+
+func TestVeoModelInfoPriceFor(t *testing.T) {
+	info := VeoModelInfo{PricePerSecond: 0.4, ResolutionPricePerSecond: map[string]float64{"1080p": 0.5}}
+	if price, ok := info.PriceFor("1080p"); !ok || price != 0.5 {
+		t.Errorf("expected the resolution price 0.5, but got %v (ok: %v)", price, ok)
+	}
+	if price, ok := info.PriceFor("720p"); !ok || price != 0.4 {
+		t.Errorf("expected the model price 0.4, but got %v (ok: %v)", price, ok)
+	}
+	if _, ok := (VeoModelInfo{}).PriceFor("720p"); ok {
+		t.Error("expected no price for a model without one")
+	}
+}
+
+func TestApplyModelPrices(t *testing.T) {
+	restoreModelMaps(t)
+
+	unknown := ApplyModelPrices(
+		map[string]float64{"Veo 3 Fast": 0.1, "veo-3.0-fast-generate-001:1080p": 0.2, "veo-9.9-generate": 1},
+		map[string]float64{"Imagen 4 Fast": 0.015, "imagen-9": 1},
+	)
+	if !slices.Equal(unknown, []string{"veo-9.9-generate", "imagen-9"}) {
+		t.Errorf("expected the unknown entries to be reported, but got %v", unknown)
+	}
+	if price, _ := SupportedVeoModels["veo-3.0-fast-generate-001"].PriceFor("720p"); price != 0.1 {
+		t.Errorf("expected the overridden price 0.1, but got %v", price)
+	}
+	if price, _ := SupportedVeoModels["veo-3.0-fast-generate-001"].PriceFor("1080p"); price != 0.2 {
+		t.Errorf("expected the overridden 1080p price 0.2, but got %v", price)
+	}
+	if price := SupportedImagenModels["imagen-4.0-fast-generate-001"].PricePerImage; price != 0.015 {
+		t.Errorf("expected the overridden price 0.015, but got %v", price)
+	}
+
+	if description := BuildVeoModelDescription(); !strings.Contains(description, "(Price: ~$0.10/s, 1080p ~$0.20/s)") {
+		t.Errorf("expected the description to include the prices, but got '%s'", description)
+	}
+	if description := BuildImagenModelDescription(); !strings.Contains(description, "(Price: ~$0.015/image)") {
+		t.Errorf("expected the description to include the price, but got '%s'", description)
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	testCases := []struct {
+		price    float64
+		expected string
+	}{
+		{0.4, "$0.40"},
+		{4, "$4.00"},
+		{0.035, "$0.035"},
+		{0.1 * 2 * 4, "$0.80"},
+	}
+
+	for _, tc := range testCases {
+		if actual := FormatPrice(tc.price); actual != tc.expected {
+			t.Errorf("FormatPrice(%v): expected '%s', but got '%s'", tc.price, tc.expected, actual)
+		}
+	}
+}
@@ -4,12 +4,15 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -70,3 +73,47 @@ func InitTracerProvider(serviceName, serviceVersion string) (*sdktrace.TracerPro
 
 	return tp, nil
 }
+
+// InitMeterProvider initializes and configures the OpenTelemetry meter provider, so that
+// metrics such as generation counts and latencies are exported alongside the traces set up by
+// InitTracerProvider. It uses the same OTEL_ENABLED switch, OTLP endpoint, and insecure setting,
+// and exports periodically over GRPC. It returns nil if OpenTelemetry is disabled.
+func InitMeterProvider(serviceName, serviceVersion string) (*sdkmetric.MeterProvider, error) {
+	if os.Getenv("OTEL_ENABLED") != "true" {
+		log.Println("OpenTelemetry metrics are disabled. Set OTEL_ENABLED=true to enable.")
+		return nil, nil
+	}
+	ctx := context.Background()
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "localhost:4317"
+	}
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(endpoint),
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true" {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(serviceVersion),
+		)),
+	)
+
+	// Register the meter provider as the global provider.
+	otel.SetMeterProvider(mp)
+
+	log.Printf("Meter provider initialized for service: %s, version: %s", serviceName, serviceVersion)
+
+	return mp, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+const (
+	defaultGeminiDescribeModel  = "gemini-3-pro-preview"
+	defaultGeminiDescribePrompt = "Describe this media in detail."
+)
+
+// resolveGeminiTextModel resolves a model name or alias to a Gemini model that returns text.
+// Image generation models are rejected because they are not suitable for captioning.
+func resolveGeminiTextModel(modelInput string) (string, error) {
+	if modelInput == "" {
+		modelInput = defaultGeminiDescribeModel
+	}
+	model, ok := common.ResolveGeminiModel(modelInput)
+	if !ok {
+		return "", fmt.Errorf("invalid model: %s. Supported models: %s", modelInput, common.BuildGeminiModelDescription())
+	}
+	if common.SupportedGeminiModels[model].ImageGeneration {
+		return "", fmt.Errorf("model %s is an image generation model and cannot be used to describe media; use a text model such as %s", model, defaultGeminiDescribeModel)
+	}
+	return model, nil
+}
+
+// geminiDescribeMediaHandler sends an image or video (local file or GCS URI) to a Gemini
+// text model together with a prompt and returns the model's text response.
+func geminiDescribeMediaHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "gemini_describe_media")
+	defer span.End()
+
+	args := request.GetArguments()
+
+	mediaURI, _ := args["media_uri"].(string)
+	mediaURI = strings.TrimSpace(mediaURI)
+	if mediaURI == "" {
+		return mcp.NewToolResultError("media_uri must be a non-empty string and is required"), nil
+	}
+
+	prompt, _ := args["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		prompt = defaultGeminiDescribePrompt
+	}
+
+	modelInput, _ := args["model"].(string)
+	model, err := resolveGeminiTextModel(modelInput)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := common.CheckPromptLength(model, prompt, common.SupportedGeminiModels[model].MaxPromptChars); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	mimeType := inferMimeType(mediaURI)
+	var mediaPart *genai.Part
+	if strings.HasPrefix(mediaURI, "gs://") {
+		mediaPart = genai.NewPartFromURI(mediaURI, mimeType)
+	} else {
+		data, err := os.ReadFile(mediaURI)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read media file %s: %v", mediaURI, err)), nil
+		}
+		mediaPart = genai.NewPartFromBytes(data, mimeType)
+	}
+
+	span.SetAttributes(
+		attribute.String("prompt", prompt),
+		attribute.String("model", model),
+		attribute.String("media_uri", mediaURI),
+		attribute.String("mime_type", mimeType),
+	)
+
+	log.Printf("Calling GenerateContent with Model: %s, Media: %s (%s), Prompt: \"%s\"", model, mediaURI, mimeType, prompt)
+	startTime := time.Now()
+
+	contents := &genai.Content{Parts: []*genai.Part{mediaPart, genai.NewPartFromText(prompt)}, Role: "USER"}
+	resp, err := client.Models.GenerateContent(ctx, model, []*genai.Content{contents}, &genai.GenerateContentConfig{})
+
+	apiCallDuration := time.Since(startTime)
+	log.Printf("GenerateContent call took: %v", apiCallDuration)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
+
+	if err != nil {
+		span.RecordError(err)
+		return mcp.NewToolResultError(fmt.Sprintf("error calling Gemini API: %v", err)), nil
+	}
+
+	var responseText strings.Builder
+	if resp != nil {
+		for _, candidate := range resp.Candidates {
+			if candidate == nil || candidate.Content == nil {
+				continue
+			}
+			for _, part := range candidate.Content.Parts {
+				responseText.WriteString(part.Text)
+			}
+		}
+	}
+
+	text := strings.TrimSpace(responseText.String())
+	if text == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("model %s returned no text for %s", model, mediaURI)), nil
+	}
+	result := mcp.NewToolResultText(text)
+	result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("Model: %s", model)))
+	if warning := common.SupportedGeminiModels[model].DeprecationWarning(); warning != "" {
+		log.Printf("Warning: %s", warning)
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("Warning: %s.", warning)))
+	}
+	return result, nil
+}
@@ -18,10 +18,12 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -32,41 +34,67 @@ import (
 	"google.golang.org/genai"
 )
 
-func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// geminiGenerateImageHandler generates images (and any accompanying text) with a Gemini
+// image model. Input images may be local files or GCS URIs. Generated images are uploaded
+// to GCS and/or written to a local directory; if neither destination is available they are
+// returned inline as base64 image content.
+func geminiGenerateImageHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	tr := otel.Tracer(serviceName)
-	ctx, span := tr.Start(ctx, "gemini_generate_content")
+	ctx, span := tr.Start(ctx, "gemini_generate_image")
 	defer span.End()
 
+	args := request.GetArguments()
+
 	// --- Parameter Parsing ---
-	prompt, ok := request.GetArguments()["prompt"].(string)
+	prompt, ok := args["prompt"].(string)
 	if !ok || strings.TrimSpace(prompt) == "" {
 		return mcp.NewToolResultError("prompt must be a non-empty string and is required"), nil
 	}
 
-	modelInput, _ := request.GetArguments()["model"].(string)
+	modelInput, _ := args["model"].(string)
 	if modelInput == "" {
-		modelInput = "nano-banana-pro"
+		modelInput = defaultGeminiImageModel
 	}
 
 	model, ok := common.ResolveGeminiModel(modelInput)
 	if !ok {
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid model: %s. Supported models: %s", modelInput, common.BuildGeminiModelDescription())), nil
 	}
+	if !common.SupportedGeminiModels[model].ImageGeneration {
+		return mcp.NewToolResultError(fmt.Sprintf("model %s does not support image generation; use one of: %s", model, strings.Join(geminiImageModels(), ", "))), nil
+	}
+	deprecationWarning := common.SupportedGeminiModels[model].DeprecationWarning()
+	if deprecationWarning != "" {
+		log.Printf("Warning: %s", deprecationWarning)
+	}
+	if err := common.CheckPromptLength(model, prompt, common.SupportedGeminiModels[model].MaxPromptChars); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	outputDir := ""
-	if dir, ok := request.GetArguments()["output_directory"].(string); ok && strings.TrimSpace(dir) != "" {
+	if dir, ok := args["output_directory"].(string); ok && strings.TrimSpace(dir) != "" {
 		outputDir = strings.TrimSpace(dir)
 	}
 
+	gcsOutputURI := resolveGCSOutputURI(args)
+	var gcsBucket, gcsPrefix string
+	if gcsOutputURI != "" {
+		trimmed := strings.TrimPrefix(gcsOutputURI, "gs://")
+		gcsBucket, gcsPrefix, _ = strings.Cut(trimmed, "/")
+		if gcsBucket == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid gcs_bucket_uri: %s", gcsOutputURI)), nil
+		}
+	}
+
 	// --- Construct Gemini Request ---
 	var parts []*genai.Part
 	parts = append(parts, genai.NewPartFromText(prompt))
 
-	if imageArgs, ok := request.GetArguments()["images"].([]interface{}); ok {
+	if imageArgs, ok := args["images"].([]interface{}); ok {
 		for _, imgArg := range imageArgs {
 			if imgPath, ok := imgArg.(string); ok {
 				if strings.HasPrefix(imgPath, "gs://") {
-					parts = append(parts, genai.NewPartFromURI(imgPath, ""))
+					parts = append(parts, genai.NewPartFromURI(imgPath, inferMimeType(imgPath)))
 				} else {
 					imgData, err := os.ReadFile(imgPath)
 					if err != nil {
@@ -81,11 +109,13 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 	span.SetAttributes(
 		attribute.String("prompt", prompt),
 		attribute.String("model", model),
+		attribute.Int("input_images", len(parts)-1),
+		attribute.String("gcs_output_uri", gcsOutputURI),
 		attribute.String("output_directory", outputDir),
 	)
 
 	// --- API Call ---
-	log.Printf("Calling GenerateContent with Model: %s, Prompt: \"%s\"", model, prompt)
+	log.Printf("Calling GenerateContent with Model: %s, Prompt: \"%s\", InputImages: %d", model, prompt, len(parts)-1)
 	startTime := time.Now()
 
 	config := &genai.GenerateContentConfig{}
@@ -105,42 +135,141 @@ func geminiGenerateContentHandler(client *genai.Client, ctx context.Context, req
 
 	// --- Process Response ---
 	var responseText strings.Builder
+	var contentItems []mcp.Content
 	var savedFiles []string
-	gentime := time.Now().Format("20060102150405")
+	var gcsURIs []string
+	var saveIssues []string
+	imageCount := 0
+	returnImageData := gcsOutputURI == "" && outputDir == ""
+	gentime := time.Now().Format("20060102-150405")
 
-	for _, candidate := range resp.Candidates {
-		for n, part := range candidate.Content.Parts {
-			if part.Text != "" {
-				responseText.WriteString(part.Text)
+	if resp != nil {
+		for _, candidate := range resp.Candidates {
+			if candidate == nil || candidate.Content == nil {
+				continue
 			}
-			if part.InlineData != nil {
-				log.Printf("part %d mime-type: %s", n, part.InlineData.MIMEType)
+			for _, part := range candidate.Content.Parts {
+				if part.Text != "" {
+					responseText.WriteString(part.Text)
+				}
+				if part.InlineData == nil || len(part.InlineData.Data) == 0 {
+					continue
+				}
+				mimeType := part.InlineData.MIMEType
+				if mimeType == "" {
+					mimeType = "image/png"
+				}
+				fileName := fmt.Sprintf("gemini-%s-%s-%d%s", model, gentime, imageCount, extensionForMimeType(mimeType))
+				imageCount++
+				log.Printf("Image %s received (Size: %s, MIME: %s)", fileName, common.FormatBytes(int64(len(part.InlineData.Data))), mimeType)
+
+				if gcsBucket != "" {
+					objectName := gcsPrefix + fileName
+					if err := common.UploadToGCS(ctx, gcsBucket, objectName, mimeType, part.InlineData.Data); err != nil {
+						log.Printf("Failed to upload %s to GCS: %v", objectName, err)
+						saveIssues = append(saveIssues, fmt.Sprintf("GCS upload of %s failed: %v", fileName, err))
+					} else {
+						gcsURIs = append(gcsURIs, fmt.Sprintf("gs://%s/%s", gcsBucket, objectName))
+					}
+				}
 
 				if outputDir != "" {
 					if err := os.MkdirAll(outputDir, 0755); err != nil {
 						return mcp.NewToolResultError(fmt.Sprintf("failed to create output directory: %v", err)), nil
 					}
-					fileName := fmt.Sprintf("gemini_%s_%d.png", gentime, n)
 					filePath := filepath.Join(outputDir, fileName)
 					if err := os.WriteFile(filePath, part.InlineData.Data, 0644); err != nil {
-						return mcp.NewToolResultError(fmt.Sprintf("failed to write image file: %v", err)), nil
+						log.Printf("Failed to write %s: %v", filePath, err)
+						saveIssues = append(saveIssues, fmt.Sprintf("local save of %s failed: %v", fileName, err))
+					} else {
+						savedFiles = append(savedFiles, filePath)
 					}
-					savedFiles = append(savedFiles, filePath)
-				} else {
-					// If no output dir, should we return base64? For now, we just log.
-					log.Println("Received image data but no output_directory was specified. Image not saved.")
+				}
+
+				if returnImageData {
+					contentItems = append(contentItems, mcp.ImageContent{
+						Type:     "image",
+						Data:     base64.StdEncoding.EncodeToString(part.InlineData.Data),
+						MIMEType: mimeType,
+					})
 				}
 			}
 		}
 	}
+	span.SetAttributes(attribute.Int("generated_images", imageCount))
 
 	// --- Format Final Result ---
-	finalMessage := responseText.String()
+	var messageParts []string
+	if text := strings.TrimSpace(responseText.String()); text != "" {
+		messageParts = append(messageParts, text)
+	}
+	if imageCount == 0 {
+		messageParts = append(messageParts, fmt.Sprintf("Model %s did not return any images for the prompt.", model))
+	} else {
+		messageParts = append(messageParts, fmt.Sprintf("Generated %d image(s) with model %s.", imageCount, model))
+	}
+	if len(gcsURIs) > 0 {
+		messageParts = append(messageParts, fmt.Sprintf("Images saved to GCS: %s.", strings.Join(gcsURIs, ", ")))
+	}
 	if len(savedFiles) > 0 {
-		finalMessage += fmt.Sprintf("\n\nGenerated and saved %d image(s): %s", len(savedFiles), strings.Join(savedFiles, ", "))
+		messageParts = append(messageParts, fmt.Sprintf("Images saved locally: %s.", strings.Join(savedFiles, ", ")))
+	}
+	if len(saveIssues) > 0 {
+		messageParts = append(messageParts, fmt.Sprintf("Save issues: %s.", strings.Join(saveIssues, "; ")))
+	}
+	if returnImageData && imageCount > 0 {
+		messageParts = append(messageParts, "Image(s) are included in this MCP response as base64 data.")
+	}
+	if deprecationWarning != "" {
+		messageParts = append(messageParts, fmt.Sprintf("Warning: %s.", deprecationWarning))
 	}
 
-	return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: strings.TrimSpace(finalMessage)}}}, nil
+	contentItems = append([]mcp.Content{mcp.TextContent{Type: "text", Text: strings.Join(messageParts, "\n\n")}}, contentItems...)
+	return &mcp.CallToolResult{Content: contentItems}, nil
+}
+
+// resolveGCSOutputURI returns the GCS prefix generated images should be uploaded to,
+// falling back to GENMEDIA_BUCKET when gcs_bucket_uri is not provided. An empty string
+// means no GCS output.
+func resolveGCSOutputURI(args map[string]interface{}) string {
+	gcsOutputURI := ""
+	if gcsBucketURI, _ := args["gcs_bucket_uri"].(string); strings.TrimSpace(gcsBucketURI) != "" {
+		gcsOutputURI = common.EnsureGCSPathPrefix(strings.TrimSpace(gcsBucketURI))
+	} else if appConfig != nil && appConfig.GenmediaBucket != "" {
+		gcsOutputURI = fmt.Sprintf("gs://%s/gemini_outputs/", appConfig.GenmediaBucket)
+		log.Printf("Handler gemini_generate_image: 'gcs_bucket_uri' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsOutputURI)
+	}
+
+	if gcsOutputURI != "" && !strings.HasSuffix(gcsOutputURI, "/") {
+		gcsOutputURI += "/"
+	}
+	return gcsOutputURI
+}
+
+// geminiImageModels returns the sorted canonical names of the Gemini models that can generate images.
+func geminiImageModels() []string {
+	var models []string
+	for name, info := range common.SupportedGeminiModels {
+		if info.ImageGeneration {
+			models = append(models, name)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// extensionForMimeType returns the file extension to use for a generated image.
+func extensionForMimeType(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
 }
 
 func inferMimeType(path string) string {
@@ -154,6 +283,12 @@ func inferMimeType(path string) string {
 		return "image/gif"
 	case ".webp":
 		return "image/webp"
+	case ".mp4":
+		return "video/mp4"
+	case ".mov":
+		return "video/quicktime"
+	case ".webm":
+		return "video/webm"
 	default:
 		// Defaulting to a common image type if extension is unknown, as the API might handle it.
 		// A more robust solution might involve reading file headers.
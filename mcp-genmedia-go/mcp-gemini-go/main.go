@@ -41,7 +41,9 @@ var (
 
 const (
 	serviceName = "mcp-gemini-go"
-	version     = "0.5.1" // Add Gemini 1.5 Pro Preview models
+	version     = "0.9.0" // Reject prompts over the model limit
+
+	defaultGeminiImageModel = "nano-banana-pro"
 )
 
 func init() {
@@ -96,19 +98,31 @@ func main() {
 
 	s := server.NewMCPServer("Gemini", version, server.WithResourceCapabilities(true, false))
 
-	tool := mcp.NewTool("gemini_image_generation",
-		mcp.WithDescription(common.BuildGeminiModelDescription()),
-		mcp.WithString("prompt", mcp.Required(), mcp.Description("The text prompt for content generation.")),
-		mcp.WithString("model", mcp.DefaultString("nano-banana-pro"), mcp.Description("The specific Gemini model to use.")),
+	imageToolOptions := []mcp.ToolOption{
+		mcp.WithDescription("Generates images from a text prompt and optional input images using a Gemini image model. Generated images are saved to GCS and/or a local directory, or returned inline if neither is available.\n\n" + common.BuildGeminiModelDescription()),
+		mcp.WithString("prompt", mcp.Required(), mcp.Description("The text prompt for image generation.")),
+		mcp.WithString("model", mcp.DefaultString(defaultGeminiImageModel), mcp.Description("The Gemini image model to use. Must be a model that supports image generation.")),
 		mcp.WithArray("images", mcp.Description("Optional. A list of local file paths or GCS URIs for input images.")),
 		mcp.WithString("output_directory", mcp.Description("Optional. Local directory to save generated image(s) to.")),
-		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store generated images (e.g., your-bucket/outputs/).")),
-	)
+		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store generated images (e.g., your-bucket/outputs/). Defaults to gs://$GENMEDIA_BUCKET/gemini_outputs/ if GENMEDIA_BUCKET is set.")),
+	}
 
 	handlerWithClient := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return geminiGenerateContentHandler(genAIClient, ctx, request)
+		return geminiGenerateImageHandler(genAIClient, ctx, request)
 	}
-	s.AddTool(tool, handlerWithClient)
+	s.AddTool(mcp.NewTool("gemini_generate_image", imageToolOptions...), handlerWithClient)
+	// gemini_image_generation is kept as an alias so existing clients keep working.
+	s.AddTool(mcp.NewTool("gemini_image_generation", imageToolOptions...), handlerWithClient)
+
+	describeTool := mcp.NewTool("gemini_describe_media",
+		mcp.WithDescription("Describes or captions an image or video using a Gemini text model. Useful for feeding generated media back to Gemini."),
+		mcp.WithString("media_uri", mcp.Required(), mcp.Description("A GCS URI (gs://...) or local file path of the image or video to describe.")),
+		mcp.WithString("prompt", mcp.DefaultString(defaultGeminiDescribePrompt), mcp.Description("Instructions for the description or caption.")),
+		mcp.WithString("model", mcp.DefaultString(defaultGeminiDescribeModel), mcp.Description("The Gemini text model to use. Image generation models are not accepted.")),
+	)
+	s.AddTool(describeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return geminiDescribeMediaHandler(genAIClient, ctx, request)
+	})
 
 	// --- Register Gemini TTS Tools ---
 	listVoicesTool := mcp.NewTool("list_gemini_voices",
@@ -305,7 +305,7 @@ func geminiAudioTTSHandler(ctx context.Context, request mcp.CallToolRequest) (*m
 		fileSaveMessage = "Audio data is included in the response."
 	}
 
-	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s. %s", voiceName, fileSaveMessage)
+	resultText := fmt.Sprintf("Speech synthesized successfully with voice %s using model %s. %s", voiceName, modelName, fileSaveMessage)
 	contentItems = append([]mcp.Content{mcp.TextContent{Type: "text", Text: resultText}}, contentItems...)
 
 	return &mcp.CallToolResult{Content: contentItems}, nil
@@ -31,10 +31,10 @@ go build -o mcp-gemini-go
 response=$(mcptools tools ./mcp-gemini-go)
 
 # Check if the response contains the expected tool name
-if [[ "$response" == *"gemini_image_generation"* ]]; then
-  echo "Verification successful: 'gemini_image_generation' tool found."
+if [[ "$response" == *"gemini_generate_image"* ]]; then
+  echo "Verification successful: 'gemini_generate_image' tool found."
 else
-  echo "Verification failed: Could not find 'gemini_generate_content' tool in the server's response."
+  echo "Verification failed: Could not find 'gemini_generate_image' tool in the server's response."
   echo "Response was:"
   echo "$response"
   exit 1
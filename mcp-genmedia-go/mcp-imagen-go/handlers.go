@@ -0,0 +1,307 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Imagen models.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// imagenGenerationHandler is the handler for the 'imagen_t2i' tool. It validates the
+// request against the resolved model's constraints, calls GenerateImages, and saves the
+// results to GCS and/or a local directory, or returns them inline as base64 data.
+func imagenGenerationHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "imagen_t2i")
+	defer span.End()
+
+	prompt, ok := request.GetArguments()["prompt"].(string)
+	if !ok || strings.TrimSpace(prompt) == "" {
+		return mcp.NewToolResultError("prompt must be a non-empty string and is required"), nil
+	}
+
+	params, err := parseImagenParams(request.GetArguments(), appConfig)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	model := params.Model
+	gcsOutputURI := params.GCSOutputURI
+	outputDir := params.OutputDir
+	attemptLocalSave := outputDir != ""
+
+	span.SetAttributes(
+		attribute.String("prompt", prompt),
+		attribute.String("model", model),
+		attribute.Int("num_images", int(params.NumberOfImages)),
+		attribute.String("aspect_ratio", params.AspectRatio),
+		attribute.String("image_size", params.ImageSize),
+		attribute.String("gcs_output_uri", gcsOutputURI),
+		attribute.String("output_directory", outputDir),
+	)
+	if params.NegativePrompt != "" {
+		span.SetAttributes(attribute.String("negative_prompt", params.NegativePrompt))
+	}
+	if params.Seed != nil {
+		span.SetAttributes(attribute.Int("seed", int(*params.Seed)))
+	}
+	if params.SafetyFilterLevel != "" {
+		span.SetAttributes(attribute.String("safety_filter_level", string(params.SafetyFilterLevel)))
+	}
+	if params.AddWatermark != nil {
+		span.SetAttributes(attribute.Bool("add_watermark", *params.AddWatermark))
+	}
+	span.SetAttributes(attribute.String("watermark_status", params.WatermarkStatus()))
+
+	select {
+	case <-ctx.Done():
+		log.Printf("Incoming context for prompt \"%s\" was already canceled: %v", prompt, ctx.Err())
+		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
+	default:
+		log.Printf("Handling imagen request: Prompt=\"%s\", Model=%s, NumImages=%d, AspectRatio=%s, ImageSize=%s, GCSOutputURI='%s', OutputDirectory='%s'",
+			prompt, model, params.NumberOfImages, params.AspectRatio, params.ImageSize, gcsOutputURI, outputDir)
+	}
+
+	config := params.GenerateImagesConfig()
+
+	apiCallCtx, apiCallCancel := context.WithTimeout(ctx, 3*time.Minute)
+	defer apiCallCancel()
+
+	log.Printf("Calling GenerateImages with Model: %s, Prompt: \"%s\". API call timeout: 3m", model, prompt)
+	startTime := time.Now()
+
+	response, err := client.Models.GenerateImages(
+		apiCallCtx,
+		model,
+		prompt,
+		config,
+	)
+
+	apiCallDuration := time.Since(startTime)
+	log.Printf("GenerateImages call took: %v", apiCallDuration)
+	span.SetAttributes(attribute.Float64("duration_ms", float64(apiCallDuration.Milliseconds())))
+
+	var contentItems []mcp.Content
+
+	if err != nil {
+		errorMessage := fmt.Sprintf("error generating images: %v", err.Error())
+		if errors.Is(err, context.DeadlineExceeded) && apiCallCtx.Err() == context.DeadlineExceeded {
+			log.Printf("GenerateImages failed due to API call timeout (3 minutes): %v", err)
+			errorMessage = "image generation timed out"
+		} else if errors.Is(err, context.Canceled) {
+			log.Printf("GenerateImages failed due to context cancellation: %v", err)
+			errorMessage = "image generation was canceled"
+		} else {
+			log.Printf("Error generating images (API call failed): %v", err)
+		}
+		span.RecordError(err)
+		return mcp.NewToolResultError(errorMessage), nil
+	}
+
+	if response == nil || len(response.GeneratedImages) == 0 {
+		noImageText := fmt.Sprintf("Sorry, I couldn't generate any images with model %s for the prompt \"%s\".", model, prompt)
+		log.Print(noImageText)
+		contentItems = append(contentItems, mcp.TextContent{Type: "text", Text: noImageText})
+		return &mcp.CallToolResult{Content: contentItems}, nil
+	}
+
+	log.Printf("Successfully received %d image metadata/references from API.", len(response.GeneratedImages))
+
+	var savedLocalFilenames []string
+	var failedLocalSaveReasons []string
+	var gcsSavedURIs []string
+	var totalSizeBytesGenerated int64 = 0
+	var imagesWithDataOrURI int = 0
+	returnImageDataInResponse := gcsOutputURI == "" && !attemptLocalSave
+	log.Printf("Will return image data in response: %t", returnImageDataInResponse)
+
+	for n, genImg := range response.GeneratedImages {
+		var imageData []byte
+		var imageMimeType string = "image/png"
+		var imageSourceIsGCS bool = false
+		var currentImageGCSURI string
+
+		if genImg.Image != nil && genImg.Image.GCSURI != "" {
+			currentImageGCSURI = genImg.Image.GCSURI
+			imagesWithDataOrURI++
+			imageSourceIsGCS = true
+			gcsSavedURIs = append(gcsSavedURIs, currentImageGCSURI)
+			log.Printf("Image %d available at GCS URI (from API response): %s", n, currentImageGCSURI)
+			if genImg.Image.MIMEType != "" {
+				imageMimeType = genImg.Image.MIMEType
+			}
+		} else if genImg.Image != nil && genImg.Image.ImageBytes != nil && len(genImg.Image.ImageBytes) > 0 {
+			imagesWithDataOrURI++
+			imageData = genImg.Image.ImageBytes
+			totalSizeBytesGenerated += int64(len(imageData))
+			if genImg.Image.MIMEType != "" {
+				imageMimeType = genImg.Image.MIMEType
+			}
+			log.Printf("Image %d received as bytes from API (Size: %s, MIME: %s)", n, common.FormatBytes(int64(len(imageData))), imageMimeType)
+		} else {
+			log.Printf("Generated image %d (model: %s) from API had no GCS URI and no direct image data.", n, model)
+			continue
+		}
+
+		if attemptLocalSave {
+			localFilename := fmt.Sprintf("imagen-%s-%s-%d", model, time.Now().Format("20060102-150405"), n)
+			switch imageMimeType {
+			case "image/jpeg":
+				localFilename += ".jpg"
+			case "image/webp":
+				localFilename += ".webp"
+			default:
+				localFilename += ".png"
+			}
+			actualSavePath := filepath.Join(outputDir, localFilename)
+			actualSavePath = filepath.Clean(actualSavePath)
+
+			if imageSourceIsGCS {
+				log.Printf("Attempting to download image %d from GCS URI %s to %s", n, currentImageGCSURI, actualSavePath)
+				downloadCtx, downloadCancel := context.WithTimeout(ctx, 2*time.Minute)
+				err := common.DownloadFromGCS(downloadCtx, currentImageGCSURI, actualSavePath)
+				downloadCancel()
+				if err != nil {
+					log.Print(err)
+					failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
+				} else {
+					log.Printf("Successfully downloaded and saved image %d to %s", n, actualSavePath)
+					savedLocalFilenames = append(savedLocalFilenames, actualSavePath)
+					fileInfo, statErr := os.Stat(actualSavePath)
+					if statErr == nil {
+						totalSizeBytesGenerated += fileInfo.Size()
+					} else {
+						log.Printf("Could not get file info for downloaded file %s: %v", actualSavePath, statErr)
+					}
+				}
+			} else if len(imageData) > 0 {
+				if err := os.MkdirAll(outputDir, 0755); err != nil {
+					log.Print(err)
+					failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
+				} else {
+					if err := os.WriteFile(actualSavePath, imageData, 0644); err != nil {
+						log.Print(err)
+						failedLocalSaveReasons = append(failedLocalSaveReasons, err.Error())
+					} else {
+						log.Printf("Saved image %s (Size: %s)", actualSavePath, common.FormatBytes(int64(len(imageData))))
+						savedLocalFilenames = append(savedLocalFilenames, actualSavePath)
+					}
+				}
+			}
+		}
+
+		if returnImageDataInResponse && len(imageData) > 0 {
+			base64Data := base64.StdEncoding.EncodeToString(imageData)
+			imageItem := mcp.ImageContent{
+				Type:     "image",
+				Data:     base64Data,
+				MIMEType: imageMimeType,
+			}
+			contentItems = append(contentItems, imageItem)
+		}
+	}
+
+	var resultText string
+	var saveMessageParts []string
+
+	if gcsOutputURI != "" {
+		if len(gcsSavedURIs) > 0 {
+			httpURIs := make([]string, len(gcsSavedURIs))
+			for i, gcsUri := range gcsSavedURIs {
+				httpURIs[i] = strings.Replace(gcsUri, "gs://", "https://storage.mtls.cloud.google.com/", 1)
+			}
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Images saved to GCS: %s. HTTPS URLs: %s.", strings.Join(gcsSavedURIs, ", "), strings.Join(httpURIs, ", ")))
+		} else if imagesWithDataOrURI > 0 && len(gcsSavedURIs) == 0 {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("GCS output was requested to '%s', but API did not return GCS URIs for the generated images.", config.OutputGCSURI))
+		} else {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("GCS output was requested to '%s', but no images with GCS URIs were returned by the API.", config.OutputGCSURI))
+		}
+	}
+
+	if attemptLocalSave {
+		if gcsOutputURI != "" {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to download images from GCS to local directory '%s'.", outputDir))
+		} else {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to save images from API response bytes to local directory '%s'.", outputDir))
+		}
+		if len(savedLocalFilenames) > 0 {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Successfully saved locally: %s.", strings.Join(savedLocalFilenames, ", ")))
+		}
+		if len(failedLocalSaveReasons) > 0 {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Local save/download issues: %s.", strings.Join(failedLocalSaveReasons, "; ")))
+		}
+	}
+
+	if !returnImageDataInResponse {
+		saveMessageParts = append(saveMessageParts, "Image data is not included in this MCP response because a GCS URI or local output directory was specified.")
+	} else if returnImageDataInResponse && imagesWithDataOrURI > 0 {
+		saveMessageParts = append(saveMessageParts, "Image(s) are included in this MCP response as base64 data.")
+	}
+
+	sizeReport := ""
+	if totalSizeBytesGenerated > 0 {
+		sizeReport = fmt.Sprintf("(total processed/downloaded byte size: %s) ", common.FormatBytes(totalSizeBytesGenerated))
+	} else if len(gcsSavedURIs) > 0 && !attemptLocalSave {
+		sizeReport = "(image sizes are on GCS) "
+	}
+
+	if imagesWithDataOrURI > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("SynthID watermark: %s.", params.WatermarkStatus()))
+		resultText = fmt.Sprintf("Generated %d image(s) %susing model %s for prompt \"%s\". This took about %s. %s",
+			imagesWithDataOrURI,
+			sizeReport,
+			model,
+			prompt,
+			apiCallDuration.Round(time.Second),
+			strings.Join(saveMessageParts, " "),
+		)
+	} else {
+		resultText = fmt.Sprintf("Processed request for model %s with prompt \"%s\" (took %s), but no images with data or GCS URIs were returned by the API. SynthID watermark: %s.",
+			model,
+			prompt,
+			apiCallDuration.Round(time.Second),
+			params.WatermarkStatus(),
+		)
+	}
+
+	textItem := mcp.TextContent{
+		Type: "text",
+		Text: strings.TrimSpace(resultText),
+	}
+
+	finalContentItems := []mcp.Content{textItem}
+	if len(params.Warnings) > 0 {
+		finalContentItems = append(finalContentItems, mcp.NewTextContent(fmt.Sprintf("Warnings: %s.", strings.Join(params.Warnings, "; "))))
+	}
+	if returnImageDataInResponse {
+		finalContentItems = append(finalContentItems, contentItems...)
+	}
+
+	return &mcp.CallToolResult{Content: finalContentItems}, nil
+}
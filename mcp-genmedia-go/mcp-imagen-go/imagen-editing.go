@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,9 +14,14 @@ import (
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/genai"
 )
 
+// imagenEditModel is the Imagen model used for all editing operations.
+const imagenEditModel = "imagen-3.0-capability-001"
+
 // SegmentationClassMap maps human-readable names to the integer IDs required by the Imagen API.
 var SegmentationClassMap = map[string]int32{
 	"backpack": 0, "umbrella": 1, "bag": 2, "tie": 3, "suitcase": 4, "case": 5, "bird": 6, "cat": 7, "dog": 8, "horse": 9, "sheep": 10, "cow": 11, "elephant": 12, "bear": 13, "zebra": 14, "giraffe": 15, "animal (other)": 16, "microwave": 17, "radiator": 18, "oven": 19, "toaster": 20, "storage tank": 21, "conveyor belt": 22, "sink": 23, "refrigerator": 24, "washer dryer": 25, "fan": 26, "dishwasher": 27, "toilet": 28, "bathtub": 29, "shower": 30, "tunnel": 31, "bridge": 32, "pier wharf": 33, "tent": 34, "building": 35, "ceiling": 36, "laptop": 37, "keyboard": 38, "mouse": 39, "remote": 40, "cell phone": 41, "television": 42, "floor": 43, "stage": 44, "banana": 45, "apple": 46, "sandwich": 47, "orange": 48, "broccoli": 49, "carrot": 50, "hot dog": 51, "pizza": 52, "donut": 53, "cake": 54, "fruit (other)": 55, "food (other)": 56, "chair (other)": 57, "armchair": 58, "swivel chair": 59, "stool": 60, "seat": 61, "couch": 62, "trash can": 63, "potted plant": 64, "nightstand": 65, "bed": 66, "table": 67, "pool table": 68, "barrel": 69, "desk": 70, "ottoman": 71, "wardrobe": 72, "crib": 73, "basket": 74, "chest of drawers": 75, "bookshelf": 76, "counter (other)": 77, "bathroom counter": 78, "kitchen island": 79, "door": 80, "light (other)": 81, "lamp": 82, "sconce": 83, "chandelier": 84, "mirror": 85, "whiteboard": 86, "shelf": 87, "stairs": 88, "escalator": 89, "cabinet": 90, "fireplace": 91, "stove": 92, "arcade machine": 93, "gravel": 94, "platform": 95, "playingfield": 96, "railroad": 97, "road": 98, "snow": 99, "sidewalk pavement": 100, "runway": 101, "terrain": 102, "book": 103, "box": 104, "clock": 105, "vase": 106, "scissors": 107, "plaything (other)": 108, "teddy bear": 109, "hair dryer": 110, "toothbrush": 111, "painting": 112, "poster": 113, "bulletin board": 114, "bottle": 115, "cup": 116, "wine glass": 117, "knife": 118, "fork": 119, "spoon": 120, "bowl": 121, "tray": 122, "range hood": 123, "plate": 124, "person": 125, "rider (other)": 126, "bicyclist": 127, "motorcyclist": 128, "paper": 129, "streetlight": 130, "road barrier": 131, "mailbox": 132, "cctv camera": 133, "junction box": 134, "traffic sign": 135, "traffic light": 136, "fire hydrant": 137, "parking meter": 138, "bench": 139, "bike rack": 140, "billboard": 141, "sky": 142, "pole": 143, "fence": 144, "railing banister": 145, "guard rail": 146, "mountain hill": 147, "rock": 148, "frisbee": 149, "skis": 150, "snowboard": 151, "sports ball": 152, "kite": 153, "baseball bat": 154, "baseball glove": 155, "skateboard": 156, "surfboard": 157, "tennis racket": 158, "net": 159, "base": 160, "sculpture": 161, "column": 162, "fountain": 163, "awning": 164, "apparel": 165, "banner": 166, "flag": 167, "blanket": 168, "curtain (other)": 169, "shower curtain": 170, "pillow": 171, "towel": 172, "rug floormat": 173, "vegetation": 174, "bicycle": 175, "car": 176, "autorickshaw": 177, "motorcycle": 178, "airplane": 179, "bus": 180, "train": 181, "truck": 182, "trailer": 183, "boat ship": 184, "slow wheeled object": 185, "river lake": 186, "sea": 187, "water (other)": 188, "swimming pool": 189, "waterfall": 190, "wall": 191, "window": 192, "window blind": 193,
@@ -48,7 +54,7 @@ func registerImagenEditingTools(s *server.MCPServer, client *genai.Client, appCo
 	s.AddTool(mcp.NewTool("imagen_edit_inpainting_insert",
 		mcp.WithDescription("Adds content to a masked area of an image."),
 		mcp.WithString("prompt", mcp.Required(), mcp.Description("A description of the content to add.")),
-		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit.")),
+		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit. Supported formats: JPEG, PNG, WebP, GIF, TIFF, and BMP.")),
 		mcp.WithString("mask_mode", mcp.Required(), mcp.Description("The masking mode to use (e.g., MASK_MODE_FOREGROUND, MASK_MODE_SEMANTIC).")),
 		mcp.WithNumber("mask_dilation", mcp.Description("The dilation to apply to the mask.")),
 		mcp.WithArray("segmentation_classes", mcp.Description("The segmentation classes to use for semantic masking.")),
@@ -59,7 +65,7 @@ func registerImagenEditingTools(s *server.MCPServer, client *genai.Client, appCo
 	// Inpainting Remove Tool
 	s.AddTool(mcp.NewTool("imagen_edit_inpainting_remove",
 		mcp.WithDescription("Removes content from a masked area of an image."),
-		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit.")),
+		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the image to edit. Supported formats: JPEG, PNG, WebP, GIF, TIFF, and BMP.")),
 		mcp.WithString("mask_mode", mcp.Required(), mcp.Description("The masking mode to use (e.g., MASK_MODE_FOREGROUND, MASK_MODE_SEMANTIC).")),
 		mcp.WithNumber("mask_dilation", mcp.Description("The dilation to apply to the mask.")),
 		mcp.WithArray("segmentation_classes", mcp.Description("The segmentation classes to use for semantic masking.")),
@@ -67,6 +73,30 @@ func registerImagenEditingTools(s *server.MCPServer, client *genai.Client, appCo
 		return imagenEditHandler(ctx, request, client, appConfig)
 	})
 
+	// General Edit Tool
+	s.AddTool(mcp.NewTool("imagen_edit",
+		mcp.WithDescription("Edits an existing image with an edit-capable Imagen model: inserts content into or removes content from a masked area (inpainting), or extends the image into a masked area (outpainting). Returns the edited images as GCS URIs, or inline if no GCS output is configured."),
+		mcp.WithString("image_uri", mcp.Required(), mcp.Description("The GCS URI of the base image to edit. Supported formats: JPEG, PNG, WebP, GIF, TIFF, and BMP.")),
+		mcp.WithString("mask_uri", mcp.Description("Optional. The GCS URI of a mask image whose white pixels mark the area to edit. Required for outpainting. If not provided for inpainting, the model decides which area to edit.")),
+		mcp.WithString("prompt", mcp.Description("A description of the desired edit. Required unless edit_mode is 'inpaint_removal'.")),
+		mcp.WithString("edit_mode",
+			mcp.Enum(supportedEditModes...),
+			mcp.DefaultString("inpaint_insertion"),
+			mcp.Description("Optional. 'inpaint_insertion' adds content to the masked area, 'inpaint_removal' removes it, and 'outpaint' extends the image into the masked area."),
+		),
+		mcp.WithString("model",
+			mcp.DefaultString(imagenEditModel),
+			mcp.Description(fmt.Sprintf("Optional. Edit-capable Imagen model to use. Defaults to %s. Models that do not support editing are rejected.", imagenEditModel)),
+		),
+		mcp.WithNumber("num_images",
+			mcp.DefaultNumber(1),
+			mcp.Description("Optional. Number of edited images to generate. The maximum is model-dependent."),
+		),
+		mcp.WithString("gcs_bucket_uri", mcp.Description("Optional. GCS URI prefix to store the edited images. If not provided, GENMEDIA_BUCKET is used; if neither is set, the images are returned inline.")),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return imagenEditImageHandler(ctx, request, client, appConfig)
+	})
+
 	// Edit Image Area Prompt
 	s.AddPrompt(mcp.NewPrompt("edit_image_area",
 		mcp.WithPromptDescription("Interactively guides a user to add or remove content from a specific area of an image."),
@@ -147,6 +177,10 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 		return mcp.NewToolResultError(fmt.Sprintf("unsupported tool for imagenEditHandler: %s", request.Params.Name)), nil
 	}
 
+	if err := common.CheckImagenCapability(imagenEditModel, common.ImagenCapabilityEdit); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get the required arguments
 	prompt, _ := args["prompt"].(string)
 	imageURI, ok := args["image_uri"].(string)
@@ -160,9 +194,14 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 		return mcp.NewToolResultError(fmt.Sprintf("failed to download image from GCS: %v", err)), nil
 	}
 
+	imageMimeType, err := resolveEditImageMimeType(imageURI, imageData)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Construct the reference images
 	rawRefImg := &genai.RawReferenceImage{
-		ReferenceImage: &genai.Image{ImageBytes: imageData},
+		ReferenceImage: &genai.Image{ImageBytes: imageData, MIMEType: imageMimeType},
 		ReferenceID:    1,
 	}
 
@@ -219,7 +258,7 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 
 	response, err := client.Models.EditImage(
 		ctx,
-		"imagen-3.0-capability-001",
+		imagenEditModel,
 		prompt,
 		referenceImages,
 		editConfig,
@@ -241,16 +280,131 @@ func imagenEditHandler(ctx context.Context, request mcp.CallToolRequest, client
 				return mcp.NewToolResultError(fmt.Sprintf("error uploading edited image to GCS: %v", err)), nil
 			}
 			gcsURI := fmt.Sprintf("gs://%s/%s", appConfig.GenmediaBucket, filename)
-			resultText = fmt.Sprintf("Image edited successfully. Edited image URI: %s", gcsURI)
+			resultText = fmt.Sprintf("Image edited successfully using model %s. Edited image URI: %s", imagenEditModel, gcsURI)
 		} else if genImg.Image != nil && genImg.Image.GCSURI != "" {
 			// The image is already in GCS.
-			resultText = fmt.Sprintf("Image edited successfully. Edited image URI: %s", genImg.Image.GCSURI)
+			resultText = fmt.Sprintf("Image edited successfully using model %s. Edited image URI: %s", imagenEditModel, genImg.Image.GCSURI)
 		} else {
-			resultText = "Image editing did not produce any images."
+			resultText = fmt.Sprintf("Image editing with model %s did not produce any images.", imagenEditModel)
 		}
 	} else {
-		resultText = "Image editing did not produce any images."
+		resultText = fmt.Sprintf("Image editing with model %s did not produce any images.", imagenEditModel)
 	}
 
 	return mcp.NewToolResultText(resultText), nil
 }
+
+// loadEditImage downloads an input image for the editing tools from GCS and sets its MIME
+// type, which must be in editInputImageMimeTypes. paramName is used in error messages.
+func loadEditImage(ctx context.Context, paramName, gcsURI string) (*genai.Image, error) {
+	data, err := common.DownloadFromGCSAsBytes(ctx, gcsURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from GCS: %w", paramName, err)
+	}
+	mimeType, err := resolveEditImageMimeType(gcsURI, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", paramName, err)
+	}
+	return &genai.Image{ImageBytes: data, MIMEType: mimeType}, nil
+}
+
+// imagenEditImageHandler is the handler for the 'imagen_edit' tool. It resolves an
+// edit-capable model, loads the base image and optional mask from GCS, and calls EditImage.
+// The edited images are written to the GCS output location if one is configured, or
+// otherwise returned inline as base64 data.
+func imagenEditImageHandler(ctx context.Context, request mcp.CallToolRequest, client *genai.Client, appConfig *common.Config) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "imagen_edit")
+	defer span.End()
+
+	params, err := parseEditParams(request.GetArguments(), appConfig)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	span.SetAttributes(
+		attribute.String("prompt", params.Prompt),
+		attribute.String("model", params.Model),
+		attribute.String("edit_mode", string(params.EditMode)),
+		attribute.String("image_uri", params.ImageURI),
+		attribute.String("mask_uri", params.MaskURI),
+		attribute.Int("num_images", int(params.NumberOfImages)),
+		attribute.String("gcs_output_uri", params.GCSOutputURI),
+	)
+
+	baseImage, err := loadEditImage(ctx, "image_uri", params.ImageURI)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	referenceImages := []genai.ReferenceImage{
+		&genai.RawReferenceImage{ReferenceImage: baseImage, ReferenceID: 1},
+	}
+	if params.MaskURI != "" {
+		maskImage, err := loadEditImage(ctx, "mask_uri", params.MaskURI)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		referenceImages = append(referenceImages, &genai.MaskReferenceImage{
+			ReferenceImage: maskImage,
+			ReferenceID:    2,
+			Config:         &genai.MaskReferenceConfig{MaskMode: genai.MaskReferenceModeMaskModeUserProvided},
+		})
+	}
+
+	editConfig := &genai.EditImageConfig{
+		EditMode:       params.EditMode,
+		NumberOfImages: params.NumberOfImages,
+		OutputGCSURI:   params.GCSOutputURI,
+	}
+	log.Printf("Calling EditImage with model %s, edit mode %s, image %s, mask '%s'", params.Model, params.EditMode, params.ImageURI, params.MaskURI)
+	startTime := time.Now()
+	response, err := client.Models.EditImage(ctx, params.Model, params.Prompt, referenceImages, editConfig)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("error editing image with model %s: %v", params.Model, err)), nil
+	}
+	duration := time.Since(startTime)
+
+	var gcsURIs []string
+	var inlineImages []mcp.Content
+	if response != nil {
+		for _, generatedImage := range response.GeneratedImages {
+			if generatedImage.Image == nil {
+				continue
+			}
+			if generatedImage.Image.GCSURI != "" {
+				gcsURIs = append(gcsURIs, generatedImage.Image.GCSURI)
+			} else if len(generatedImage.Image.ImageBytes) > 0 {
+				mimeType := generatedImage.Image.MIMEType
+				if mimeType == "" {
+					mimeType = "image/png"
+				}
+				inlineImages = append(inlineImages, mcp.ImageContent{
+					Type:     "image",
+					Data:     base64.StdEncoding.EncodeToString(generatedImage.Image.ImageBytes),
+					MIMEType: mimeType,
+				})
+			}
+		}
+	}
+	editedCount := len(gcsURIs) + len(inlineImages)
+	span.SetAttributes(attribute.Int("edited_images", editedCount))
+
+	var resultText string
+	if editedCount == 0 {
+		resultText = fmt.Sprintf("Image editing (%s) with model %s did not produce any images. The output may have been filtered by the safety filters.", params.EditMode, params.Model)
+	} else {
+		resultText = fmt.Sprintf("Edited %s (%s) with model %s, producing %d image(s). This took about %s.", params.ImageURI, params.EditMode, params.Model, editedCount, duration.Round(time.Second))
+		if len(gcsURIs) > 0 {
+			resultText += fmt.Sprintf(" Edited images saved to GCS: %s.", strings.Join(gcsURIs, ", "))
+		}
+		if len(inlineImages) > 0 {
+			resultText += fmt.Sprintf(" %d image(s) are returned inline as base64-encoded data.", len(inlineImages))
+		}
+	}
+
+	contentItems := []mcp.Content{mcp.NewTextContent(resultText)}
+	if len(params.Warnings) > 0 {
+		contentItems = append(contentItems, mcp.NewTextContent(fmt.Sprintf("Warnings: %s.", strings.Join(params.Warnings, "; "))))
+	}
+	contentItems = append(contentItems, inlineImages...)
+	return &mcp.CallToolResult{Content: contentItems}, nil
+}
@@ -0,0 +1,412 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Imagen models.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+// defaultImagenModel is used when the 'model' argument is not provided.
+const defaultImagenModel = "imagen-4.0-fast-generate-001"
+
+// safetyFilterLevels maps the accepted 'safety_filter_level' values to the API's thresholds,
+// from the most to the least restrictive.
+var safetyFilterLevels = map[string]genai.SafetyFilterLevel{
+	"block_most":   genai.SafetyFilterLevelBlockLowAndAbove,
+	"block_some":   genai.SafetyFilterLevelBlockMediumAndAbove,
+	"block_few":    genai.SafetyFilterLevelBlockOnlyHigh,
+	"block_fewest": genai.SafetyFilterLevelBlockNone,
+}
+
+// supportedSafetyFilterLevels lists the keys of safetyFilterLevels in order, for error messages.
+var supportedSafetyFilterLevels = []string{"block_most", "block_some", "block_few", "block_fewest"}
+
+// ImageParams holds the validated parameters of an imagen_t2i request.
+type ImageParams struct {
+	Model          string
+	NumberOfImages int32
+	AspectRatio    string
+	// ImageSize is empty when the model's default size is used.
+	ImageSize    string
+	GCSOutputURI string
+	OutputDir    string
+	// NegativePrompt and Seed are left unset in the config when empty or nil.
+	NegativePrompt string
+	Seed           *int32
+	// SafetyFilterLevel is empty when the API default is used.
+	SafetyFilterLevel genai.SafetyFilterLevel
+	// AddWatermark is nil when the API default (a SynthID watermark) is used.
+	AddWatermark *bool
+	// Warnings are non-fatal notices, such as a model deprecation, to include in the result.
+	Warnings []string
+}
+
+// GenerateImagesConfig builds the genai.GenerateImagesConfig for these parameters.
+func (p *ImageParams) GenerateImagesConfig() *genai.GenerateImagesConfig {
+	config := &genai.GenerateImagesConfig{
+		NumberOfImages: p.NumberOfImages,
+		AspectRatio:    p.AspectRatio,
+		ImageSize:      p.ImageSize,
+		OutputGCSURI:   p.GCSOutputURI,
+	}
+	if p.NegativePrompt != "" {
+		config.NegativePrompt = p.NegativePrompt
+	}
+	if p.Seed != nil {
+		config.Seed = p.Seed
+	}
+	if p.SafetyFilterLevel != "" {
+		config.SafetyFilterLevel = p.SafetyFilterLevel
+	}
+	if p.AddWatermark != nil {
+		config.AddWatermark = *p.AddWatermark
+	}
+	return config
+}
+
+// WatermarkStatus describes whether the generated images carry a SynthID watermark.
+func (p *ImageParams) WatermarkStatus() string {
+	switch {
+	case p.AddWatermark == nil:
+		return "enabled (API default)"
+	case *p.AddWatermark:
+		return "enabled"
+	default:
+		return "disabled"
+	}
+}
+
+// resolveModelArg resolves the 'model' argument (defaulting to defaultImagenModel)
+// to its canonical name and model details.
+func resolveModelArg(args map[string]interface{}) (string, common.ImagenModelInfo, error) {
+	modelInput, ok := args["model"].(string)
+	if !ok || modelInput == "" {
+		log.Printf("Model not provided or empty, using default: %s", defaultImagenModel)
+		modelInput = defaultImagenModel
+	}
+	canonicalName, found := common.ResolveImagenModel(modelInput)
+	if !found {
+		return "", common.ImagenModelInfo{}, fmt.Errorf("model '%s' is not a valid or supported model name", modelInput)
+	}
+	if !strings.EqualFold(modelInput, canonicalName) {
+		log.Printf("Handler: interpreted model '%s' as '%s'", modelInput, canonicalName)
+	}
+	return canonicalName, common.SupportedImagenModels[canonicalName], nil
+}
+
+// resolveNumberOfImages reads the 'num_images' argument, defaulting to 1, and checks it against
+// the model's MaxImages. Requests above the cap are clamped to it if clamp is true, or
+// rejected otherwise.
+func resolveNumberOfImages(args map[string]interface{}, model string, modelDetails common.ImagenModelInfo, clamp bool) (int32, error) {
+	var numberOfImages int32 = 1
+	if numImagesArg, ok := args["num_images"]; ok {
+		numImagesFloat, ok := numImagesArg.(float64)
+		if !ok {
+			return 0, fmt.Errorf("num_images must be a number, got %T", numImagesArg)
+		}
+		if numImagesFloat != math.Trunc(numImagesFloat) || numImagesFloat < 1 {
+			return 0, fmt.Errorf("num_images must be a positive whole number, got %v", numImagesFloat)
+		}
+		numberOfImages = int32(min(numImagesFloat, math.MaxInt32))
+	}
+	if numberOfImages > modelDetails.MaxImages {
+		if !clamp {
+			return 0, fmt.Errorf("num_images %d exceeds the maximum of %d images per request for model %s", numberOfImages, modelDetails.MaxImages, model)
+		}
+		log.Printf("Warning: Requested %d images, but model %s only supports up to %d. Adjusting to max.", numberOfImages, model, modelDetails.MaxImages)
+		numberOfImages = modelDetails.MaxImages
+	}
+	return numberOfImages, nil
+}
+
+// resolveAspectRatio reads the 'aspect_ratio' argument, defaulting to 1:1, and validates it
+// against the model's supported aspect ratios.
+func resolveAspectRatio(args map[string]interface{}, model string, modelDetails common.ImagenModelInfo) (string, error) {
+	aspectRatio, _ := args["aspect_ratio"].(string)
+	aspectRatio = strings.TrimSpace(aspectRatio)
+	if aspectRatio == "" {
+		aspectRatio = "1:1"
+	}
+	if !slices.Contains(modelDetails.SupportedAspectRatios, aspectRatio) {
+		return "", fmt.Errorf("aspect ratio '%s' is not supported by model %s. Supported aspect ratios are: [%s]", aspectRatio, model, strings.Join(modelDetails.SupportedAspectRatios, ", "))
+	}
+	return aspectRatio, nil
+}
+
+// resolveImageSize reads the optional 'image_size' argument and validates it against the
+// model's supported image sizes. It returns an empty string when no size is requested.
+func resolveImageSize(args map[string]interface{}, model string) (string, error) {
+	imageSize, _ := args["image_size"].(string)
+	imageSize = strings.TrimSpace(imageSize)
+	if err := common.ValidateImagenImageSize(model, imageSize); err != nil {
+		return "", err
+	}
+	return imageSize, nil
+}
+
+// resolveSeed reads the optional 'seed' argument. The genai SDK transmits the seed as an
+// int32, so values are limited to the range it can represent without overflow.
+func resolveSeed(args map[string]interface{}) (*int32, error) {
+	seedArg, ok := args["seed"].(float64)
+	if !ok {
+		return nil, nil
+	}
+	if seedArg != math.Trunc(seedArg) {
+		return nil, fmt.Errorf("seed must be a whole number, got %v", seedArg)
+	}
+	if seedArg < 0 || seedArg > math.MaxInt32 {
+		return nil, fmt.Errorf("seed %v is out of range. It must be between 0 and %d", seedArg, math.MaxInt32)
+	}
+	seed := int32(seedArg)
+	return &seed, nil
+}
+
+// resolveSafetyFilterLevel reads the optional 'safety_filter_level' argument. It returns an
+// empty level when the argument is not provided, so the API default is used.
+func resolveSafetyFilterLevel(args map[string]interface{}) (genai.SafetyFilterLevel, error) {
+	levelArg, _ := args["safety_filter_level"].(string)
+	levelArg = strings.ToLower(strings.TrimSpace(levelArg))
+	if levelArg == "" {
+		return "", nil
+	}
+	level, ok := safetyFilterLevels[levelArg]
+	if !ok {
+		return "", fmt.Errorf("safety_filter_level '%s' is not supported. Accepted values are: [%s]", levelArg, strings.Join(supportedSafetyFilterLevels, ", "))
+	}
+	return level, nil
+}
+
+// resolveGCSOutputURI returns the GCS URI prefix for the generated images from the
+// 'gcs_bucket_uri' argument or, if it is not provided, from GENMEDIA_BUCKET. It returns an
+// empty string if neither is set.
+func resolveGCSOutputURI(args map[string]interface{}, appConfig *common.Config) string {
+	gcsOutputURI := ""
+	gcsBucketUriParam, _ := args["gcs_bucket_uri"].(string)
+	gcsBucketUriParam = strings.TrimSpace(gcsBucketUriParam)
+
+	if gcsBucketUriParam != "" {
+		gcsOutputURI = common.EnsureGCSPathPrefix(gcsBucketUriParam)
+	} else if appConfig.GenmediaBucket != "" {
+		gcsOutputURI = fmt.Sprintf("gs://%s/imagen_outputs/", appConfig.GenmediaBucket)
+		log.Printf("Handler imagen_t2i: 'gcs_bucket_uri' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsOutputURI)
+	} else {
+		log.Printf("Handler imagen_t2i: 'gcs_bucket_uri' parameter and GENMEDIA_BUCKET env var are both empty. No GCS output will be saved.")
+	}
+
+	if gcsOutputURI != "" && !strings.HasSuffix(gcsOutputURI, "/") {
+		gcsOutputURI += "/"
+	}
+	return gcsOutputURI
+}
+
+// parseImagenParams extracts and validates image generation parameters from the request arguments.
+func parseImagenParams(args map[string]interface{}, appConfig *common.Config) (*ImageParams, error) {
+	// Model
+	model, modelDetails, err := resolveModelArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if modelDetails.EditOnly {
+		return nil, fmt.Errorf("model %s can only edit images and cannot generate them from text. Use the imagen_edit tool instead", model)
+	}
+	var warnings []string
+	if warning := modelDetails.DeprecationWarning(); warning != "" {
+		log.Printf("Warning: %s", warning)
+		warnings = append(warnings, warning)
+	}
+
+	// Prompt Length
+	prompt, _ := args["prompt"].(string)
+	if err := common.CheckPromptLength(model, strings.TrimSpace(prompt), modelDetails.MaxPromptChars); err != nil {
+		return nil, err
+	}
+
+	// Number of Images
+	numberOfImages, err := resolveNumberOfImages(args, model, modelDetails, appConfig.ClampNumImages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aspect Ratio
+	aspectRatio, err := resolveAspectRatio(args, model, modelDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	// Image Size
+	imageSize, err := resolveImageSize(args, model)
+	if err != nil {
+		return nil, err
+	}
+
+	// Negative Prompt
+	negativePrompt, _ := args["negative_prompt"].(string)
+
+	// Seed
+	seed, err := resolveSeed(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Safety Filter Level
+	safetyFilterLevel, err := resolveSafetyFilterLevel(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Watermark
+	var addWatermark *bool
+	if watermarkArg, ok := args["add_watermark"].(bool); ok {
+		addWatermark = &watermarkArg
+	}
+
+	// Output Directory
+	outputDir, _ := args["output_directory"].(string)
+
+	return &ImageParams{
+		Model:             model,
+		NumberOfImages:    numberOfImages,
+		AspectRatio:       aspectRatio,
+		ImageSize:         imageSize,
+		GCSOutputURI:      resolveGCSOutputURI(args, appConfig),
+		OutputDir:         strings.TrimSpace(outputDir),
+		NegativePrompt:    strings.TrimSpace(negativePrompt),
+		Seed:              seed,
+		SafetyFilterLevel: safetyFilterLevel,
+		AddWatermark:      addWatermark,
+		Warnings:          warnings,
+	}, nil
+}
+
+// editInputImageMimeTypes is the allowlist of MIME types for images passed to the Imagen
+// editing tools. Imagen accepts more input formats than Veo, including TIFF and BMP.
+var editInputImageMimeTypes = []string{"image/jpeg", "image/png", "image/webp", "image/gif", "image/tiff", "image/bmp"}
+
+// resolveEditImageMimeType determines the MIME type of an input image for the editing tools,
+// from the URI's extension or else from the image content, and checks it against
+// editInputImageMimeTypes.
+func resolveEditImageMimeType(imageURI string, data []byte) (string, error) {
+	mimeType := common.InferMimeTypeFromPath(imageURI)
+	if mimeType == "" {
+		mimeType = common.DetectImageMimeType(data)
+	}
+	if !common.IsAllowedMimeType(mimeType, editInputImageMimeTypes) {
+		return "", fmt.Errorf("image '%s' has unsupported MIME type '%s'. Supported types are: [%s]", imageURI, mimeType, strings.Join(editInputImageMimeTypes, ", "))
+	}
+	return mimeType, nil
+}
+
+// editModes maps the 'edit_mode' values accepted by imagen_edit to genai edit modes.
+var editModes = map[string]genai.EditMode{
+	"inpaint_insertion": genai.EditModeInpaintInsertion,
+	"inpaint_removal":   genai.EditModeInpaintRemoval,
+	"outpaint":          genai.EditModeOutpaint,
+}
+
+// supportedEditModes lists the accepted values for the edit_mode parameter, in display order.
+var supportedEditModes = []string{"inpaint_insertion", "inpaint_removal", "outpaint"}
+
+// EditParams holds the parameters of an imagen_edit request.
+type EditParams struct {
+	Model          string
+	Prompt         string
+	ImageURI       string
+	MaskURI        string
+	EditMode       genai.EditMode
+	NumberOfImages int32
+	GCSOutputURI   string
+	// Warnings are non-fatal notices, such as a model deprecation, to include in the result.
+	Warnings []string
+}
+
+// parseEditParams extracts and validates the imagen_edit parameters. The model defaults to
+// imagenEditModel and must support editing. Outpainting requires a mask marking the area
+// to fill.
+func parseEditParams(args map[string]interface{}, appConfig *common.Config) (*EditParams, error) {
+	modelArgs := args
+	if modelInput, _ := args["model"].(string); strings.TrimSpace(modelInput) == "" {
+		modelArgs = map[string]interface{}{"model": imagenEditModel}
+	}
+	model, modelDetails, err := resolveModelArg(modelArgs)
+	if err != nil {
+		return nil, err
+	}
+	if err := common.CheckImagenCapability(model, common.ImagenCapabilityEdit); err != nil {
+		return nil, err
+	}
+	var warnings []string
+	if warning := modelDetails.DeprecationWarning(); warning != "" {
+		log.Printf("Warning: %s", warning)
+		warnings = append(warnings, warning)
+	}
+
+	imageURI, _ := args["image_uri"].(string)
+	imageURI = strings.TrimSpace(imageURI)
+	if !strings.HasPrefix(imageURI, "gs://") {
+		return nil, fmt.Errorf("image_uri must be a GCS URI starting with 'gs://', got '%s'", imageURI)
+	}
+	maskURI, _ := args["mask_uri"].(string)
+	maskURI = strings.TrimSpace(maskURI)
+	if maskURI != "" && !strings.HasPrefix(maskURI, "gs://") {
+		return nil, fmt.Errorf("mask_uri must be a GCS URI starting with 'gs://', got '%s'", maskURI)
+	}
+
+	editModeArg, _ := args["edit_mode"].(string)
+	editModeArg = strings.ToLower(strings.TrimSpace(editModeArg))
+	if editModeArg == "" {
+		editModeArg = "inpaint_insertion"
+	}
+	editMode, ok := editModes[editModeArg]
+	if !ok {
+		return nil, fmt.Errorf("edit_mode '%s' is not supported. Accepted values are: [%s]", editModeArg, strings.Join(supportedEditModes, ", "))
+	}
+	if editMode == genai.EditModeOutpaint && maskURI == "" {
+		return nil, fmt.Errorf("edit_mode 'outpaint' requires a mask_uri marking the area to fill")
+	}
+
+	prompt, _ := args["prompt"].(string)
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" && editMode != genai.EditModeInpaintRemoval {
+		return nil, fmt.Errorf("prompt is required for edit_mode '%s'", editModeArg)
+	}
+	if err := common.CheckPromptLength(model, prompt, modelDetails.MaxPromptChars); err != nil {
+		return nil, err
+	}
+
+	numberOfImages, err := resolveNumberOfImages(args, model, modelDetails, appConfig.ClampNumImages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EditParams{
+		Model:          model,
+		Prompt:         prompt,
+		ImageURI:       imageURI,
+		MaskURI:        maskURI,
+		EditMode:       editMode,
+		NumberOfImages: numberOfImages,
+		GCSOutputURI:   resolveGCSOutputURI(args, appConfig),
+		Warnings:       warnings,
+	}, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestParseImagenParams(t *testing.T) {
+	testCases := []struct {
+		name              string
+		args              map[string]interface{}
+		expectedModel     string
+		expectedNumImages int32
+		expectedRatio     string
+		expectedImageSize string
+		expectedError     bool
+	}{
+		{"defaults", map[string]interface{}{}, "imagen-4.0-fast-generate-001", 1, "1:1", "", false},
+		{"alias and size", map[string]interface{}{"model": "Imagen 4", "image_size": "2K", "aspect_ratio": "16:9"}, "imagen-4.0-generate-001", 1, "16:9", "2K", false},
+		{"num images capped", map[string]interface{}{"model": "imagen-4.0-ultra-generate-001", "num_images": 4.0}, "imagen-4.0-ultra-generate-001", 1, "1:1", "", false},
+		{"unknown model", map[string]interface{}{"model": "not-a-model"}, "", 0, "", "", true},
+		{"unsupported aspect ratio", map[string]interface{}{"aspect_ratio": "21:9"}, "", 0, "", "", true},
+		{"image size on Imagen 3", map[string]interface{}{"model": "Imagen 3", "image_size": "1K"}, "", 0, "", "", true},
+		{"unsupported image size", map[string]interface{}{"image_size": "4K"}, "", 0, "", "", true},
+		{"fractional num images", map[string]interface{}{"num_images": 1.5}, "", 0, "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseImagenParams(tc.args, &common.Config{ClampNumImages: true})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if params.Model != tc.expectedModel {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedModel, params.Model)
+			}
+			if params.NumberOfImages != tc.expectedNumImages {
+				t.Errorf("expected %d, but got %d", tc.expectedNumImages, params.NumberOfImages)
+			}
+			if params.AspectRatio != tc.expectedRatio {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedRatio, params.AspectRatio)
+			}
+			if params.ImageSize != tc.expectedImageSize {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedImageSize, params.ImageSize)
+			}
+		})
+	}
+}
+
+func TestResolveNumberOfImages(t *testing.T) {
+	ultra := common.SupportedImagenModels["imagen-4.0-ultra-generate-001"]
+	testCases := []struct {
+		name          string
+		numImages     float64
+		clamp         bool
+		expected      int32
+		expectedError bool
+	}{
+		{"within limit", 1, false, 1, false},
+		{"clamped to limit", 4, true, 1, false},
+		{"rejected above limit", 4, false, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"num_images": tc.numImages}
+			actual, err := resolveNumberOfImages(args, "imagen-4.0-ultra-generate-001", ultra, tc.clamp)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "maximum of 1 images per request for model imagen-4.0-ultra-generate-001") {
+				t.Errorf("expected error naming the per-model limit, but got '%v'", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestImageParamsGenerateImagesConfig(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		args                   map[string]interface{}
+		expectedNegativePrompt string
+		expectedSeed           *int32
+		expectedError          bool
+	}{
+		{"unset", map[string]interface{}{}, "", nil, false},
+		{"negative prompt and seed", map[string]interface{}{"negative_prompt": " blurry ", "seed": 42.0}, "blurry", genai.Ptr[int32](42), false},
+		{"fractional seed", map[string]interface{}{"seed": 1.5}, "", nil, true},
+		{"negative seed", map[string]interface{}{"seed": -1.0}, "", nil, true},
+		{"seed out of range", map[string]interface{}{"seed": 4294967295.0}, "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseImagenParams(tc.args, &common.Config{ClampNumImages: true})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			config := params.GenerateImagesConfig()
+			if config.NegativePrompt != tc.expectedNegativePrompt {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedNegativePrompt, config.NegativePrompt)
+			}
+			if (config.Seed == nil) != (tc.expectedSeed == nil) || (config.Seed != nil && *config.Seed != *tc.expectedSeed) {
+				t.Errorf("expected seed %v, but got %v", tc.expectedSeed, config.Seed)
+			}
+		})
+	}
+}
+
+func TestResolveSafetyFilterLevel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          map[string]interface{}
+		expected      genai.SafetyFilterLevel
+		expectedError bool
+	}{
+		{"not provided", map[string]interface{}{}, "", false},
+		{"block_most", map[string]interface{}{"safety_filter_level": "block_most"}, genai.SafetyFilterLevelBlockLowAndAbove, false},
+		{"case-insensitive", map[string]interface{}{"safety_filter_level": "Block_Few"}, genai.SafetyFilterLevelBlockOnlyHigh, false},
+		{"unknown", map[string]interface{}{"safety_filter_level": "block_all"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveSafetyFilterLevel(tc.args)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseImagenParamsDeprecationWarning(t *testing.T) {
+	params, err := parseImagenParams(map[string]interface{}{"model": "imagen-3.0-generate-001"}, &common.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if len(params.Warnings) != 1 || !strings.Contains(params.Warnings[0], "migrate to imagen-3.0-generate-002") {
+		t.Errorf("expected a deprecation warning naming the replacement, but got %v", params.Warnings)
+	}
+
+	params, err = parseImagenParams(map[string]interface{}{}, &common.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if len(params.Warnings) != 0 {
+		t.Errorf("expected no warnings for the default model, but got %v", params.Warnings)
+	}
+}
+
+func TestImageParamsWatermark(t *testing.T) {
+	testCases := []struct {
+		name              string
+		args              map[string]interface{}
+		expectedWatermark bool
+		expectedStatus    string
+	}{
+		{"unset", map[string]interface{}{}, false, "enabled (API default)"},
+		{"enabled", map[string]interface{}{"add_watermark": true}, true, "enabled"},
+		{"disabled", map[string]interface{}{"add_watermark": false}, false, "disabled"},
+		{"invalid type", map[string]interface{}{"add_watermark": "false"}, false, "enabled (API default)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseImagenParams(tc.args, &common.Config{ClampNumImages: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := params.GenerateImagesConfig().AddWatermark; got != tc.expectedWatermark {
+				t.Errorf("expected %v, but got %v", tc.expectedWatermark, got)
+			}
+			if got := params.WatermarkStatus(); got != tc.expectedStatus {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedStatus, got)
+			}
+		})
+	}
+}
+
+func TestResolveEditImageMimeType(t *testing.T) {
+	testCases := []struct {
+		name          string
+		uri           string
+		data          []byte
+		expected      string
+		expectedError bool
+	}{
+		{"png by extension", "gs://bucket/image.png", nil, "image/png", false},
+		{"tiff by extension", "gs://bucket/scan.tiff", nil, "image/tiff", false},
+		{"bmp by extension", "gs://bucket/image.bmp", nil, "image/bmp", false},
+		{"tiff by content", "gs://bucket/scan", []byte("II*\x000000"), "image/tiff", false},
+		{"bmp by content", "gs://bucket/image", []byte("BM0000"), "image/bmp", false},
+		{"video rejected", "gs://bucket/clip.mp4", nil, "", true},
+		{"unknown content rejected", "gs://bucket/blob", []byte("hello"), "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveEditImageMimeType(tc.uri, tc.data)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseEditParams(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             map[string]interface{}
+		expectedModel    string
+		expectedEditMode genai.EditMode
+		expectedError    string
+	}{
+		{"defaults", map[string]interface{}{"image_uri": "gs://b/i.png", "prompt": "add a hat"}, "imagen-3.0-capability-001", genai.EditModeInpaintInsertion, ""},
+		{"alias", map[string]interface{}{"image_uri": "gs://b/i.png", "prompt": "add a hat", "model": "Imagen 3 Edit"}, "imagen-3.0-capability-001", genai.EditModeInpaintInsertion, ""},
+		{"removal without prompt", map[string]interface{}{"image_uri": "gs://b/i.png", "mask_uri": "gs://b/m.png", "edit_mode": "inpaint_removal"}, "imagen-3.0-capability-001", genai.EditModeInpaintRemoval, ""},
+		{"outpaint with mask", map[string]interface{}{"image_uri": "gs://b/i.png", "mask_uri": "gs://b/m.png", "edit_mode": "outpaint", "prompt": "a beach"}, "imagen-3.0-capability-001", genai.EditModeOutpaint, ""},
+		{"outpaint without mask", map[string]interface{}{"image_uri": "gs://b/i.png", "edit_mode": "outpaint", "prompt": "a beach"}, "", "", "requires a mask_uri"},
+		{"insertion without prompt", map[string]interface{}{"image_uri": "gs://b/i.png"}, "", "", "prompt is required"},
+		{"unknown edit mode", map[string]interface{}{"image_uri": "gs://b/i.png", "prompt": "x", "edit_mode": "bgswap"}, "", "", "edit_mode 'bgswap' is not supported"},
+		{"model without edit support", map[string]interface{}{"image_uri": "gs://b/i.png", "prompt": "x", "model": "Imagen 4"}, "", "", "does not support the edit operation"},
+		{"local image", map[string]interface{}{"image_uri": "/tmp/i.png", "prompt": "x"}, "", "", "image_uri must be a GCS URI"},
+		{"prompt too long", map[string]interface{}{"image_uri": "gs://b/i.png", "prompt": strings.Repeat("a", 1921)}, "", "", "prompt is 1921 characters long, which exceeds the limit of 1920 characters"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseEditParams(tc.args, &common.Config{ClampNumImages: true})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if params.Model != tc.expectedModel {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedModel, params.Model)
+			}
+			if params.EditMode != tc.expectedEditMode {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedEditMode, params.EditMode)
+			}
+		})
+	}
+}
+
+func TestParseImagenParamsRejectsEditOnlyModel(t *testing.T) {
+	_, err := parseImagenParams(map[string]interface{}{"model": "imagen-3.0-capability-001"}, &common.Config{ClampNumImages: true})
+	if err == nil || !strings.Contains(err.Error(), "imagen_edit") {
+		t.Errorf("expected an error pointing to imagen_edit, but got: %v", err)
+	}
+}
+
+func TestParseImagenParamsPromptLength(t *testing.T) {
+	maxChars := common.SupportedImagenModels["imagen-4.0-generate-001"].MaxPromptChars
+	testCases := []struct {
+		name          string
+		prompt        string
+		expectedError bool
+	}{
+		{"at limit", strings.Repeat("a", maxChars), false},
+		{"over limit", strings.Repeat("a", maxChars+1), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"model": "imagen-4.0-generate-001", "prompt": tc.prompt}
+			_, err := parseImagenParams(args, &common.Config{ClampNumImages: true})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "exceeds the limit of") {
+				t.Errorf("expected a prompt length error, but got: %v", err)
+			}
+		})
+	}
+}
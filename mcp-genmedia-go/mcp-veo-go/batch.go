@@ -0,0 +1,338 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// maxBatchPrompts is the largest number of prompts veo_batch_t2v accepts in one call.
+const maxBatchPrompts = 50
+
+// defaultBatchConcurrency is the number of prompts veo_batch_t2v generates at once when
+// max_concurrency is not provided. The server-wide GENMEDIA_MAX_CONCURRENCY still applies.
+const defaultBatchConcurrency = 2
+
+// batchPrompt is one entry of the 'prompts' argument of veo_batch_t2v. The optional fields
+// override the batch-wide arguments of the same name for this prompt only.
+type batchPrompt struct {
+	Prompt      string   `json:"prompt"`
+	Model       string   `json:"model,omitempty"`
+	Duration    *float64 `json:"duration,omitempty"`
+	AspectRatio string   `json:"aspect_ratio,omitempty"`
+}
+
+// batchPromptResult is the outcome for one prompt of a veo_batch_t2v call. Index is the
+// prompt's zero-based position in the 'prompts' argument.
+type batchPromptResult struct {
+	Index         int      `json:"index"`
+	Prompt        string   `json:"prompt"`
+	Model         string   `json:"model,omitempty"`
+	Status        string   `json:"status"`
+	OperationName string   `json:"operation_name,omitempty"`
+	GCSURIs       []string `json:"gcs_uris,omitempty"`
+	LocalPaths    []string `json:"local_paths,omitempty"`
+	// Filtered lists the prompt's videos removed by the safety filters, so they can be
+	// retried with an adjusted prompt.
+	Filtered []filteredVideo `json:"filtered,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// batchResult is the machine-readable result of veo_batch_t2v. Status is "completed" if every
+// prompt succeeded, "partial" if some failed, or "failed".
+type batchResult struct {
+	Status    string              `json:"status"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []batchPromptResult `json:"results"`
+}
+
+// parseBatchPrompts reads the 'prompts' argument: an array (or a JSON string encoding one)
+// whose entries are either prompt strings or objects with a 'prompt' and optional 'model',
+// 'duration', and 'aspect_ratio' overrides.
+func parseBatchPrompts(args map[string]interface{}) ([]batchPrompt, error) {
+	var promptsJSON []byte
+	switch v := args["prompts"].(type) {
+	case string:
+		promptsJSON = []byte(v)
+	case []interface{}:
+		// Re-encode the decoded array so both forms share the same validation below.
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read 'prompts': %v", err)
+		}
+		promptsJSON = encoded
+	case nil:
+		return nil, fmt.Errorf("prompts is required and must be a non-empty array")
+	default:
+		return nil, fmt.Errorf("'prompts' must be an array or a JSON string, got %T", v)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(promptsJSON, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse 'prompts': %v. Please provide an array of prompt strings or objects with a 'prompt'", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("prompts is required and must be a non-empty array")
+	}
+	if len(entries) > maxBatchPrompts {
+		return nil, fmt.Errorf("prompts contains %d entries, which exceeds the maximum of %d per batch", len(entries), maxBatchPrompts)
+	}
+
+	prompts := make([]batchPrompt, len(entries))
+	for i, entry := range entries {
+		if err := json.Unmarshal(entry, &prompts[i].Prompt); err != nil {
+			if err := json.Unmarshal(entry, &prompts[i]); err != nil {
+				return nil, fmt.Errorf("prompts[%d] must be a string or an object with a 'prompt': %v", i, err)
+			}
+		}
+		prompts[i].Prompt = strings.TrimSpace(prompts[i].Prompt)
+		if prompts[i].Prompt == "" {
+			return nil, fmt.Errorf("prompts[%d] must have a non-empty prompt", i)
+		}
+	}
+	return prompts, nil
+}
+
+// resolveBatchConcurrency reads the 'max_concurrency' argument, defaulting to
+// defaultBatchConcurrency.
+func resolveBatchConcurrency(args map[string]interface{}) (int, error) {
+	concurrencyArg, ok := args["max_concurrency"].(float64)
+	if !ok {
+		return defaultBatchConcurrency, nil
+	}
+	if concurrencyArg != math.Trunc(concurrencyArg) || concurrencyArg < 1 {
+		return 0, fmt.Errorf("max_concurrency must be a positive whole number, got %v", concurrencyArg)
+	}
+	return int(concurrencyArg), nil
+}
+
+// batchPromptArgs returns the arguments for generating a single prompt of a batch: the
+// batch-wide arguments with the prompt's overrides applied. Audio defaults to what the
+// prompt's model supports, since prompts in one batch may use different models.
+func batchPromptArgs(ctx context.Context, args map[string]interface{}, entry batchPrompt) map[string]interface{} {
+	promptArgs := maps.Clone(args)
+	delete(promptArgs, "prompts")
+	delete(promptArgs, "max_concurrency")
+	promptArgs["prompt"] = entry.Prompt
+	if entry.Model != "" {
+		promptArgs["model"] = entry.Model
+	}
+	if entry.Duration != nil {
+		promptArgs["duration"] = *entry.Duration
+	}
+	if entry.AspectRatio != "" {
+		promptArgs["aspect_ratio"] = entry.AspectRatio
+	}
+	if _, ok := promptArgs["generate_audio"].(bool); !ok {
+		if _, modelDetails, err := resolveModelArg(ctx, promptArgs); err == nil {
+			promptArgs["generate_audio"] = modelDetails.SupportsGenerateAudio
+		}
+	}
+	return promptArgs
+}
+
+// batchPromptOutputDir returns the local directory for the videos of the prompt at index, a
+// numbered subdirectory of outputDir so that the prompts' files do not overwrite each other.
+func batchPromptOutputDir(outputDir string, index int) string {
+	if outputDir == "" {
+		return ""
+	}
+	return filepath.Join(outputDir, fmt.Sprintf("prompt_%d", index))
+}
+
+// veoBatchTextToVideoHandler is the handler for the 'veo_batch_t2v' tool. Each prompt is
+// generated like a veo_t2v request, with at most max_concurrency prompts in flight at once.
+// A failed prompt is reported alongside the others rather than aborting the batch; the
+// result is only an error if every prompt failed.
+func veoBatchTextToVideoHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_batch_t2v")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_batch_t2v")
+
+	args := request.GetArguments()
+	if err := checkToolParams("veo_batch_t2v", args); err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	client, err := resolveGenAIClient(ctx, client, args)
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+
+	prompts, err := parseBatchPrompts(args)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	// Every prompt would be saved to the same object.
+	if outputURI, _ := args["output_uri"].(string); strings.TrimSpace(outputURI) != "" {
+		return invalidArgumentResult("output_uri is not supported by veo_batch_t2v because every prompt would be saved to the same object. Use bucket and output_filename_prefix instead"), nil
+	}
+	maxConcurrency, err := resolveBatchConcurrency(args)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	outputFormat, err := parseOutputFormat(args)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+
+	span.SetAttributes(
+		attribute.Int("num_prompts", len(prompts)),
+		attribute.Int("max_concurrency", maxConcurrency),
+	)
+	logger.Info("Handling Veo batch t2v request", "num_prompts", len(prompts), "max_concurrency", maxConcurrency)
+
+	mcpServer := server.ServerFromContext(ctx)
+	var progressToken mcp.ProgressToken
+	if request.Params.Meta != nil {
+		progressToken = request.Params.Meta.ProgressToken
+	}
+
+	slots := newGenerationLimiter(maxConcurrency)
+	results := make([]*mcp.CallToolResult, len(prompts))
+	models := make([]string, len(prompts))
+	var wg sync.WaitGroup
+	for i, entry := range prompts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			promptArgs := batchPromptArgs(ctx, args, entry)
+			promptArgs["output_format"] = "json"
+			if outputDir, _ := promptArgs["output_directory"].(string); outputDir != "" {
+				promptArgs["output_directory"] = batchPromptOutputDir(outputDir, i)
+			}
+
+			promptCtx, _ := withLogAttrs(ctx, "prompt_index", i)
+			release, err := slots.Acquire(promptCtx, nil)
+			if err != nil {
+				results[i] = codedToolResultError(errorCode(err), fmt.Sprintf("prompt was not started: %v", err))
+				return
+			}
+			defer release()
+			results[i], models[i] = generateBatchPrompt(client, promptCtx, mcpServer, progressToken, promptArgs, fmt.Sprintf("batch t2v %d", i))
+		}()
+	}
+	wg.Wait()
+
+	return batchToolResult(ctx, prompts, models, results, outputFormat)
+}
+
+// generateBatchPrompt generates the videos for one prompt of a batch, returning the result
+// and the model used (empty if the arguments were rejected).
+func generateBatchPrompt(client *genai.Client, ctx context.Context, mcpServer *server.MCPServer, progressToken mcp.ProgressToken, args map[string]interface{}, callType string) (*mcp.CallToolResult, string) {
+	params, err := parseCommonVideoParams(ctx, args, appConfig)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), ""
+	}
+	ctx, _ = withLogAttrs(ctx, "model", params.Model)
+	if len(params.AspectRatios) > 1 {
+		return invalidArgumentResult("each prompt of a batch must use a single aspect_ratio"), params.Model
+	}
+	t2v, err := newTextToVideoRequest(ctx, params, args, false)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), params.Model
+	}
+	result, err := t2v.generate(client, ctx, mcpServer, progressToken, callType)
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), params.Model
+	}
+	return result, params.Model
+}
+
+// batchToolResult aggregates the per-prompt results of a batch into a single tool result.
+// In "json" mode the content is the JSON-encoded batchResult (also set as structured
+// content); otherwise it is a summary line per prompt. Inline videos are appended in both.
+func batchToolResult(ctx context.Context, prompts []batchPrompt, models []string, results []*mcp.CallToolResult, outputFormat string) (*mcp.CallToolResult, error) {
+	batch := batchResult{Results: make([]batchPromptResult, len(prompts))}
+	var summaries, inlineVideos []mcp.Content
+	for i, result := range results {
+		entry := batchPromptResult{Index: i, Prompt: prompts[i].Prompt, Model: models[i], Status: "completed"}
+		if result == nil {
+			result = codedToolResultError(codeInternal, "no result was returned")
+		}
+		if result.IsError {
+			entry.Status = "failed"
+			batch.Failed++
+		} else {
+			batch.Succeeded++
+		}
+		var texts []string
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			} else {
+				inlineVideos = append(inlineVideos, content)
+			}
+		}
+		if video, ok := result.StructuredContent.(videoResult); ok {
+			entry.OperationName = video.OperationName
+			entry.GCSURIs = video.GCSURIs
+			entry.LocalPaths = video.LocalPaths
+			entry.Filtered = video.Filtered
+			entry.Message = video.Message
+			// Warnings follow the JSON-encoded result as separate text content.
+			if len(texts) > 1 {
+				entry.Message += " " + strings.Join(texts[1:], " ")
+			}
+		} else {
+			entry.Message = strings.Join(texts, " ")
+		}
+		batch.Results[i] = entry
+		if outputFormat != "json" {
+			summaries = append(summaries, mcp.NewTextContent(fmt.Sprintf("Prompt %d (%s): %s", i, entry.Status, entry.Message)))
+		}
+	}
+
+	switch batch.Failed {
+	case 0:
+		batch.Status = "completed"
+	case len(prompts):
+		batch.Status = "failed"
+	default:
+		batch.Status = "partial"
+	}
+	loggerFromContext(ctx).Info("Batch t2v finished", "succeeded", batch.Succeeded, "failed", batch.Failed)
+
+	var toolResult *mcp.CallToolResult
+	if outputFormat == "json" {
+		batchJSON, err := json.MarshalIndent(batch, "", "  ")
+		if err != nil {
+			return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal batch result: %v", err)), nil
+		}
+		toolResult = mcp.NewToolResultStructured(batch, string(batchJSON))
+	} else {
+		toolResult = mcp.NewToolResultText(fmt.Sprintf("Generated videos for %d of %d prompt(s).", batch.Succeeded, len(prompts)))
+	}
+	toolResult.Content = append(toolResult.Content, summaries...)
+	toolResult.Content = append(toolResult.Content, inlineVideos...)
+	toolResult.IsError = batch.Failed == len(prompts)
+	return toolResult, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseBatchPrompts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		arg           interface{}
+		expected      []batchPrompt
+		expectedError string
+	}{
+		{"strings", []interface{}{"a fox", " a city "}, []batchPrompt{{Prompt: "a fox"}, {Prompt: "a city"}}, ""},
+		{"objects with overrides", []interface{}{
+			map[string]interface{}{"prompt": "a fox", "model": "Veo 3 Fast", "aspect_ratio": "9:16"},
+		}, []batchPrompt{{Prompt: "a fox", Model: "Veo 3 Fast", AspectRatio: "9:16"}}, ""},
+		{"JSON string", `["a fox", {"prompt": "a city", "duration": 6}]`, []batchPrompt{{Prompt: "a fox"}, {Prompt: "a city"}}, ""},
+		{"missing", nil, nil, "prompts is required"},
+		{"empty array", []interface{}{}, nil, "prompts is required"},
+		{"invalid JSON", `["a fox"`, nil, "failed to parse 'prompts'"},
+		{"empty prompt", []interface{}{"a fox", "  "}, nil, "prompts[1] must have a non-empty prompt"},
+		{"object without prompt", []interface{}{map[string]interface{}{"model": "Veo 2"}}, nil, "prompts[0] must have a non-empty prompt"},
+		{"wrong entry type", []interface{}{1.0}, nil, "prompts[0] must be a string or an object"},
+		{"too many", make([]interface{}, maxBatchPrompts+1), nil, "exceeds the maximum"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{}
+			if tc.arg != nil {
+				args["prompts"] = tc.arg
+			}
+			prompts, err := parseBatchPrompts(args)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(prompts) != len(tc.expected) {
+				t.Fatalf("expected %d prompts, but got %d", len(tc.expected), len(prompts))
+			}
+			for i, expected := range tc.expected {
+				actual := prompts[i]
+				if actual.Prompt != expected.Prompt || actual.Model != expected.Model || actual.AspectRatio != expected.AspectRatio {
+					t.Errorf("expected %+v, but got %+v", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveBatchConcurrency(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        map[string]interface{}
+		expected    int
+		expectError bool
+	}{
+		{"default", map[string]interface{}{}, defaultBatchConcurrency, false},
+		{"provided", map[string]interface{}{"max_concurrency": 5.0}, 5, false},
+		{"zero", map[string]interface{}{"max_concurrency": 0.0}, 0, true},
+		{"fractional", map[string]interface{}{"max_concurrency": 1.5}, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveBatchConcurrency(tc.args)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBatchPromptArgs(t *testing.T) {
+	duration := 6.0
+	args := map[string]interface{}{
+		"prompts":         []interface{}{"a fox"},
+		"max_concurrency": 2.0,
+		"model":           "veo-3.1-generate-preview",
+		"aspect_ratio":    "16:9",
+		"bucket":          "gs://bucket/out",
+	}
+
+	promptArgs := batchPromptArgs(t.Context(), args, batchPrompt{Prompt: "a fox", Model: "veo-2.0-generate-001", Duration: &duration, AspectRatio: "9:16"})
+	if _, ok := promptArgs["prompts"]; ok {
+		t.Errorf("expected 'prompts' to be removed, but got %v", promptArgs["prompts"])
+	}
+	if _, ok := promptArgs["max_concurrency"]; ok {
+		t.Errorf("expected 'max_concurrency' to be removed, but got %v", promptArgs["max_concurrency"])
+	}
+	if promptArgs["prompt"] != "a fox" || promptArgs["model"] != "veo-2.0-generate-001" || promptArgs["duration"] != 6.0 || promptArgs["aspect_ratio"] != "9:16" {
+		t.Errorf("expected the prompt's overrides to be applied, but got %v", promptArgs)
+	}
+	if promptArgs["bucket"] != "gs://bucket/out" {
+		t.Errorf("expected batch-wide arguments to be kept, but got %v", promptArgs)
+	}
+	if promptArgs["generate_audio"] != false {
+		t.Errorf("expected audio to default to the model's support, but got %v", promptArgs["generate_audio"])
+	}
+	if args["model"] != "veo-3.1-generate-preview" {
+		t.Errorf("expected the batch arguments to be left unchanged, but got %v", args)
+	}
+}
+
+func TestBatchToolResult(t *testing.T) {
+	prompts := []batchPrompt{{Prompt: "a fox"}, {Prompt: "a city"}}
+	models := []string{"veo-2.0-generate-001", "veo-2.0-generate-001"}
+	succeeded := mcp.NewToolResultStructured(videoResult{Status: "completed", OperationName: "operations/1", GCSURIs: []string{"gs://bucket/out/1/sample_0.mp4"}, Message: "generated"}, "{}")
+	failed := invalidArgumentResult("duration is not supported")
+
+	testCases := []struct {
+		name           string
+		results        []*mcp.CallToolResult
+		expectedStatus string
+		expectedError  bool
+	}{
+		{"all succeeded", []*mcp.CallToolResult{succeeded, succeeded}, "completed", false},
+		{"partial failure", []*mcp.CallToolResult{succeeded, failed}, "partial", false},
+		{"all failed", []*mcp.CallToolResult{failed, failed}, "failed", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := batchToolResult(context.Background(), prompts, models, tc.results, "json")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsError != tc.expectedError {
+				t.Errorf("expected IsError to be %v, but got %v", tc.expectedError, result.IsError)
+			}
+			batch, ok := result.StructuredContent.(batchResult)
+			if !ok {
+				t.Fatalf("expected structured content of type batchResult, but got %T", result.StructuredContent)
+			}
+			if batch.Status != tc.expectedStatus {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedStatus, batch.Status)
+			}
+			if len(batch.Results) != 2 || batch.Results[0].Prompt != "a fox" || batch.Results[1].Index != 1 {
+				t.Fatalf("expected results in request order, but got %+v", batch.Results)
+			}
+			if first := batch.Results[0]; first.Status == "completed" && (len(first.GCSURIs) != 1 || first.OperationName != "operations/1") {
+				t.Errorf("expected the output URIs of a completed prompt, but got %+v", first)
+			}
+			if last := batch.Results[1]; last.Status == "failed" && !strings.Contains(last.Message, "[code=INVALID_ARGUMENT]") {
+				t.Errorf("expected the error message of a failed prompt, but got '%s'", last.Message)
+			}
+		})
+	}
+}
+
+func TestGenerateBatchPromptStyle(t *testing.T) {
+	appConfig = &common.Config{PromptStyles: map[string]string{"noir": "Film noir style."}}
+	defer func() { appConfig = nil }()
+
+	args := map[string]interface{}{"prompt": "a fox", "style": "noir", "model": "veo-2.0-generate-001", "generate_audio": false, "dry_run": true, "output_format": "json"}
+	result, model := generateBatchPrompt(nil, t.Context(), nil, nil, args, "batch t2v 0")
+	if model != "veo-2.0-generate-001" {
+		t.Errorf("expected model 'veo-2.0-generate-001', but got '%s'", model)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %v", result.Content)
+	}
+	var resolved dryRunConfig
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &resolved); err != nil {
+		t.Fatalf("failed to parse the dry run result: %v", err)
+	}
+	if expected := "a fox. Film noir style."; resolved.Prompt != expected {
+		t.Errorf("expected the styled prompt '%s', but got '%s'", expected, resolved.Prompt)
+	}
+	if len(result.Content) < 2 || !strings.Contains(result.Content[1].(mcp.TextContent).Text, "noir") {
+		t.Errorf("expected a note about the applied style, but got %v", result.Content)
+	}
+}
+
+func TestGenerateBatchPromptMatchesTextToVideo(t *testing.T) {
+	appConfig = &common.Config{PromptStyles: map[string]string{"noir": "Film noir style."}}
+	defer func() { appConfig = nil }()
+
+	args := map[string]interface{}{"prompt": " a fox ", "negative_prompt": " rain ", "enhance_prompt": false, "style": "noir", "model": "veo-2.0-generate-001", "generate_audio": false, "dry_run": true, "output_format": "json"}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = maps.Clone(args)
+	single, err := veoTextToVideoHandler(nil, t.Context(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	batch, _ := generateBatchPrompt(nil, t.Context(), nil, nil, maps.Clone(args), "t2v")
+	if single.IsError || batch.IsError {
+		t.Fatalf("unexpected error results: %v and %v", single.Content, batch.Content)
+	}
+	if len(single.Content) != len(batch.Content) {
+		t.Fatalf("expected the same notes and warnings, but got %v and %v", single.Content, batch.Content)
+	}
+	for i := range single.Content {
+		if expected, actual := single.Content[i].(mcp.TextContent).Text, batch.Content[i].(mcp.TextContent).Text; actual != expected {
+			t.Errorf("expected content %d of the batch prompt to match veo_t2v, '%s', but got '%s'", i, expected, actual)
+		}
+	}
+}
+
+func TestVeoBatchTextToVideoHandlerOutputFormat(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"prompts": []interface{}{"a fox"}, "output_format": "xml"}
+	result, err := veoBatchTextToVideoHandler(nil, t.Context(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.HasPrefix(text, "[code=INVALID_ARGUMENT]") || !strings.Contains(text, "output_format 'xml' is not supported") {
+		t.Errorf("expected an INVALID_ARGUMENT result for the output format, but got '%s'", text)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+// resultCacheBackends lists the accepted values of GENMEDIA_RESULT_CACHE. An empty value
+// disables caching.
+var resultCacheBackends = []string{"none", "memory"}
+
+// cachedResult records the outputs of a completed generation that can be returned for an
+// identical request.
+type cachedResult struct {
+	OperationName string
+	Model         string
+	GCSURIs       []string
+	CreatedAt     time.Time
+}
+
+// resultCache is a concurrency-safe, in-memory cache of generation results keyed by
+// resultCacheKey. Entries older than the TTL are treated as missing, dropped on lookup, and
+// swept from the cache whenever a result is added.
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedResult
+}
+
+// newResultCache returns the cache selected by backend, or nil if caching is disabled.
+// Unknown backends are an error.
+func newResultCache(backend string, ttl time.Duration) (*resultCache, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		return &resultCache{ttl: ttl, entries: make(map[string]cachedResult)}, nil
+	default:
+		return nil, fmt.Errorf("GENMEDIA_RESULT_CACHE '%s' is not supported. Accepted values are: [%s]", backend, strings.Join(resultCacheBackends, ", "))
+	}
+}
+
+// Get returns the result cached under key if it has not expired.
+func (c *resultCache) Get(key string, now time.Time) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResult{}, false
+	}
+	if now.Sub(entry.CreatedAt) > c.ttl {
+		delete(c.entries, key)
+		return cachedResult{}, false
+	}
+	return entry, true
+}
+
+// Put caches result under key, replacing any earlier entry, and drops the entries that have
+// expired by result.CreatedAt.
+func (c *resultCache) Put(key string, result cachedResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, entry := range c.entries {
+		if result.CreatedAt.Sub(entry.CreatedAt) > c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = result
+}
+
+// Delete drops the result cached under key, e.g. because its outputs no longer exist.
+func (c *resultCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// videoResults caches generation results. It is nil unless GENMEDIA_RESULT_CACHE enables
+// caching, which is set up in main.
+var videoResults *resultCache
+
+// resultCacheKey returns a stable hash of everything that determines the outputs of a
+// generation request: the call type, model, prompt, input image or video, the generation
+// config (aspect ratio, duration, seed, reference images, output location, and so on), and
+// the options applied to the GCS outputs.
+func resultCacheKey(callType, model, prompt string, image *genai.Image, video *genai.Video, config *genai.GenerateVideosConfig, objectOptions outputObjectOptions) (string, error) {
+	data, err := json.Marshal(struct {
+		CallType      string
+		Model         string
+		Prompt        string
+		Image         *genai.Image
+		Video         *genai.Video
+		Config        *genai.GenerateVideosConfig
+		ObjectOptions outputObjectOptions
+	}{callType, model, prompt, image, video, config, objectOptions})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the request for the result cache: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookupCachedResult returns the result cached under key if it has not expired and all of
+// its GCS outputs still exist. Results whose outputs were deleted or cannot be checked are
+// dropped from the cache so the request is generated again.
+func lookupCachedResult(ctx context.Context, key string) (cachedResult, bool) {
+	cached, ok := videoResults.Get(key, time.Now())
+	if !ok {
+		return cachedResult{}, false
+	}
+	logger := loggerFromContext(ctx)
+	for _, gcsURI := range cached.GCSURIs {
+		exists, err := common.GCSObjectExists(ctx, gcsURI)
+		if err != nil || !exists {
+			logger.Info("Cached result is no longer available; generating again", "gcs_uri", gcsURI, "error", err)
+			videoResults.Delete(key)
+			return cachedResult{}, false
+		}
+	}
+	return cached, true
+}
+
+// cachedVideoToolResult builds the tool result for a request answered from the result cache.
+// The cached GCS outputs are downloaded to outputDir if it is set, as for a new generation.
+func cachedVideoToolResult(ctx context.Context, cached cachedResult, config *genai.GenerateVideosConfig, outputDir, outputFormat, callType string) (*mcp.CallToolResult, error) {
+	operation := &genai.GenerateVideosOperation{Name: cached.OperationName, Done: true, Response: &genai.GenerateVideosResponse{}}
+	for _, gcsURI := range cached.GCSURIs {
+		operation.Response.GeneratedVideos = append(operation.Response.GeneratedVideos, &genai.GeneratedVideo{Video: &genai.Video{URI: gcsURI}})
+	}
+	// The cached objects already carry their final names, so no filename prefix is applied.
+	outputs := collectGeneratedVideos(ctx, operation, outputDir, "", "", cached.Model)
+
+	var durationSecs int32
+	if config.DurationSeconds != nil {
+		durationSecs = *config.DurationSeconds
+	}
+	summary := videoResult{
+		Status:        "completed",
+		OperationName: cached.OperationName,
+		Model:         cached.Model,
+		AspectRatio:   config.AspectRatio,
+		Duration:      durationSecs,
+		Watermarked:   true,
+		Cached:        true,
+		Errors:        outputs.Errors,
+	}
+
+	messageParts := []string{
+		fmt.Sprintf("Returned %d cached video(s) (%s) using model %s from an identical request made at %s (operation %s); the Veo API was not called. Set force_regenerate to render them again.",
+			len(outputs.GCSURIs), callType, cached.Model, cached.CreatedAt.UTC().Format(time.RFC3339), cached.OperationName),
+		fmt.Sprintf("Videos saved to GCS: %s.", strings.Join(outputs.GCSURIs, ", ")),
+	}
+	if len(outputs.LocalFiles) > 0 {
+		messageParts = append(messageParts, fmt.Sprintf("Successfully saved locally to '%s': %s.", outputDir, strings.Join(outputs.LocalFiles, ", ")))
+	}
+	if message := thumbnailsMessage(outputs); message != "" {
+		messageParts = append(messageParts, message)
+	}
+	if len(outputs.Errors) > 0 {
+		messageParts = append(messageParts, fmt.Sprintf("Local download/save issues: %s.", strings.Join(outputs.Errors, "; ")))
+	}
+	messageParts = append(messageParts, synthIDVideoNote)
+	return videoToolResult(outputFormat, strings.Join(messageParts, " "), summary, outputs)
+}
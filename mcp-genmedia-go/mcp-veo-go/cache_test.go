@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestNewResultCache(t *testing.T) {
+	testCases := []struct {
+		backend     string
+		expectCache bool
+		expectError bool
+	}{
+		{"", false, false},
+		{"none", false, false},
+		{"memory", true, false},
+		{" Memory ", true, false},
+		{"redis", false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.backend, func(t *testing.T) {
+			cache, err := newResultCache(tc.backend, time.Hour)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if (cache != nil) != tc.expectCache {
+				t.Errorf("expected cache: %v, but got %v", tc.expectCache, cache != nil)
+			}
+		})
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	cache, err := newResultCache("memory", time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	createdAt := time.Now()
+	cache.Put("key", cachedResult{OperationName: "op", GCSURIs: []string{"gs://bucket/video.mp4"}, CreatedAt: createdAt})
+
+	if cached, ok := cache.Get("key", createdAt.Add(30*time.Minute)); !ok || cached.OperationName != "op" {
+		t.Errorf("expected a cache hit for 'op', but got %+v (hit: %v)", cached, ok)
+	}
+	if _, ok := cache.Get("key", createdAt.Add(2*time.Hour)); ok {
+		t.Error("expected the entry to have expired")
+	}
+	if _, ok := cache.Get("key", createdAt); ok {
+		t.Error("expected the expired entry to have been dropped")
+	}
+
+	cache.Put("key", cachedResult{CreatedAt: createdAt})
+	cache.Delete("key")
+	if _, ok := cache.Get("key", createdAt); ok {
+		t.Error("expected the deleted entry to be missing")
+	}
+}
+
+func TestResultCachePutSweepsExpired(t *testing.T) {
+	cache, err := newResultCache("memory", time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	createdAt := time.Now()
+	cache.Put("old", cachedResult{CreatedAt: createdAt})
+	cache.Put("recent", cachedResult{CreatedAt: createdAt.Add(90 * time.Minute)})
+	cache.Put("new", cachedResult{CreatedAt: createdAt.Add(2 * time.Hour)})
+
+	if _, ok := cache.entries["old"]; ok {
+		t.Error("expected the expired entry to be swept when a result was added")
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("expected the 2 unexpired entries to be kept, but got %d", len(cache.entries))
+	}
+}
+
+func TestResultCacheKey(t *testing.T) {
+	seed := int32(42)
+	duration := int32(8)
+	newConfig := func() *genai.GenerateVideosConfig {
+		return &genai.GenerateVideosConfig{AspectRatio: "16:9", DurationSeconds: &duration, Seed: &seed}
+	}
+	key := func(prompt string, config *genai.GenerateVideosConfig, opts outputObjectOptions) string {
+		k, err := resultCacheKey("t2v", "veo-3.0-generate-001", prompt, nil, nil, config, opts)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		return k
+	}
+
+	base := key("a cat", newConfig(), outputObjectOptions{Labels: map[string]string{"a": "1", "b": "2"}})
+	if same := key("a cat", newConfig(), outputObjectOptions{Labels: map[string]string{"b": "2", "a": "1"}}); same != base {
+		t.Errorf("expected identical requests to have the same key, but got '%s' and '%s'", base, same)
+	}
+
+	otherSeed := newConfig()
+	otherSeed.Seed = &duration
+	withReference := newConfig()
+	withReference.ReferenceImages = []*genai.VideoGenerationReferenceImage{{Image: &genai.Image{GCSURI: "gs://bucket/ref.png"}}}
+	variants := map[string]string{
+		"prompt":           key("a dog", newConfig(), outputObjectOptions{Labels: map[string]string{"a": "1", "b": "2"}}),
+		"seed":             key("a cat", otherSeed, outputObjectOptions{Labels: map[string]string{"a": "1", "b": "2"}}),
+		"reference images": key("a cat", withReference, outputObjectOptions{Labels: map[string]string{"a": "1", "b": "2"}}),
+		"object options":   key("a cat", newConfig(), outputObjectOptions{}),
+	}
+	for name, variant := range variants {
+		if variant == base {
+			t.Errorf("expected a different %s to change the key", name)
+		}
+	}
+}
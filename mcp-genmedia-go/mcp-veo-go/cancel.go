@@ -0,0 +1,177 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// cancelOperationTimeout bounds the Vertex AI calls made by veo_cancel_operation.
+const cancelOperationTimeout = 30 * time.Second
+
+// cancelResult is the machine-readable result of the veo_cancel_operation tool. Status is
+// "cancel_requested", "already_completed", or "already_failed".
+type cancelResult struct {
+	Status        string `json:"status"`
+	OperationName string `json:"operation_name"`
+	Message       string `json:"message"`
+}
+
+// operationLocation returns the location segment of an operation name such as
+// projects/p/locations/us-central1/publishers/google/models/m/operations/id, or an
+// empty string if the name has none.
+func operationLocation(operationName string) string {
+	parts := strings.Split(operationName, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "locations" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// operationCancelURL returns the Vertex AI REST URL that cancels the named operation. The
+// regional endpoint of the operation's location is used unless baseURL overrides it.
+func operationCancelURL(baseURL, location, operationName string) string {
+	if baseURL == "" {
+		if location == "" || location == "global" {
+			baseURL = "https://aiplatform.googleapis.com"
+		} else {
+			baseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+		}
+	}
+	return fmt.Sprintf("%s/v1/%s:cancel", strings.TrimSuffix(baseURL, "/"), strings.TrimPrefix(operationName, "/"))
+}
+
+// sendCancelRequest posts a cancel request for a long-running operation. Error responses are
+// returned as a genai.APIError so they are reported like the other Vertex AI errors.
+func sendCancelRequest(ctx context.Context, httpClient *http.Client, cancelURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cancelURL, strings.NewReader("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to create cancel request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cancel request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return apiErrorFromResponse(resp)
+}
+
+// apiErrorFromResponse reads a failed Google Cloud REST response into a genai.APIError, so that
+// it is reported like the other Vertex AI errors. Bodies that are not a JSON error object are
+// used as the message.
+func apiErrorFromResponse(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var errResp struct {
+		Error genai.APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		errResp.Error.Message = strings.TrimSpace(string(body))
+	}
+	errResp.Error.Code = resp.StatusCode
+	return errResp.Error
+}
+
+// veoCancelOperationHandler is the handler for the 'veo_cancel_operation' tool. It checks the
+// operation first, so that an operation that has already finished is reported as such, and
+// otherwise asks Vertex AI to cancel it. The genai SDK has no cancel method, so the request
+// is sent to the Vertex AI REST API with Application Default Credentials, or as the
+// impersonated service account if one is given.
+func veoCancelOperationHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_cancel_operation")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_cancel_operation")
+
+	args := request.GetArguments()
+	client, err := resolveGenAIClient(ctx, client, args)
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+
+	operationName, _ := args["operation_name"].(string)
+	if operationName = strings.TrimSpace(operationName); operationName == "" {
+		return invalidArgumentResult("operation_name must be a non-empty string and is required"), nil
+	}
+	span.SetAttributes(attribute.String("operation_name", operationName))
+	ctx, logger = withLogAttrs(ctx, "model", modelFromOperationName(operationName), "operation_name", operationName)
+	logger.Info("Handling Veo cancel operation request")
+
+	ctx, cancel := context.WithTimeout(ctx, cancelOperationTimeout)
+	defer cancel()
+
+	operation, err := client.Operations.GetVideosOperation(ctx, &genai.GenerateVideosOperation{Name: operationName}, &genai.GetOperationConfig{})
+	if err != nil {
+		logger.Error("Error fetching operation before canceling it", "error", err)
+		return codedToolResultError(errorCode(err), fmt.Sprintf("error fetching operation '%s': %v", operationName, err)), nil
+	}
+
+	result := cancelResult{OperationName: operationName}
+	switch {
+	case operation.Done && operation.Error != nil:
+		errMessage, errCode := operationErrorDetails(operation.Error)
+		result.Status = "already_failed"
+		result.Message = fmt.Sprintf("Operation %s had already finished with an error, so there is nothing to cancel: %s (code: %d).", operationName, errMessage, errCode)
+	case operation.Done:
+		result.Status = "already_completed"
+		result.Message = fmt.Sprintf("Operation %s had already completed, so it was not canceled. Use veo_get_operation to retrieve its videos.", operationName)
+	default:
+		location := operationLocation(operationName)
+		if location == "" {
+			location = appConfig.Location
+		}
+		httpClient, err := restHTTPClient(ctx, args)
+		if err != nil {
+			return codedToolResultError(errorCode(err), fmt.Sprintf("failed to load credentials to cancel operation '%s': %v", operationName, err)), nil
+		}
+		if err := sendCancelRequest(ctx, httpClient, operationCancelURL(appConfig.ApiEndpoint, location, operationName)); err != nil {
+			logger.Error("Error canceling operation", "error", err)
+			return codedToolResultError(errorCode(err), fmt.Sprintf("failed to cancel operation '%s': %v", operationName, err)), nil
+		}
+		result.Status = "cancel_requested"
+		result.Message = fmt.Sprintf("Cancellation of operation %s was requested. Cancellation is best effort: the operation may still complete, which veo_get_operation will show.", operationName)
+	}
+	logger.Info(result.Message, "status", result.Status)
+	span.SetAttributes(attribute.String("status", result.Status))
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal cancel result: %v", err)), nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: result.Message},
+			mcp.TextContent{Type: "text", Text: string(resultJSON)},
+		},
+	}, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+func TestOperationCancelURL(t *testing.T) {
+	const name = "projects/p/locations/us-central1/publishers/google/models/veo-2.0-generate-001/operations/123"
+	testCases := []struct {
+		name     string
+		baseURL  string
+		location string
+		expected string
+	}{
+		{"regional", "", operationLocation(name), "https://us-central1-aiplatform.googleapis.com/v1/" + name + ":cancel"},
+		{"global", "", "global", "https://aiplatform.googleapis.com/v1/" + name + ":cancel"},
+		{"custom endpoint", "https://example.com/", "us-central1", "https://example.com/v1/" + name + ":cancel"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := operationCancelURL(tc.baseURL, tc.location, name)
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOperationLocation(t *testing.T) {
+	if location := operationLocation("projects/p/locations/europe-west4/operations/1"); location != "europe-west4" {
+		t.Errorf("expected 'europe-west4', but got '%s'", location)
+	}
+	if location := operationLocation("operations/1"); location != "" {
+		t.Errorf("expected no location, but got '%s'", location)
+	}
+}
+
+func TestSendCancelRequest(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       int
+		body         string
+		expectedCode int
+	}{
+		{"success", http.StatusOK, "{}", 0},
+		{"not found", http.StatusNotFound, `{"error": {"code": 404, "message": "operation not found", "status": "NOT_FOUND"}}`, 404},
+		{"non-JSON error", http.StatusBadGateway, "bad gateway", 502},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected a POST request, but got %s", r.Method)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			err := sendCancelRequest(t.Context(), server.Client(), operationCancelURL(server.URL, "", "projects/p/operations/1"))
+			if tc.expectedCode == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, but got: %v", err)
+				}
+				return
+			}
+			var apiErr genai.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected a genai.APIError, but got: %v", err)
+			}
+			if apiErr.Code != tc.expectedCode || apiErr.Message == "" {
+				t.Errorf("expected code %d with a message, but got %d '%s'", tc.expectedCode, apiErr.Code, apiErr.Message)
+			}
+		})
+	}
+}
+
+func TestVeoCancelOperationHandlerInvalidArguments(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	testCases := []struct {
+		name     string
+		args     map[string]interface{}
+		expected string
+	}{
+		{"missing operation name", map[string]interface{}{}, "operation_name must be a non-empty string"},
+		{"invalid service account", map[string]interface{}{"operation_name": "operations/1", "impersonate_service_account": "not-an-email"}, "is not a service account email"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tc.args
+			result, err := veoCancelOperationHandler(nil, t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !result.IsError || !strings.HasPrefix(text, "[code=INVALID_ARGUMENT]") || !strings.Contains(text, tc.expected) {
+				t.Errorf("expected an INVALID_ARGUMENT result containing '%s', but got '%s'", tc.expected, text)
+			}
+		})
+	}
+}
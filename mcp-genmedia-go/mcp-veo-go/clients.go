@@ -0,0 +1,164 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials/impersonate"
+	"cloud.google.com/go/auth/httptransport"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/genai"
+)
+
+// cloudPlatformScope is the OAuth scope used for Google Cloud REST requests and for
+// impersonated credentials.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// serviceAccountEmailPattern matches Google Cloud service account emails, such as
+// name@project.iam.gserviceaccount.com or 123-compute@developer.gserviceaccount.com.
+var serviceAccountEmailPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*@[a-z0-9][a-z0-9.-]*\.gserviceaccount\.com$`)
+
+// genAIClientCache holds GenAI clients created for per-call project/location overrides and
+// impersonated service accounts, keyed by "project/location" (with "/service-account"
+// appended when impersonating), so they are not recreated on every call.
+var (
+	genAIClientCacheMu sync.Mutex
+	genAIClientCache   = make(map[string]*genai.Client)
+)
+
+// newGenAIClientConfig builds the Vertex AI client configuration for a project and location,
+// honoring the custom API endpoint from the app config.
+func newGenAIClientConfig(project, location string) *genai.ClientConfig {
+	clientConfig := &genai.ClientConfig{
+		Backend:  genai.BackendVertexAI,
+		Project:  project,
+		Location: location,
+	}
+	if appConfig.ApiEndpoint != "" {
+		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
+	}
+	return clientConfig
+}
+
+// parseServiceAccountArg reads the optional 'impersonate_service_account' argument, returning
+// an empty string if it is not set and an error if it is not a service account email.
+func parseServiceAccountArg(args map[string]interface{}) (string, error) {
+	serviceAccountArg, _ := args["impersonate_service_account"].(string)
+	serviceAccount := strings.ToLower(strings.TrimSpace(serviceAccountArg))
+	if serviceAccount == "" {
+		return "", nil
+	}
+	if !serviceAccountEmailPattern.MatchString(serviceAccount) {
+		return "", fmt.Errorf("%w: impersonate_service_account '%s' is not a service account email (e.g., name@project.iam.gserviceaccount.com)", errInvalidServiceAccount, serviceAccountArg)
+	}
+	return serviceAccount, nil
+}
+
+// impersonatedCredentials returns credentials that act as serviceAccount. A token is fetched
+// up front so that a missing permission is reported clearly here rather than as an opaque
+// failure of the first API call.
+func impersonatedCredentials(ctx context.Context, serviceAccount string) (*auth.Credentials, error) {
+	credentials, err := impersonate.NewCredentials(&impersonate.CredentialsOptions{
+		TargetPrincipal: serviceAccount,
+		Scopes:          []string{cloudPlatformScope},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating impersonated credentials for service account '%s': %w", serviceAccount, err)
+	}
+	if _, err := credentials.Token(ctx); err != nil {
+		return nil, fmt.Errorf("%w: could not impersonate service account '%s'. The server's credentials need the Service Account Token Creator role (roles/iam.serviceAccountTokenCreator) on it: %v", errImpersonationDenied, serviceAccount, err)
+	}
+	return credentials, nil
+}
+
+// resolveGenAIClient returns the client to use for a request. If the optional 'project' or
+// 'location' arguments differ from the configured defaults, or 'impersonate_service_account'
+// is set, a cached request-scoped client for that combination is returned (and created on
+// first use); otherwise the global client is returned.
+func resolveGenAIClient(ctx context.Context, defaultClient *genai.Client, args map[string]interface{}) (*genai.Client, error) {
+	projectArg, _ := args["project"].(string)
+	locationArg, _ := args["location"].(string)
+	project := strings.TrimSpace(projectArg)
+	location := strings.TrimSpace(locationArg)
+	if project == "" {
+		project = appConfig.ProjectID
+	}
+	if location == "" {
+		location = appConfig.Location
+	}
+	serviceAccount, err := parseServiceAccountArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if project == appConfig.ProjectID && location == appConfig.Location && serviceAccount == "" {
+		return defaultClient, nil
+	}
+
+	key := project + "/" + location
+	if serviceAccount != "" {
+		key += "/" + serviceAccount
+	}
+	genAIClientCacheMu.Lock()
+	defer genAIClientCacheMu.Unlock()
+	if client, ok := genAIClientCache[key]; ok {
+		return client, nil
+	}
+
+	loggerFromContext(ctx).Info("Creating GenAI client", "project", project, "location", location, "impersonate_service_account", serviceAccount)
+	clientCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+	clientConfig := newGenAIClientConfig(project, location)
+	if serviceAccount != "" {
+		credentials, err := impersonatedCredentials(clientCtx, serviceAccount)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.Credentials = credentials
+	}
+	client, err := genai.NewClient(clientCtx, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GenAI client for project '%s' and location '%s': %w", project, location, err)
+	}
+	genAIClientCache[key] = client
+	return client, nil
+}
+
+// restHTTPClient returns the HTTP client used for Google Cloud REST requests that the genai SDK
+// does not cover: one authorized as the 'impersonate_service_account' if it is set, or with
+// Application Default Credentials otherwise.
+func restHTTPClient(ctx context.Context, args map[string]interface{}) (*http.Client, error) {
+	serviceAccount, err := parseServiceAccountArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if serviceAccount == "" {
+		return google.DefaultClient(ctx, cloudPlatformScope)
+	}
+	credentials, err := impersonatedCredentials(ctx, serviceAccount)
+	if err != nil {
+		return nil, err
+	}
+	return httptransport.NewClient(&httptransport.Options{Credentials: credentials})
+}
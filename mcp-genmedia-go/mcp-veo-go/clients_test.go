@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestResolveGenAIClient(t *testing.T) {
+	appConfig = &common.Config{ProjectID: "default-project", Location: "us-central1"}
+	defer func() { appConfig = nil }()
+	defaultClient := &genai.Client{}
+
+	client, err := resolveGenAIClient(t.Context(), defaultClient, map[string]interface{}{})
+	if err != nil || client != defaultClient {
+		t.Fatalf("expected the default client without overrides, but got %v (err: %v)", client, err)
+	}
+
+	client, err = resolveGenAIClient(t.Context(), defaultClient, map[string]interface{}{"project": "default-project"})
+	if err != nil || client != defaultClient {
+		t.Fatalf("expected the default client when overrides match the defaults, but got %v (err: %v)", client, err)
+	}
+
+	args := map[string]interface{}{"project": "other-project", "location": "europe-west4"}
+	first, err := resolveGenAIClient(t.Context(), defaultClient, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == defaultClient {
+		t.Fatal("expected a request-scoped client for an overridden project")
+	}
+	second, err := resolveGenAIClient(t.Context(), defaultClient, args)
+	if err != nil || second != first {
+		t.Errorf("expected the cached client to be reused, but got %v (err: %v)", second, err)
+	}
+}
+
+func TestParseServiceAccountArg(t *testing.T) {
+	testCases := []struct {
+		name          string
+		value         interface{}
+		expected      string
+		expectedError bool
+	}{
+		{"unset", nil, "", false},
+		{"blank", "  ", "", false},
+		{"iam service account", "Renderer@my-project.iam.gserviceaccount.com", "renderer@my-project.iam.gserviceaccount.com", false},
+		{"default compute service account", "123456-compute@developer.gserviceaccount.com", "123456-compute@developer.gserviceaccount.com", false},
+		{"user email", "someone@example.com", "", true},
+		{"not an email", "renderer", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{}
+			if tc.value != nil {
+				args["impersonate_service_account"] = tc.value
+			}
+			actual, err := parseServiceAccountArg(args)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil && errorCode(err) != codeInvalidArgument {
+				t.Errorf("expected code %s, but got %s", codeInvalidArgument, errorCode(err))
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveGenAIClientInvalidServiceAccount(t *testing.T) {
+	appConfig = &common.Config{ProjectID: "default-project", Location: "us-central1"}
+	defer func() { appConfig = nil }()
+
+	_, err := resolveGenAIClient(t.Context(), &genai.Client{}, map[string]interface{}{"impersonate_service_account": "someone@example.com"})
+	if err == nil || errorCode(err) != codeInvalidArgument {
+		t.Errorf("expected an %s error, but got %v", codeInvalidArgument, err)
+	}
+}
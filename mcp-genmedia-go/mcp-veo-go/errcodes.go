@@ -0,0 +1,156 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+// Canonical status names used in the [code=...] tag of error results that do not come
+// from the API.
+const (
+	codeInvalidArgument  = "INVALID_ARGUMENT"
+	codeNotFound         = "NOT_FOUND"
+	codePermissionDenied = "PERMISSION_DENIED"
+	codeCancelled        = "CANCELLED"
+	codeDeadlineExceeded = "DEADLINE_EXCEEDED"
+	codeInternal         = "INTERNAL"
+	codeUnimplemented    = "UNIMPLEMENTED"
+	codeUnknown          = "UNKNOWN"
+	// codeContentFiltered is not a gRPC status. It marks operations that completed without
+	// videos, which happens when every output was removed by the safety filters.
+	codeContentFiltered = "CONTENT_FILTERED"
+	// codePromptFlagged is not a gRPC status either. It marks generations that were not
+	// started because prompt moderation (GENMEDIA_MODERATE_PROMPTS) flagged the prompt.
+	codePromptFlagged = "PROMPT_FLAGGED"
+)
+
+// grpcStatusNames maps the numeric gRPC status codes found in failed long-running
+// operations to their canonical names.
+var grpcStatusNames = map[int32]string{
+	1:  "CANCELLED",
+	2:  "UNKNOWN",
+	3:  "INVALID_ARGUMENT",
+	4:  "DEADLINE_EXCEEDED",
+	5:  "NOT_FOUND",
+	6:  "ALREADY_EXISTS",
+	7:  "PERMISSION_DENIED",
+	8:  "RESOURCE_EXHAUSTED",
+	9:  "FAILED_PRECONDITION",
+	10: "ABORTED",
+	11: "OUT_OF_RANGE",
+	12: "UNIMPLEMENTED",
+	13: "INTERNAL",
+	14: "UNAVAILABLE",
+	15: "DATA_LOSS",
+	16: "UNAUTHENTICATED",
+}
+
+// httpStatusNames maps HTTP status codes to canonical status names, for API errors that
+// carry no status of their own.
+var httpStatusNames = map[int]string{
+	400: "INVALID_ARGUMENT",
+	401: "UNAUTHENTICATED",
+	403: "PERMISSION_DENIED",
+	404: "NOT_FOUND",
+	409: "ABORTED",
+	429: "RESOURCE_EXHAUSTED",
+	499: "CANCELLED",
+	500: "INTERNAL",
+	501: "UNIMPLEMENTED",
+	503: "UNAVAILABLE",
+	504: "DEADLINE_EXCEEDED",
+}
+
+// errInputNotFound is wrapped by errors for input images that do not exist or cannot be read.
+var errInputNotFound = errors.New("image not found or not accessible")
+
+// errInvalidServiceAccount is wrapped by errors for malformed impersonate_service_account values.
+var errInvalidServiceAccount = errors.New("invalid service account")
+
+// errImpersonationDenied is wrapped by errors for service accounts that cannot be impersonated.
+var errImpersonationDenied = errors.New("service account impersonation denied")
+
+// errorCode returns the canonical status name of err: the status reported by the API for a
+// genai.APIError (or one derived from its HTTP code), CANCELLED or DEADLINE_EXCEEDED for
+// context errors, NOT_FOUND for missing input images, INVALID_ARGUMENT or PERMISSION_DENIED
+// for service accounts that are malformed or cannot be impersonated, and UNKNOWN otherwise.
+func errorCode(err error) string {
+	var apiErr genai.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.Status != "" {
+			return apiErr.Status
+		}
+		if name, ok := httpStatusNames[apiErr.Code]; ok {
+			return name
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return codeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codeCancelled
+	case errors.Is(err, errInputNotFound):
+		return codeNotFound
+	case errors.Is(err, errInvalidServiceAccount):
+		return codeInvalidArgument
+	case errors.Is(err, errImpersonationDenied):
+		return codePermissionDenied
+	}
+	return codeUnknown
+}
+
+// operationErrorCode returns the canonical status name of the numeric code in a failed
+// operation's error.
+func operationErrorCode(code int32) string {
+	if name, ok := grpcStatusNames[code]; ok {
+		return name
+	}
+	return codeUnknown
+}
+
+// codedToolResultError returns an error tool result whose text starts with a [code=...] tag,
+// so that clients can branch on the error category without parsing the message.
+func codedToolResultError(code, message string) *mcp.CallToolResult {
+	return mcp.NewToolResultError(fmt.Sprintf("[code=%s] %s", code, message))
+}
+
+// invalidArgumentResult returns an error tool result for a request rejected by validation.
+func invalidArgumentResult(message string) *mcp.CallToolResult {
+	return codedToolResultError(codeInvalidArgument, message)
+}
+
+// resultErrorCode returns the status name in the [code=...] tag of an error result, or
+// UNKNOWN if its text has no tag.
+func resultErrorCode(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			if rest, found := strings.CutPrefix(text.Text, "[code="); found {
+				if code, _, found := strings.Cut(rest, "]"); found && code != "" {
+					return code
+				}
+			}
+			break
+		}
+	}
+	return codeUnknown
+}
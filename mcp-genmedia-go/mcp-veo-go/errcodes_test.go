@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"API status", genai.APIError{Code: 400, Status: "INVALID_ARGUMENT"}, "INVALID_ARGUMENT"},
+		{"wrapped API status", fmt.Errorf("start: %w", genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}), "RESOURCE_EXHAUSTED"},
+		{"HTTP code only", genai.APIError{Code: 403}, "PERMISSION_DENIED"},
+		{"unmapped HTTP code", genai.APIError{Code: 418}, "UNKNOWN"},
+		{"deadline", fmt.Errorf("poll: %w", context.DeadlineExceeded), "DEADLINE_EXCEEDED"},
+		{"canceled", context.Canceled, "CANCELLED"},
+		{"missing input image", fmt.Errorf("image_uri 'gs://bucket/a.png': %w", errInputNotFound), "NOT_FOUND"},
+		{"invalid service account", fmt.Errorf("%w: bad email", errInvalidServiceAccount), "INVALID_ARGUMENT"},
+		{"impersonation denied", fmt.Errorf("%w: missing role", errImpersonationDenied), "PERMISSION_DENIED"},
+		{"other", errors.New("boom"), "UNKNOWN"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := errorCode(tc.err)
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOperationErrorCode(t *testing.T) {
+	if code := operationErrorCode(3); code != "INVALID_ARGUMENT" {
+		t.Errorf("expected 'INVALID_ARGUMENT', but got '%s'", code)
+	}
+	if code := operationErrorCode(99); code != "UNKNOWN" {
+		t.Errorf("expected 'UNKNOWN', but got '%s'", code)
+	}
+}
+
+func TestCodedToolResultError(t *testing.T) {
+	result := invalidArgumentResult("duration '3' is not supported")
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.HasPrefix(text, "[code=INVALID_ARGUMENT] duration") {
+		t.Errorf("expected an error result tagged with the code, but got '%s'", text)
+	}
+}
+
+func TestResultErrorCode(t *testing.T) {
+	if code := resultErrorCode(codedToolResultError(codeContentFiltered, "no videos")); code != "CONTENT_FILTERED" {
+		t.Errorf("expected 'CONTENT_FILTERED', but got '%s'", code)
+	}
+	if code := resultErrorCode(mcp.NewToolResultError("untagged failure")); code != "UNKNOWN" {
+		t.Errorf("expected 'UNKNOWN', but got '%s'", code)
+	}
+}
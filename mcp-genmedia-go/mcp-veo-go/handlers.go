@@ -20,7 +20,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"maps"
+	"slices"
 	"strings"
 
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
@@ -36,27 +37,75 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(ctx, "veo_t2v")
 	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_t2v")
 
-	prompt, ok := request.GetArguments()["prompt"].(string)
-	if !ok || strings.TrimSpace(prompt) == "" {
-		return mcp.NewToolResultError("prompt must be a non-empty string and is required for text-to-video"), nil
+	if err := checkToolParams("veo_t2v", request.GetArguments()); err != nil {
+		return invalidArgumentResult(err.Error()), nil
 	}
 
-	gcsBucket, outputDir, model, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, err := parseCommonVideoParams(request.GetArguments(), appConfig)
+	client, err := resolveGenAIClient(ctx, client, request.GetArguments())
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return codedToolResultError(errorCode(err), err.Error()), nil
 	}
 
+	// prompt_template, when used instead of prompt, is expanded here so that the final
+	// prompt is what gets generated, traced, and cached.
+	prompt, promptTemplate, err := resolvePromptArg(request.GetArguments())
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	args := request.GetArguments()
+	if promptTemplate != "" {
+		// Validate the expanded prompt, e.g. its length, as if it had been passed as prompt.
+		args = maps.Clone(args)
+		args["prompt"] = prompt
+	}
+
+	params, err := parseCommonVideoParams(ctx, args, appConfig)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	ctx, logger = withLogAttrs(ctx, "model", params.Model)
+
+	t2v, err := newTextToVideoRequest(ctx, params, args, promptTemplate != "")
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	prompt = t2v.Prompt
+
 	span.SetAttributes(
 		attribute.String("prompt", prompt),
-		attribute.String("gcs_bucket", gcsBucket),
-		attribute.String("output_dir", outputDir),
-		attribute.String("model", model),
-		attribute.String("aspect_ratio", finalAspectRatio),
-		attribute.Int("num_videos", int(numberOfVideos)),
-		attribute.Int("duration_secs", int(durationSecs)),
-		attribute.Bool("generate_audio", generateAudio),
+		attribute.String("negative_prompt", t2v.Config.NegativePrompt),
+		attribute.String("gcs_bucket", params.GCSBucket),
+		attribute.String("output_dir", params.OutputDir),
+		attribute.String("model", params.Model),
+		attribute.String("aspect_ratio", params.AspectRatio),
+		attribute.String("resolution", params.Resolution),
+		attribute.Int("num_videos", int(params.NumberOfVideos)),
+		attribute.Int("duration_secs", int(params.DurationSecs)),
+		attribute.Bool("generate_audio", params.GenerateAudio),
 	)
+	if len(params.AspectRatios) > 1 {
+		span.SetAttributes(attribute.StringSlice("aspect_ratios", params.AspectRatios))
+	}
+	if params.Seed != nil {
+		span.SetAttributes(attribute.Int("seed", int(*params.Seed)))
+	}
+	if params.PersonGeneration != "" {
+		span.SetAttributes(attribute.String("person_generation", params.PersonGeneration))
+	}
+	if params.CompressionQuality != "" {
+		span.SetAttributes(attribute.String("compression_quality", params.CompressionQuality))
+	}
+	if enhancePrompt, ok := args["enhance_prompt"].(bool); ok {
+		span.SetAttributes(attribute.Bool("enhance_prompt", enhancePrompt))
+	}
+	if promptTemplate != "" {
+		span.SetAttributes(attribute.String("prompt_template", promptTemplate))
+	}
+	if params.Style != "" {
+		span.SetAttributes(attribute.String("style", params.Style))
+	}
 
 	mcpServer := server.ServerFromContext(ctx)
 	var progressToken mcp.ProgressToken
@@ -66,24 +115,93 @@ func veoTextToVideoHandler(client *genai.Client, ctx context.Context, request mc
 
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming t2v context for prompt %s was already canceled: %v", prompt, ctx.Err())
-		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
+		logger.Warn("Incoming request was already canceled", "prompt", prompt, "error", ctx.Err())
+		return codedToolResultError(errorCode(ctx.Err()), fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo t2v request: Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t", prompt, gcsBucket, outputDir, model, numberOfVideos, finalAspectRatio, durationSecs, generateAudio)
+		logger.Info("Handling Veo t2v request", "prompt", prompt, "negative_prompt", t2v.Config.NegativePrompt, "gcs_bucket", params.GCSBucket, "output_dir", params.OutputDir, "num_videos", params.NumberOfVideos, "aspect_ratio", params.AspectRatio, "duration_secs", params.DurationSecs, "generate_audio", params.GenerateAudio)
 	}
 
-	config := &genai.GenerateVideosConfig{
-		NumberOfVideos:  numberOfVideos,
-		AspectRatio:     finalAspectRatio,
-		OutputGCSURI:    gcsBucket,
-		DurationSeconds: &durationSecs,
+	return t2v.generate(client, ctx, mcpServer, progressToken, "t2v")
+}
+
+// textToVideoRequest is a validated text-to-video generation. veo_t2v and each prompt of
+// veo_batch_t2v build and run one, so that both generate a prompt the same way.
+type textToVideoRequest struct {
+	Params *VideoParams
+	// Prompt is the prompt sent to Veo, after prompt_template expansion and styling.
+	Prompt string
+	// PromptNote describes how Prompt differs from the 'prompt' argument, if it does.
+	PromptNote string
+	Config     *genai.GenerateVideosConfig
+	// Warnings lists the parameter and reference image warnings for the result.
+	Warnings []string
+}
+
+// newTextToVideoRequest builds the text-to-video request for params from args, whose 'prompt'
+// is the unstyled prompt. fromTemplate reports whether it was expanded from prompt_template.
+func newTextToVideoRequest(ctx context.Context, params *VideoParams, args map[string]interface{}, fromTemplate bool) (*textToVideoRequest, error) {
+	referenceImages, refWarnings, err := parseReferenceImages(ctx, args, params.Model)
+	if err != nil {
+		return nil, err
 	}
 
-	if generateAudio {
-		config.GenerateAudio = &generateAudio
+	prompt, _ := args["prompt"].(string)
+	prompt = params.StyledPrompt(strings.TrimSpace(prompt))
+	config := params.GenerateVideosConfig()
+	if negativePrompt, ok := args["negative_prompt"].(string); ok {
+		config.NegativePrompt = strings.TrimSpace(negativePrompt)
+	}
+	// When enhance_prompt is not provided, the API default is used.
+	if enhancePrompt, ok := args["enhance_prompt"].(bool); ok {
+		config.EnhancePrompt = enhancePrompt
+	}
+	if len(referenceImages) > 0 {
+		config.ReferenceImages = referenceImages
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, model, prompt, nil, config, "t2v")
+	return &textToVideoRequest{
+		Params:     params,
+		Prompt:     prompt,
+		PromptNote: finalPromptNote(prompt, fromTemplate, params.Style),
+		Config:     config,
+		Warnings:   slices.Concat(params.Warnings, refWarnings),
+	}, nil
+}
+
+// generate runs the request, once per aspect ratio if several were requested, and returns the
+// result with the prompt note and warnings added. Unless it is a dry run, the prompt is screened
+// by moderation first. The whole generation, including queueing and polling, is capped at
+// timeout_seconds.
+func (r *textToVideoRequest) generate(client *genai.Client, ctx context.Context, mcpServer *server.MCPServer, progressToken mcp.ProgressToken, callType string) (*mcp.CallToolResult, error) {
+	params := r.Params
+	ctx, cancel := params.WithTimeout(ctx)
+	defer cancel()
+
+	if !params.DryRun {
+		if blocked := moderationBlockResult(ctx, r.Prompt); blocked != nil {
+			return addResultWarnings(blocked, r.Warnings), nil
+		}
+	}
+
+	generateOne := func(config *genai.GenerateVideosConfig, outputDir, callType string) (*mcp.CallToolResult, error) {
+		if params.DryRun {
+			return dryRunResult(ctx, callType, r.Prompt, nil, nil, config, params)
+		}
+		return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, params.Model, r.Prompt, nil, nil, config, params.OutputObjectOptions(), params.OutputFormat, params.ForceRegenerate, callType)
+	}
+
+	var result *mcp.CallToolResult
+	var err error
+	if len(params.AspectRatios) > 1 {
+		result, err = generateAspectRatioBatch(ctx, params.AspectRatios, params.OutputFormat, func(aspectRatio string) (*mcp.CallToolResult, error) {
+			ratioConfig := *r.Config
+			ratioConfig.AspectRatio = aspectRatio
+			return generateOne(&ratioConfig, aspectRatioOutputDir(params.OutputDir, aspectRatio), callType+" "+aspectRatio)
+		})
+	} else {
+		result, err = generateOne(r.Config, params.OutputDir, callType)
+	}
+	return addResultWarnings(addResultNote(result, r.PromptNote), r.Warnings), err
 }
 
 // veoImageToVideoHandler is the handler for the 'veo_i2v' tool.
@@ -91,30 +209,68 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(ctx, "veo_i2v")
 	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_i2v")
+
+	if err := checkToolParams("veo_i2v", request.GetArguments()); err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+
+	client, err := resolveGenAIClient(ctx, client, request.GetArguments())
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
 
 	imageURI, ok := request.GetArguments()["image_uri"].(string)
 	if !ok || strings.TrimSpace(imageURI) == "" {
-		return mcp.NewToolResultError("image_uri must be a non-empty string (GCS URI) and is required for image-to-video"), nil
-	}
-	if !strings.HasPrefix(imageURI, "gs://") {
-		return mcp.NewToolResultError(fmt.Sprintf("invalid image_uri '%s'. Must be a GCS URI starting with 'gs://'", imageURI)), nil
+		return invalidArgumentResult("image_uri must be a non-empty string (GCS URI, local file path, or base64 data) and is required for image-to-video"), nil
 	}
+	imageURI = strings.TrimSpace(imageURI)
 
 	var mimeType string
 	if mt, ok := request.GetArguments()["mime_type"].(string); ok && strings.TrimSpace(mt) != "" {
 		mimeType = strings.ToLower(strings.TrimSpace(mt))
-		if mimeType != "image/jpeg" && mimeType != "image/png" {
-			log.Printf("Unsupported MIME type provided: %s. Only 'image/jpeg' and 'image/png' are supported.", mimeType)
-			return mcp.NewToolResultError(fmt.Sprintf("Unsupported MIME type '%s'. Please use 'image/jpeg' or 'image/png'.", mimeType)), nil
+		if !isSupportedInputImageMimeType(mimeType) {
+			logger.Warn("Unsupported MIME type provided; only image/jpeg and image/png are supported", "mime_type", mimeType)
+			return invalidArgumentResult(fmt.Sprintf("Unsupported MIME type '%s'. Please use 'image/jpeg' or 'image/png'.", mimeType)), nil
+		}
+		logger.Info("Using provided MIME type", "mime_type", mimeType)
+	}
+
+	var inputImage *genai.Image
+	imageSource := imageURI
+	if strings.HasPrefix(imageURI, "gs://") {
+		if mimeType == "" {
+			if mimeTypesRequired() {
+				return invalidArgumentResult(fmt.Sprintf("mime_type is required for image '%s' because GENMEDIA_REQUIRE_MIME_TYPES disables inferring it from the extension. Please specify it as 'image/jpeg' or 'image/png'.", imageURI)), nil
+			}
+			mimeType = inferMimeTypeFromURI(imageURI)
+			if !isSupportedInputImageMimeType(mimeType) {
+				logger.Warn("Could not infer a supported MIME type (image/jpeg or image/png) from image_uri", "image_uri", imageURI)
+				return invalidArgumentResult(fmt.Sprintf("MIME type for image '%s' could not be inferred or is not supported. Please specify 'mime_type' as 'image/jpeg' or 'image/png'.", imageURI)), nil
+			}
+			logger.Info("Inferred MIME type from image_uri", "mime_type", mimeType, "image_uri", imageURI)
+		}
+		inputImage = &genai.Image{
+			GCSURI:   imageURI,
+			MIMEType: mimeType,
 		}
-		log.Printf("Using provided and validated MIME type: %s", mimeType)
 	} else {
-		mimeType = inferMimeTypeFromURI(imageURI)
+		imageBytes, detectedMimeType, source, err := loadLocalOrInlineImage(imageURI)
+		if err != nil {
+			return invalidArgumentResult(fmt.Sprintf("invalid image_uri: %v", err)), nil
+		}
+		imageSource = source
 		if mimeType == "" {
-			log.Printf("Could not infer a supported MIME type (image/jpeg or image/png) from image_uri: %s. Please provide a 'mime_type' parameter.", imageURI)
-			return mcp.NewToolResultError(fmt.Sprintf("MIME type for image '%s' could not be inferred or is not supported. Please specify 'mime_type' as 'image/jpeg' or 'image/png'.", imageURI)), nil
+			mimeType = detectedMimeType
+			logger.Info("Detected MIME type from image content", "mime_type", mimeType, "image", imageSource)
+		}
+		if !isSupportedInputImageMimeType(mimeType) {
+			return invalidArgumentResult(fmt.Sprintf("image %s has unsupported MIME type '%s'. Only 'image/jpeg' and 'image/png' are supported.", imageSource, mimeType)), nil
+		}
+		inputImage = &genai.Image{
+			ImageBytes: imageBytes,
+			MIMEType:   mimeType,
 		}
-		log.Printf("Inferred MIME type: %s for image_uri: %s", mimeType, imageURI)
 	}
 
 	prompt := ""
@@ -122,23 +278,82 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 		prompt = strings.TrimSpace(promptArg)
 	}
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, err := parseCommonVideoParams(request.GetArguments(), appConfig)
+	negativePrompt := ""
+	if negPromptArg, ok := request.GetArguments()["negative_prompt"].(string); ok {
+		negativePrompt = strings.TrimSpace(negPromptArg)
+	}
+
+	// When enhance_prompt is not provided, the API default is used.
+	enhancePrompt, enhancePromptSet := request.GetArguments()["enhance_prompt"].(bool)
+
+	// aspect_ratio "auto" picks the supported ratio closest to the input image.
+	args := request.GetArguments()
+	aspectRatioAuto := isAutoAspectRatio(args)
+	var aspectRatioNote, aspectRatioWarning string
+	if aspectRatioAuto {
+		var ok bool
+		args, aspectRatioNote, ok = resolveAutoAspectRatio(ctx, args, inputImage)
+		if !ok {
+			aspectRatioNote, aspectRatioWarning = "", aspectRatioNote
+		}
+	}
+
+	params, err := parseCommonVideoParams(ctx, args, appConfig)
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return invalidArgumentResult(err.Error()), nil
+	}
+	ctx, logger = withLogAttrs(ctx, "model", params.Model)
+	if len(params.AspectRatios) > 1 {
+		return invalidArgumentResult("multiple aspect ratios are only supported by veo_t2v"), nil
+	}
+
+	referenceImages, refWarnings, err := parseReferenceImages(ctx, request.GetArguments(), params.Model)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	warnings := slices.Concat(params.Warnings, refWarnings)
+	prompt = params.StyledPrompt(prompt)
+	promptNote := finalPromptNote(prompt, false, params.Style)
+	if aspectRatioWarning != "" {
+		warnings = append(warnings, aspectRatioWarning)
+	}
+
+	if appConfig.CheckInputImages {
+		if err := checkInputImageExists(ctx, "image_uri", inputImage); err != nil {
+			return codedToolResultError(errorCode(err), err.Error()), nil
+		}
 	}
 
 	span.SetAttributes(
-		attribute.String("image_uri", imageURI),
+		attribute.String("image_uri", imageSource),
 		attribute.String("mime_type", mimeType),
+		attribute.Bool("aspect_ratio_auto", aspectRatioAuto),
 		attribute.String("prompt", prompt),
-		attribute.String("gcs_bucket", gcsBucket),
-		attribute.String("output_dir", outputDir),
-		attribute.String("model", modelName),
-		attribute.String("aspect_ratio", finalAspectRatio),
-		attribute.Int("num_videos", int(numberOfVideos)),
-		attribute.Int("duration_secs", int(durationSecs)),
-		attribute.Bool("generate_audio", generateAudio),
+		attribute.String("negative_prompt", negativePrompt),
+		attribute.String("gcs_bucket", params.GCSBucket),
+		attribute.String("output_dir", params.OutputDir),
+		attribute.String("model", params.Model),
+		attribute.String("aspect_ratio", params.AspectRatio),
+		attribute.String("resolution", params.Resolution),
+		attribute.Int("num_videos", int(params.NumberOfVideos)),
+		attribute.Int("duration_secs", int(params.DurationSecs)),
+		attribute.Bool("generate_audio", params.GenerateAudio),
 	)
+	if params.Seed != nil {
+		span.SetAttributes(attribute.Int("seed", int(*params.Seed)))
+	}
+	if params.PersonGeneration != "" {
+		span.SetAttributes(attribute.String("person_generation", params.PersonGeneration))
+	}
+	if params.CompressionQuality != "" {
+		span.SetAttributes(attribute.String("compression_quality", params.CompressionQuality))
+	}
+	if enhancePromptSet {
+		span.SetAttributes(attribute.Bool("enhance_prompt", enhancePrompt))
+	}
+	if params.Style != "" {
+		span.SetAttributes(attribute.String("style", params.Style))
+	}
 
 	mcpServer := server.ServerFromContext(ctx)
 	var progressToken mcp.ProgressToken
@@ -146,31 +361,48 @@ func veoImageToVideoHandler(client *genai.Client, ctx context.Context, request m
 		progressToken = request.Params.Meta.ProgressToken
 	}
 
+	// Cap the whole generation, including queueing and polling, at timeout_seconds.
+	ctx, cancel := params.WithTimeout(ctx)
+	defer cancel()
+
 	select {
 	case <-ctx.Done():
-		log.Printf("Incoming i2v context for image_uri %s was already canceled: %v", imageURI, ctx.Err())
-		return mcp.NewToolResultError(fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
+		logger.Warn("Incoming request was already canceled", "image_uri", imageSource, "error", ctx.Err())
+		return codedToolResultError(errorCode(ctx.Err()), fmt.Sprintf("request processing canceled early: %v", ctx.Err())), nil
 	default:
-		log.Printf("Handling Veo i2v request: ImageURI=\"%s\", MimeType=\"%s\", Prompt=\"%s\", GCSBucket=%s, OutputDir='%s', Model=%s, NumVideos=%d, AspectRatio=%s, Duration=%ds, GenerateAudio=%t", imageURI, mimeType, prompt, gcsBucket, outputDir, modelName, numberOfVideos, finalAspectRatio, durationSecs, generateAudio)
+		logger.Info("Handling Veo i2v request", "image_uri", imageSource, "mime_type", mimeType, "prompt", prompt, "negative_prompt", negativePrompt, "gcs_bucket", params.GCSBucket, "output_dir", params.OutputDir, "num_videos", params.NumberOfVideos, "aspect_ratio", params.AspectRatio, "duration_secs", params.DurationSecs, "generate_audio", params.GenerateAudio)
 	}
 
-	inputImage := &genai.Image{
-		GCSURI:   imageURI,
-		MIMEType: mimeType,
+	if !params.DryRun {
+		if blocked := moderationBlockResult(ctx, prompt); blocked != nil {
+			return addResultWarnings(blocked, warnings), nil
+		}
+		cleanup, err := stageInputImage(ctx, inputImage, params.GCSBucket, appConfig.KeepUploadedInputs)
+		if err != nil {
+			return codedToolResultError(errorCode(err), err.Error()), nil
+		}
+		defer cleanup()
 	}
 
-	config := &genai.GenerateVideosConfig{
-		NumberOfVideos:  numberOfVideos,
-		AspectRatio:     finalAspectRatio,
-		OutputGCSURI:    gcsBucket,
-		DurationSeconds: &durationSecs,
+	config := params.GenerateVideosConfig()
+
+	if negativePrompt != "" {
+		config.NegativePrompt = negativePrompt
+	}
+	if enhancePromptSet {
+		config.EnhancePrompt = enhancePrompt
+	}
+	if len(referenceImages) > 0 {
+		config.ReferenceImages = referenceImages
 	}
 
-	if generateAudio {
-		config.GenerateAudio = &generateAudio
+	if params.DryRun {
+		result, err := dryRunResult(ctx, "i2v", prompt, inputImage, nil, config, params)
+		return addResultWarnings(addResultNote(addResultNote(result, aspectRatioNote), promptNote), warnings), err
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, prompt, inputImage, config, "i2v")
+	result, err := callGenerateVideosAPI(client, ctx, mcpServer, progressToken, params.OutputDir, params.Model, prompt, inputImage, nil, config, params.OutputObjectOptions(), params.OutputFormat, params.ForceRegenerate, "i2v")
+	return addResultWarnings(addResultNote(addResultNote(result, aspectRatioNote), promptNote), warnings), err
 }
 
 // veoInterpolationHandler is the handler for the 'veo_interpolate' tool.
@@ -178,113 +410,204 @@ func veoInterpolationHandler(client *genai.Client, ctx context.Context, request
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(ctx, "veo_interpolate")
 	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_interpolate")
 
-	// Get first frame
-	firstFrameURI, ok := request.GetArguments()["first_frame_uri"].(string)
-	if !ok || strings.TrimSpace(firstFrameURI) == "" {
-		return mcp.NewToolResultError("first_frame_uri must be a non-empty GCS URI"), nil
-	}
-	if !strings.HasPrefix(firstFrameURI, "gs://") {
-		return mcp.NewToolResultError(fmt.Sprintf("invalid first_frame_uri '%s'. Must be a GCS URI starting with 'gs://'", firstFrameURI)), nil
-	}
-	firstFrameMimeType := inferMimeTypeFromURI(firstFrameURI)
-	if mt, ok := request.GetArguments()["first_frame_mime_type"].(string); ok && strings.TrimSpace(mt) != "" {
-		firstFrameMimeType = strings.ToLower(strings.TrimSpace(mt))
-	}
-	if firstFrameMimeType == "" {
-		return mcp.NewToolResultError(fmt.Sprintf("MIME type for first_frame_uri '%s' could not be inferred. Please specify 'first_frame_mime_type'.", firstFrameURI)), nil
+	if err := checkToolParams("veo_interpolate", request.GetArguments()); err != nil {
+		return invalidArgumentResult(err.Error()), nil
 	}
 
-	// Get last frame
-	lastFrameURI, ok := request.GetArguments()["last_frame_uri"].(string)
-	if !ok || strings.TrimSpace(lastFrameURI) == "" {
-		return mcp.NewToolResultError("last_frame_uri must be a non-empty GCS URI"), nil
-	}
-	if !strings.HasPrefix(lastFrameURI, "gs://") {
-		return mcp.NewToolResultError(fmt.Sprintf("invalid last_frame_uri '%s'. Must be a GCS URI starting with 'gs://'", lastFrameURI)), nil
-	}
-	lastFrameMimeType := inferMimeTypeFromURI(lastFrameURI)
-	if mt, ok := request.GetArguments()["last_frame_mime_type"].(string); ok && strings.TrimSpace(mt) != "" {
-		lastFrameMimeType = strings.ToLower(strings.TrimSpace(mt))
+	client, err := resolveGenAIClient(ctx, client, request.GetArguments())
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+
+	// Get first and last frames. The first frame is optional: without it, the video is
+	// generated from the prompt toward the last frame.
+	firstFrameURI, _ := request.GetArguments()["first_frame_uri"].(string)
+	firstFrameMimeType, _ := request.GetArguments()["first_frame_mime_type"].(string)
+	var firstFrameImage *genai.Image
+	if strings.TrimSpace(firstFrameURI) != "" {
+		firstFrameImage, firstFrameURI, err = loadFrameImage("first_frame_uri", firstFrameURI, firstFrameMimeType)
+		if err != nil {
+			return invalidArgumentResult(err.Error()), nil
+		}
+		firstFrameMimeType = firstFrameImage.MIMEType
 	}
-	if lastFrameMimeType == "" {
-		return mcp.NewToolResultError(fmt.Sprintf("MIME type for last_frame_uri '%s' could not be inferred. Please specify 'last_frame_mime_type'.", lastFrameURI)), nil
+
+	lastFrameURI, _ := request.GetArguments()["last_frame_uri"].(string)
+	lastFrameMimeType, _ := request.GetArguments()["last_frame_mime_type"].(string)
+	lastFrameImage, lastFrameURI, err := loadFrameImage("last_frame_uri", lastFrameURI, lastFrameMimeType)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	lastFrameMimeType = lastFrameImage.MIMEType
+	var frameWarning string
+	if firstFrameImage != nil {
+		frameWarning, err = checkFrameMimeTypes(ctx, firstFrameMimeType, lastFrameMimeType, appConfig.StrictFrameMimeTypes)
+		if err != nil {
+			return invalidArgumentResult(err.Error()), nil
+		}
 	}
 
-	gcsBucket, outputDir, modelName, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, err := parseCommonVideoParams(request.GetArguments(), appConfig)
+	params, err := parseCommonVideoParams(ctx, request.GetArguments(), appConfig)
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return invalidArgumentResult(err.Error()), nil
+	}
+	ctx, logger = withLogAttrs(ctx, "model", params.Model)
+	if len(params.AspectRatios) > 1 {
+		return invalidArgumentResult("multiple aspect ratios are only supported by veo_t2v"), nil
 	}
 
-	modelInfo, ok := common.SupportedVeoModels[modelName]
+	modelInfo, ok := common.SupportedVeoModels[params.Model]
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("Model '%s' is not a supported Veo model.", modelName)), nil
+		return invalidArgumentResult(fmt.Sprintf("Model '%s' is not a supported Veo model.", params.Model)), nil
 	}
 
 	if !modelInfo.SupportsLastFrame {
-		return mcp.NewToolResultError(fmt.Sprintf("Interpolation with a last frame is not supported on model '%s'.", modelName)), nil
+		return invalidArgumentResult(fmt.Sprintf("Interpolation with a last frame is not supported on model '%s'.", params.Model)), nil
 	}
 
-	// Get reference images and check for support
-	var referenceImages []*genai.VideoGenerationReferenceImage
-	if refImagesJSON, ok := request.GetArguments()["reference_images"].(string); ok && strings.TrimSpace(refImagesJSON) != "" {
-		if !modelInfo.SupportsReferenceImages {
-			return mcp.NewToolResultError(fmt.Sprintf("Providing reference images is not supported on model '%s'.", modelName)), nil
+	if appConfig.CheckInputImages {
+		if err := checkInputImageExists(ctx, "first_frame_uri", firstFrameImage); err != nil {
+			return codedToolResultError(errorCode(err), err.Error()), nil
 		}
-
-		var refImageInputs []struct {
-			URI  string `json:"uri"`
-			Type string `json:"type"`
+		if err := checkInputImageExists(ctx, "last_frame_uri", lastFrameImage); err != nil {
+			return codedToolResultError(errorCode(err), err.Error()), nil
 		}
-
-		if err := json.Unmarshal([]byte(refImagesJSON), &refImageInputs); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse 'reference_images' JSON: %v. Please provide a valid JSON array of objects, each with 'uri' and 'type'.", err)), nil
+	}
+	if appConfig.CheckFrameDimensions && firstFrameImage != nil {
+		dimensionWarning, err := checkFrameDimensions(ctx, firstFrameImage, lastFrameImage, appConfig.StrictFrameDimensions)
+		if err != nil {
+			return invalidArgumentResult(err.Error()), nil
 		}
-
-		for _, input := range refImageInputs {
-			trimmedURI := strings.TrimSpace(input.URI)
-			if !strings.HasPrefix(trimmedURI, "gs://") {
-				log.Printf("Skipping invalid reference image URI: %v", trimmedURI)
-				continue
-			}
-			mimeType := inferMimeTypeFromURI(trimmedURI)
-			if mimeType == "" {
-				log.Printf("Skipping reference image with unknown MIME type: %s", trimmedURI)
-				continue
-			}
-
-			var refType genai.VideoGenerationReferenceType
-			switch strings.ToUpper(strings.TrimSpace(input.Type)) {
-			case "ASSET":
-				refType = genai.VideoGenerationReferenceTypeAsset
-			case "STYLE":
-				refType = genai.VideoGenerationReferenceTypeStyle
-			default:
-				log.Printf("Skipping reference image with invalid type '%s'. Must be 'ASSET' or 'STYLE'.", input.Type)
-				continue
-			}
-
-			imageForRef := &genai.Image{GCSURI: trimmedURI, MIMEType: mimeType}
-			referenceImages = append(referenceImages, &genai.VideoGenerationReferenceImage{Image: imageForRef, ReferenceType: refType})
+		if dimensionWarning != "" {
+			params.Warnings = append(params.Warnings, dimensionWarning)
 		}
 	}
 
+	referenceImages, refWarnings, err := parseReferenceImages(ctx, request.GetArguments(), params.Model)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	warnings := slices.Concat(params.Warnings, refWarnings)
+
 	prompt := ""
 	if promptArg, ok := request.GetArguments()["prompt"].(string); ok {
 		prompt = strings.TrimSpace(promptArg)
 	}
+	if firstFrameImage == nil && prompt == "" {
+		return invalidArgumentResult("prompt must be a non-empty string when first_frame_uri is not provided, to describe how the video starts"), nil
+	}
+	prompt = params.StyledPrompt(prompt)
+	promptNote := finalPromptNote(prompt, false, params.Style)
 
 	span.SetAttributes(
 		attribute.String("first_frame_uri", firstFrameURI),
+		attribute.String("first_frame_mime_type", firstFrameMimeType),
 		attribute.String("last_frame_uri", lastFrameURI),
+		attribute.String("last_frame_mime_type", lastFrameMimeType),
 		attribute.String("prompt", prompt),
-		attribute.String("gcs_bucket", gcsBucket),
-		attribute.String("output_dir", outputDir),
-		attribute.String("model", modelName),
-		attribute.String("aspect_ratio", finalAspectRatio),
-		attribute.Int("num_videos", int(numberOfVideos)),
-		attribute.Int("duration_secs", int(durationSecs)),
+		attribute.String("gcs_bucket", params.GCSBucket),
+		attribute.String("output_dir", params.OutputDir),
+		attribute.String("model", params.Model),
+		attribute.String("aspect_ratio", params.AspectRatio),
+		attribute.String("resolution", params.Resolution),
+		attribute.Int("num_videos", int(params.NumberOfVideos)),
+		attribute.Int("duration_secs", int(params.DurationSecs)),
+	)
+	if params.Seed != nil {
+		span.SetAttributes(attribute.Int("seed", int(*params.Seed)))
+	}
+	if params.PersonGeneration != "" {
+		span.SetAttributes(attribute.String("person_generation", params.PersonGeneration))
+	}
+	if params.CompressionQuality != "" {
+		span.SetAttributes(attribute.String("compression_quality", params.CompressionQuality))
+	}
+	if params.Style != "" {
+		span.SetAttributes(attribute.String("style", params.Style))
+	}
+
+	mcpServer := server.ServerFromContext(ctx)
+	var progressToken mcp.ProgressToken
+	if request.Params.Meta != nil {
+		progressToken = request.Params.Meta.ProgressToken
+	}
+
+	// Cap the whole generation, including queueing and polling, at timeout_seconds.
+	ctx, cancel := params.WithTimeout(ctx)
+	defer cancel()
+
+	logger.Info("Handling Veo interpolate request", "first_frame_uri", firstFrameURI, "last_frame_uri", lastFrameURI, "prompt", prompt, "gcs_bucket", params.GCSBucket, "output_dir", params.OutputDir, "num_videos", params.NumberOfVideos, "aspect_ratio", params.AspectRatio, "duration_secs", params.DurationSecs)
+
+	if !params.DryRun {
+		if blocked := moderationBlockResult(ctx, prompt); blocked != nil {
+			return addResultWarnings(blocked, warnings), nil
+		}
+		for _, frame := range []*genai.Image{firstFrameImage, lastFrameImage} {
+			cleanup, err := stageInputImage(ctx, frame, params.GCSBucket, appConfig.KeepUploadedInputs)
+			if err != nil {
+				return codedToolResultError(errorCode(err), err.Error()), nil
+			}
+			defer cleanup()
+		}
+	}
+
+	config := params.GenerateVideosConfig()
+	config.LastFrame = lastFrameImage
+	if len(referenceImages) > 0 {
+		config.ReferenceImages = referenceImages
+	}
+
+	if frameWarning != "" {
+		warnings = append(warnings, frameWarning)
+	}
+	if params.DryRun {
+		result, err := dryRunResult(ctx, "interpolate", prompt, firstFrameImage, nil, config, params)
+		return addResultWarnings(addResultNote(result, promptNote), warnings), err
+	}
+	result, err := callGenerateVideosAPI(client, ctx, mcpServer, progressToken, params.OutputDir, params.Model, prompt, firstFrameImage, nil, config, params.OutputObjectOptions(), params.OutputFormat, params.ForceRegenerate, "interpolate")
+	return addResultWarnings(addResultNote(result, promptNote), warnings), err
+}
+
+// veoExtendHandler is the handler for the 'veo_extend' tool. It continues an existing video
+// stored in GCS; the API returns the source video with the extension appended.
+func veoExtendHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_extend")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_extend")
+
+	if err := checkToolParams("veo_extend", request.GetArguments()); err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+
+	client, err := resolveGenAIClient(ctx, client, request.GetArguments())
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+
+	params, err := parseExtendParams(ctx, request.GetArguments(), appConfig)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	ctx, logger = withLogAttrs(ctx, "model", params.Model)
+
+	span.SetAttributes(
+		attribute.String("video_uri", params.VideoURI),
+		attribute.String("prompt", params.Prompt),
+		attribute.String("gcs_bucket", params.GCSBucket),
+		attribute.String("output_dir", params.OutputDir),
+		attribute.String("model", params.Model),
+		attribute.Int("num_videos", int(params.NumberOfVideos)),
+		attribute.Int("extension_duration_secs", int(params.DurationSecs)),
 	)
+	if params.SourceDurationSecs > 0 {
+		span.SetAttributes(attribute.Int("source_duration_secs", int(params.SourceDurationSecs)))
+	}
+	if params.Seed != nil {
+		span.SetAttributes(attribute.Int("seed", int(*params.Seed)))
+	}
 
 	mcpServer := server.ServerFromContext(ctx)
 	var progressToken mcp.ProgressToken
@@ -292,28 +615,268 @@ func veoInterpolationHandler(client *genai.Client, ctx context.Context, request
 		progressToken = request.Params.Meta.ProgressToken
 	}
 
-	firstFrameImage := &genai.Image{
-		GCSURI:   firstFrameURI,
-		MIMEType: firstFrameMimeType,
+	// Cap the whole generation, including queueing and polling, at timeout_seconds.
+	ctx, cancel := params.WithTimeout(ctx)
+	defer cancel()
+
+	logger.Info("Handling Veo extend request", "video_uri", params.VideoURI, "prompt", params.Prompt, "gcs_bucket", params.GCSBucket, "output_dir", params.OutputDir, "num_videos", params.NumberOfVideos, "extension_duration_secs", params.DurationSecs)
+	sourceVideo := &genai.Video{URI: params.VideoURI, MIMEType: params.VideoMimeType}
+	config := params.GenerateVideosConfig()
+	if params.DryRun {
+		result, err := dryRunResult(ctx, "extend", params.Prompt, nil, sourceVideo, config, params.VideoParams)
+		return addResultWarnings(result, params.Warnings), err
+	}
+
+	if blocked := moderationBlockResult(ctx, params.Prompt); blocked != nil {
+		return addResultWarnings(blocked, params.Warnings), nil
+	}
+	result, err := callGenerateVideosAPI(client, ctx, mcpServer, progressToken, params.OutputDir, params.Model, params.Prompt, nil, sourceVideo, config, params.OutputObjectOptions(), params.OutputFormat, params.ForceRegenerate, "extend")
+	return addResultWarnings(result, params.Warnings), err
+}
+
+// veoGetOperationHandler is the handler for the 'veo_get_operation' tool.
+// It fetches the current state of a previously started video generation operation,
+// returning the output GCS URIs if it has completed or its status if it is still running.
+func veoGetOperationHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_get_operation")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_get_operation")
+
+	client, err := resolveGenAIClient(ctx, client, request.GetArguments())
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+
+	operationName, ok := request.GetArguments()["operation_name"].(string)
+	if !ok || strings.TrimSpace(operationName) == "" {
+		return invalidArgumentResult("operation_name must be a non-empty string and is required"), nil
+	}
+	operationName = strings.TrimSpace(operationName)
+
+	outputDir, _ := request.GetArguments()["output_directory"].(string)
+
+	outputFormat, err := parseOutputFormat(request.GetArguments())
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+
+	span.SetAttributes(
+		attribute.String("operation_name", operationName),
+		attribute.String("output_dir", outputDir),
+	)
+
+	ctx, logger = withLogAttrs(ctx, "model", modelFromOperationName(operationName), "operation_name", operationName)
+	logger.Info("Handling Veo get operation request", "output_dir", outputDir)
+
+	var getOpOpts genai.GetOperationConfig
+	operation, err := client.Operations.GetVideosOperation(ctx, &genai.GenerateVideosOperation{Name: operationName}, &getOpOpts)
+	if err != nil {
+		logger.Error("Error fetching operation", "error", err)
+		return codedToolResultError(errorCode(err), fmt.Sprintf("error fetching operation '%s': %v", operationName, err)), nil
+	}
+
+	if !operation.Done {
+		statusText := fmt.Sprintf("Video generation operation %s is still running.", operationName)
+		if operation.Metadata != nil {
+			if p, ok := operation.Metadata["progressPercent"].(float64); ok {
+				statusText = fmt.Sprintf("Video generation operation %s is still running (%d%% complete).", operationName, int(p))
+			}
+		}
+		return videoToolResult(outputFormat, statusText+" Call veo_get_operation again later to retrieve the result.", videoResult{Status: "running", OperationName: operationName, Model: modelFromOperationName(operationName)}, nil)
+	}
+
+	if operation.Error != nil {
+		errMessage, errCode := operationErrorDetails(operation.Error)
+		logger.Error("Operation failed", "error", errMessage, "code", errCode, "full_error", operation.Error)
+		return codedToolResultError(operationErrorCode(errCode), fmt.Sprintf("video generation operation %s failed: %s (code: %d)", operationName, errMessage, errCode)), nil
+	}
+
+	modelName := modelFromOperationName(operationName)
+	outputs := collectGeneratedVideos(ctx, operation, outputDir, "", "", modelName)
+	summary := videoResult{Status: "completed", OperationName: operationName, Model: modelName, Filtered: filteredVideosFromResponse(operation.Response), Errors: outputs.Errors}
+	if enhancedPrompt := enhancedPromptFromOperation(operation); enhancedPrompt != "" {
+		span.SetAttributes(attribute.String("enhanced_prompt", enhancedPrompt))
+		summary.EnhancedPrompt = enhancedPrompt
+	}
+	if outputs.Count() == 0 {
+		resultText := fmt.Sprintf("Video generation operation %s (model %s) completed, but no videos were found.", operationName, modelName)
+		if message := filteredVideosMessage(summary.Filtered); message != "" {
+			resultText += " " + message
+		}
+		return videoToolResult(outputFormat, resultText, summary, outputs)
+	}
+
+	summary.Watermarked = true
+	resultText := fmt.Sprintf("Video generation operation %s (model %s) completed with %d video(s).", operationName, modelName, outputs.Count())
+	if len(outputs.GCSURIs) > 0 {
+		resultText += fmt.Sprintf(" Videos saved to GCS: %s.", strings.Join(outputs.GCSURIs, ", "))
+	}
+	if len(outputs.LocalFiles) > 0 {
+		resultText += fmt.Sprintf(" Successfully saved locally to '%s': %s.", outputDir, strings.Join(outputs.LocalFiles, ", "))
+	}
+	if len(outputs.Errors) > 0 {
+		resultText += fmt.Sprintf(" Local download/save issues: %s.", strings.Join(outputs.Errors, "; "))
+	}
+	if len(outputs.InlineVideos) > 0 {
+		resultText += fmt.Sprintf(" %d video(s) are returned inline as base64-encoded data.", len(outputs.InlineVideos))
+	}
+	if message := filteredVideosMessage(summary.Filtered); message != "" {
+		resultText += " " + message
+	}
+	resultText += " " + synthIDVideoNote
+	if summary.EnhancedPrompt != "" {
+		resultText += fmt.Sprintf(" Enhanced prompt used by Veo: %q.", summary.EnhancedPrompt)
+	}
+	return videoToolResult(outputFormat, resultText, summary, outputs)
+}
+
+// listVeoModelsHandler is the handler for the 'list_veo_models' tool.
+// It returns the supported Veo models and their constraints as a JSON array,
+// sorted by canonical model name.
+func listVeoModelsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	_, logger := startRequestLogger(ctx, "list_veo_models")
+	logger.Info("Handling list_veo_models request")
+
+	names := make([]string, 0, len(common.SupportedVeoModels))
+	for name := range common.SupportedVeoModels {
+		names = append(names, name)
 	}
+	slices.Sort(names)
 
-	lastFrameImage := &genai.Image{
-		GCSURI:   lastFrameURI,
-		MIMEType: lastFrameMimeType,
+	models := make([]common.VeoModelInfo, 0, len(names))
+	for _, name := range names {
+		models = append(models, common.SupportedVeoModels[name])
 	}
 
-	config := &genai.GenerateVideosConfig{
-		NumberOfVideos:  numberOfVideos,
-		AspectRatio:     finalAspectRatio,
-		OutputGCSURI:    gcsBucket,
-		DurationSeconds: &durationSecs,
-		LastFrame:       lastFrameImage,
-		ReferenceImages: referenceImages,
+	modelListJSON, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal model list: %v", err)), nil
 	}
 
-	if generateAudio {
-		config.GenerateAudio = &generateAudio
+	summary := fmt.Sprintf("Found %d supported Veo models.", len(models))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: summary},
+			mcp.TextContent{Type: "text", Text: string(modelListJSON)},
+		},
+	}, nil
+}
+
+// veoModelCapabilitiesHandler is the handler for the 'veo_model_capabilities' tool. It resolves
+// the 'model' argument with ResolveVeoModel and returns that model's constraints and supported
+// features as a JSON object, in the same form as the entries of list_veo_models. Unknown models
+// are reported as NOT_FOUND, with suggestions when a close match exists.
+func veoModelCapabilitiesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	_, logger := startRequestLogger(ctx, "veo_model_capabilities")
+
+	modelInput, _ := request.GetArguments()["model"].(string)
+	modelInput = strings.TrimSpace(modelInput)
+	if modelInput == "" {
+		return invalidArgumentResult("model must be a non-empty model name or alias"), nil
+	}
+	logger.Info("Handling veo_model_capabilities request", "model", modelInput)
+
+	canonicalName, found := common.ResolveVeoModel(modelInput)
+	if !found {
+		message := fmt.Sprintf("model '%s' is not a supported Veo model", modelInput)
+		if suggestions := common.SuggestVeoModels(modelInput, maxModelSuggestions); len(suggestions) > 0 {
+			message += fmt.Sprintf(". Did you mean %s?", strings.Join(suggestions, ", "))
+		}
+		return codedToolResultError(codeNotFound, message), nil
 	}
 
-	return callGenerateVideosAPI(client, ctx, mcpServer, progressToken, outputDir, modelName, prompt, firstFrameImage, config, "interpolate")
+	modelJSON, err := json.MarshalIndent(common.SupportedVeoModels[canonicalName], "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal model capabilities: %v", err)), nil
+	}
+
+	summary := fmt.Sprintf("Capabilities of model %s.", canonicalName)
+	if !strings.EqualFold(modelInput, canonicalName) {
+		summary = fmt.Sprintf("Capabilities of model %s (resolved from '%s').", canonicalName, modelInput)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: summary},
+			mcp.TextContent{Type: "text", Text: string(modelJSON)},
+		},
+	}, nil
+}
+
+// CostEstimate is the result of the veo_estimate_cost tool.
+type CostEstimate struct {
+	Model            string `json:"model"`
+	Resolution       string `json:"resolution"`
+	NumberOfVideos   int32  `json:"num_videos"`
+	DurationSecs     int32  `json:"duration"`
+	GeneratedSeconds int32  `json:"generated_seconds"`
+	// PricePerSecond and EstimatedCost are omitted when no price is known for the model.
+	PricePerSecond *float64 `json:"price_per_second,omitempty"`
+	EstimatedCost  *float64 `json:"estimated_cost,omitempty"`
+}
+
+// veoEstimateCostHandler estimates the number of generated seconds and the rough cost of a
+// Veo request. The model, duration, number of videos, and resolution are resolved and validated
+// the same way as for the generation tools, so the estimate reflects what would actually run.
+func veoEstimateCostHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, logger := startRequestLogger(ctx, "veo_estimate_cost")
+	args := request.GetArguments()
+
+	model, modelDetails, err := resolveModelArg(ctx, args)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	numberOfVideos, err := resolveNumberOfVideos(ctx, args, model, modelDetails, appConfig.ClampNumVideos)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	durationSecs, err := resolveDuration(ctx, args, model, modelDetails)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	resolution, err := resolveResolution(args, model, modelDetails)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+
+	logger.Info("Handling veo_estimate_cost request", "model", model, "num_videos", numberOfVideos, "duration_secs", durationSecs, "resolution", resolution)
+
+	estimate := CostEstimate{
+		Model:            model,
+		Resolution:       resolution,
+		NumberOfVideos:   numberOfVideos,
+		DurationSecs:     durationSecs,
+		GeneratedSeconds: numberOfVideos * durationSecs,
+	}
+
+	summary := fmt.Sprintf("Estimated %d generated second(s) (%d video(s) x %ds) with model %s at %s.", estimate.GeneratedSeconds, numberOfVideos, durationSecs, model, resolution)
+	if price, ok := veoPricePerSecond(model, resolution); ok {
+		cost := price * float64(estimate.GeneratedSeconds)
+		estimate.PricePerSecond = &price
+		estimate.EstimatedCost = &cost
+		summary += fmt.Sprintf(" Rough cost: %s (at %s per second).", common.FormatPrice(cost), common.FormatPrice(price))
+	} else {
+		summary += " No price is known for this model; set GENMEDIA_VEO_PRICE_PER_SECOND to include a cost estimate."
+	}
+
+	estimateJSON, err := json.MarshalIndent(estimate, "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal cost estimate: %v", err)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: summary},
+			mcp.TextContent{Type: "text", Text: string(estimateJSON)},
+		},
+	}, nil
+}
+
+// veoPricePerSecond looks up the price per generated second for a model in its model info,
+// preferring a resolution-specific price. Prices default to the list prices in
+// common.SupportedVeoModels and are overridden at startup by GENMEDIA_VEO_PRICE_PER_SECOND, so
+// the estimate matches the price shown in the model descriptions.
+func veoPricePerSecond(model, resolution string) (float64, bool) {
+	return common.SupportedVeoModels[model].PriceFor(resolution)
 }
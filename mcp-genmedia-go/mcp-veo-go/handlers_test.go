@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+func TestVeoEstimateCostHandler(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+	defer func(models map[string]common.VeoModelInfo) { common.SupportedVeoModels = models }(maps.Clone(common.SupportedVeoModels))
+	common.ApplyModelPrices(map[string]float64{
+		"veo-3.0-fast-generate-001":       0.1,
+		"veo-3.0-fast-generate-001:1080p": 0.2,
+	}, nil)
+
+	testCases := []struct {
+		name        string
+		args        map[string]interface{}
+		expected    string
+		expectError bool
+	}{
+		{"model price", map[string]interface{}{"model": "Veo 3 Fast", "num_videos": float64(2), "duration": float64(4)}, "Rough cost: $0.80 (at $0.10 per second)", false},
+		{"resolution price", map[string]interface{}{"model": "Veo 3 Fast", "resolution": "1080p"}, "Rough cost: $1.60 (at $0.20 per second)", false},
+		{"default price", map[string]interface{}{"model": "veo-2.0-generate-001"}, "Rough cost: $4.00 (at $0.50 per second)", false},
+		{"no price", map[string]interface{}{"model": "veo-2.0-generate-exp"}, "No price is known", false},
+		{"invalid duration", map[string]interface{}{"model": "Veo 3 Fast", "duration": float64(5)}, "not supported", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tc.args
+			result, err := veoEstimateCostHandler(t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsError != tc.expectError {
+				t.Fatalf("expected IsError %v, but got %v", tc.expectError, result.IsError)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.Contains(text, tc.expected) {
+				t.Errorf("expected result containing '%s', but got '%s'", tc.expected, text)
+			}
+			if tc.expectError {
+				return
+			}
+			var estimate map[string]interface{}
+			if err := json.Unmarshal([]byte(result.Content[1].(mcp.TextContent).Text), &estimate); err != nil {
+				t.Fatalf("failed to parse the estimate: %v", err)
+			}
+			if _, ok := estimate["num_videos"]; !ok || estimate["model"] == nil {
+				t.Errorf("expected snake_case fields such as 'model' and 'num_videos', but got %v", estimate)
+			}
+			if _, ok := estimate["estimated_cost"]; ok != !strings.Contains(text, "No price is known") {
+				t.Errorf("expected 'estimated_cost' only when a price is known, but got %v", estimate)
+			}
+		})
+	}
+}
+
+func TestGenerationHandlersRejectForeignParams(t *testing.T) {
+	testCases := []struct {
+		name     string
+		handler  func(*genai.Client, context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		args     map[string]interface{}
+		expected string
+	}{
+		{"last frame on t2v", veoTextToVideoHandler, map[string]interface{}{"prompt": "a cat", "last_frame_uri": "gs://bucket/last.png"}, "'last_frame_uri' is only accepted by veo_interpolate"},
+		{"image on interpolate", veoInterpolationHandler, map[string]interface{}{"first_frame_uri": "gs://bucket/first.png", "last_frame_uri": "gs://bucket/last.png", "image_uri": "gs://bucket/image.png"}, "'image_uri' is only accepted by veo_i2v"},
+		{"aspect ratio on extend", veoExtendHandler, map[string]interface{}{"video_uri": "gs://bucket/video.mp4", "aspect_ratio": "9:16"}, "extensions keep the aspect ratio of the source video"},
+		{"prompt on batch", veoBatchTextToVideoHandler, map[string]interface{}{"prompts": []interface{}{"a cat"}, "prompt": "a dog"}, "use 'prompts'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tc.args
+			result, err := tc.handler(nil, t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatal("expected an error result")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.HasPrefix(text, "[code=INVALID_ARGUMENT]") || !strings.Contains(text, tc.expected) {
+				t.Errorf("expected an INVALID_ARGUMENT result containing '%s', but got '%s'", tc.expected, text)
+			}
+		})
+	}
+}
+
+func TestVeoInterpolationHandlerLastFrameOnly(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	testCases := []struct {
+		name          string
+		args          map[string]interface{}
+		expectedError string
+	}{
+		{"last frame only", map[string]interface{}{"last_frame_uri": "gs://bucket/last.png", "prompt": "a sunrise over the sea"}, ""},
+		{"both frames", map[string]interface{}{"first_frame_uri": "gs://bucket/first.png", "last_frame_uri": "gs://bucket/last.png"}, ""},
+		{"no prompt", map[string]interface{}{"last_frame_uri": "gs://bucket/last.png"}, "prompt must be a non-empty string"},
+		{"no last frame", map[string]interface{}{"first_frame_uri": "gs://bucket/first.png", "prompt": "a sunrise"}, "last_frame_uri must be a non-empty"},
+		{"model without last frames", map[string]interface{}{"last_frame_uri": "gs://bucket/last.png", "prompt": "a sunrise", "model": "veo-2.0-generate-001"}, "not supported on model"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"model": "veo-3.1-generate-preview", "generate_audio": false, "dry_run": true, "output_format": "json"}
+			maps.Copy(args, tc.args)
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = args
+			result, err := veoInterpolationHandler(nil, t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if tc.expectedError != "" {
+				if !result.IsError || !strings.Contains(text, tc.expectedError) {
+					t.Errorf("expected an error containing '%s', but got '%s'", tc.expectedError, text)
+				}
+				return
+			}
+			if result.IsError {
+				t.Fatalf("unexpected error result: %s", text)
+			}
+			var resolved dryRunConfig
+			if err := json.Unmarshal([]byte(text), &resolved); err != nil {
+				t.Fatalf("failed to parse the dry run result: %v", err)
+			}
+			if !strings.HasPrefix(resolved.LastFrame, "gs://bucket/last.png") {
+				t.Errorf("expected the last frame to be sent, but got '%s'", resolved.LastFrame)
+			}
+			_, hasFirstFrame := tc.args["first_frame_uri"]
+			if (resolved.InputImage != "") != hasFirstFrame {
+				t.Errorf("expected a first frame: %v, but got '%s'", hasFirstFrame, resolved.InputImage)
+			}
+		})
+	}
+}
+
+func TestVeoModelCapabilitiesHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		model        interface{}
+		expectedCode string
+		expectedName string
+	}{
+		{"canonical name", "veo-3.1-generate-preview", "", "veo-3.1-generate-preview"},
+		{"alias", "veo 3 fast", "", "veo-3.0-fast-generate-001"},
+		{"unknown model", "sora", "NOT_FOUND", ""},
+		{"missing model", nil, "INVALID_ARGUMENT", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{"model": tc.model}
+			result, err := veoModelCapabilitiesHandler(t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectedCode != "" {
+				text := result.Content[0].(mcp.TextContent).Text
+				if !result.IsError || !strings.HasPrefix(text, "[code="+tc.expectedCode+"]") {
+					t.Errorf("expected a %s error, but got '%s'", tc.expectedCode, text)
+				}
+				return
+			}
+			if result.IsError {
+				t.Fatalf("unexpected error result: %v", result.Content)
+			}
+			var info common.VeoModelInfo
+			if err := json.Unmarshal([]byte(result.Content[1].(mcp.TextContent).Text), &info); err != nil {
+				t.Fatalf("failed to decode the capabilities: %v", err)
+			}
+			if info.CanonicalName != tc.expectedName || info.MaxVideos == 0 || len(info.SupportedAspectRatios) == 0 {
+				t.Errorf("unexpected capabilities for %s: %+v", tc.expectedName, info)
+			}
+		})
+	}
+}
+
+func TestVeoExtendHandlerDryRun(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"video_uri":     "gs://bucket/video.mp4",
+		"model":         "veo-3.1-generate-preview",
+		"prompt":        "the camera pulls back",
+		"dry_run":       true,
+		"output_format": "json",
+	}
+	result, err := veoExtendHandler(nil, t.Context(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", text)
+	}
+	var resolved dryRunConfig
+	if err := json.Unmarshal([]byte(text), &resolved); err != nil {
+		t.Fatalf("failed to parse the dry run result: %v", err)
+	}
+	if resolved.Status != "dry_run" || resolved.CallType != "extend" {
+		t.Errorf("expected an extend dry run, but got status '%s' and call type '%s'", resolved.Status, resolved.CallType)
+	}
+	if !strings.HasPrefix(resolved.SourceVideo, "gs://bucket/video.mp4") {
+		t.Errorf("expected the source video to be reported, but got '%s'", resolved.SourceVideo)
+	}
+}
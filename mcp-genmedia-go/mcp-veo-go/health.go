@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genai"
+)
+
+// healthCheckTimeout bounds the Vertex AI call made by veo_health, so that a probe never
+// hangs on an unreachable backend.
+const healthCheckTimeout = 15 * time.Second
+
+// healthResult is the machine-readable result of the veo_health tool.
+type healthResult struct {
+	Status    string `json:"status"`
+	Project   string `json:"project"`
+	Location  string `json:"location"`
+	Model     string `json:"model"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode int    `json:"error_code,omitempty"`
+}
+
+// describeHealthError turns a failed health check call into an actionable message, calling
+// out authentication and permission problems explicitly.
+func describeHealthError(err error, project, location, model string) (string, int) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("timed out after %s waiting for Vertex AI in %s", healthCheckTimeout, location), 0
+	}
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Sprintf("could not reach Vertex AI: %v", err), 0
+	}
+	switch apiErr.Code {
+	case 401:
+		return fmt.Sprintf("authentication failed: the server's credentials were rejected (%s). Check Application Default Credentials or the service account in use", apiErr.Message), apiErr.Code
+	case 403:
+		return fmt.Sprintf("permission denied in project %s (%s). Make sure the Vertex AI API (aiplatform.googleapis.com) is enabled and the credentials have a role such as roles/aiplatform.user", project, apiErr.Message), apiErr.Code
+	case 404:
+		return fmt.Sprintf("model %s was not found in location %s (%s)", model, location, apiErr.Message), apiErr.Code
+	default:
+		return fmt.Sprintf("Vertex AI returned an error: %s (code: %d)", apiErr.Message, apiErr.Code), apiErr.Code
+	}
+}
+
+// veoHealthHandler is the handler for the 'veo_health' tool. It checks that the server can
+// reach Vertex AI with its configured credentials by fetching the metadata of a Veo model,
+// which is authenticated but does not use any generation quota. It reports OK or the
+// failure, along with the latency of the call.
+func veoHealthHandler(client *genai.Client, ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_health")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_health")
+
+	args := request.GetArguments()
+	model, _, err := resolveModelArg(ctx, args)
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	client, err = resolveGenAIClient(ctx, client, args)
+	if err != nil {
+		return codedToolResultError(errorCode(err), err.Error()), nil
+	}
+	project, _ := args["project"].(string)
+	if project = strings.TrimSpace(project); project == "" {
+		project = appConfig.ProjectID
+	}
+	location, _ := args["location"].(string)
+	if location = strings.TrimSpace(location); location == "" {
+		location = appConfig.Location
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Models.Get(checkCtx, model, nil)
+	latency := time.Since(start)
+
+	result := healthResult{
+		Status:    "ok",
+		Project:   project,
+		Location:  location,
+		Model:     model,
+		LatencyMS: latency.Milliseconds(),
+	}
+	summary := fmt.Sprintf("OK: reached Vertex AI in project %s (%s) in %dms; model %s is available.", project, location, result.LatencyMS, model)
+	if err != nil {
+		result.Status = "error"
+		result.Error, result.ErrorCode = describeHealthError(err, project, location, model)
+		summary = fmt.Sprintf("FAILED: %s (after %dms).", result.Error, result.LatencyMS)
+		logger.Error("Health check failed", "model", model, "error", err)
+	}
+	span.SetAttributes(
+		attribute.String("status", result.Status),
+		attribute.Int64("latency_ms", result.LatencyMS),
+	)
+
+	resultJSON, jsonErr := json.MarshalIndent(result, "", "  ")
+	if jsonErr != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal health result: %v", jsonErr)), nil
+	}
+	return &mcp.CallToolResult{
+		IsError: err != nil,
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: summary},
+			mcp.TextContent{Type: "text", Text: string(resultJSON)},
+		},
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+func TestDescribeHealthError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expected     string
+		expectedCode int
+	}{
+		{"unauthenticated", genai.APIError{Code: 401, Message: "invalid credentials"}, "authentication failed", 401},
+		{"permission denied", genai.APIError{Code: 403, Message: "denied"}, "permission denied in project my-project", 403},
+		{"model not found", genai.APIError{Code: 404, Message: "not found"}, "model veo-2.0-generate-001 was not found in location us-central1", 404},
+		{"server error", genai.APIError{Code: 500, Message: "internal"}, "Vertex AI returned an error: internal (code: 500)", 500},
+		{"timeout", fmt.Errorf("get model: %w", context.DeadlineExceeded), "timed out after", 0},
+		{"network", errors.New("dial tcp: no route to host"), "could not reach Vertex AI", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message, code := describeHealthError(tc.err, "my-project", "us-central1", "veo-2.0-generate-001")
+			if !strings.Contains(message, tc.expected) {
+				t.Errorf("expected message containing '%s', but got '%s'", tc.expected, message)
+			}
+			if code != tc.expectedCode {
+				t.Errorf("expected code %d, but got %d", tc.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestVeoHealthHandlerInvalidModel(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"model": "not-a-model"}
+	result, err := veoHealthHandler(nil, t.Context(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.HasPrefix(text, "[code=INVALID_ARGUMENT]") {
+		t.Errorf("expected an INVALID_ARGUMENT result, but got '%s'", text)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxIdempotencyKeyLength is the longest accepted idempotency_key.
+const maxIdempotencyKeyLength = 256
+
+// errIdempotencyKeyReused is returned for an idempotency_key that was already used with
+// different arguments.
+var errIdempotencyKeyReused = errors.New("idempotency_key was already used with different arguments")
+
+// idempotentRequest tracks a request made with an idempotency key. done is closed once the
+// request finishes, after which result and err are set.
+type idempotentRequest struct {
+	fingerprint string
+	done        chan struct{}
+	result      *mcp.CallToolResult
+	err         error
+	completedAt time.Time
+}
+
+// succeeded reports whether a finished request produced a result worth returning again.
+func (r *idempotentRequest) succeeded() bool {
+	return r.err == nil && r.result != nil && !r.result.IsError
+}
+
+// idempotencyStore is a concurrency-safe, in-memory record of the requests made with an
+// idempotency key. Successful requests are kept for the TTL after they finish; failed ones
+// are forgotten so that they can be retried.
+type idempotencyStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	requests map[string]*idempotentRequest
+}
+
+// newIdempotencyStore returns a store that keeps completed requests for ttl.
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{ttl: ttl, requests: make(map[string]*idempotentRequest)}
+}
+
+// idempotencyKeys records the requests made with an idempotency_key. It is set up from
+// appConfig.IdempotencyTTL in main and is nil (keys are ignored) until then, e.g., in tests.
+var idempotencyKeys *idempotencyStore
+
+// Begin returns the request recorded under key, which is either in flight or completed within
+// the TTL, or records a new one. If owner is true the caller must run the request and then call
+// Finish. A key recorded with a different fingerprint is an error.
+func (s *idempotencyStore) Begin(key, fingerprint string, now time.Time) (request *idempotentRequest, owner bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, r := range s.requests {
+		if !r.completedAt.IsZero() && now.Sub(r.completedAt) > s.ttl {
+			delete(s.requests, k)
+		}
+	}
+	if existing, ok := s.requests[key]; ok {
+		if existing.fingerprint != fingerprint {
+			return nil, false, errIdempotencyKeyReused
+		}
+		return existing, false, nil
+	}
+	request = &idempotentRequest{fingerprint: fingerprint, done: make(chan struct{})}
+	s.requests[key] = request
+	return request, true, nil
+}
+
+// Finish records the outcome of a request returned by Begin and wakes the requests waiting
+// for it. Failed requests are forgotten so that the key can be used again.
+func (s *idempotencyStore) Finish(key string, request *idempotentRequest, result *mcp.CallToolResult, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request.result, request.err, request.completedAt = result, err, now
+	if !request.succeeded() && s.requests[key] == request {
+		delete(s.requests, key)
+	}
+	close(request.done)
+}
+
+// requestFingerprint returns a hash of a request's arguments other than idempotency_key, used
+// to detect a key reused for a different request.
+func requestFingerprint(args map[string]interface{}) (string, error) {
+	args = maps.Clone(args)
+	delete(args, "idempotency_key")
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the request arguments: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayedResult returns a copy of the result of an earlier request with a note saying that
+// it was returned again rather than generated.
+func replayedResult(result *mcp.CallToolResult, key string) *mcp.CallToolResult {
+	replayed := *result
+	replayed.Content = append([]mcp.Content(nil), result.Content...)
+	return addResultNote(&replayed, fmt.Sprintf("Returned the result of an earlier request with idempotency_key '%s'; no new generation was started.", key))
+}
+
+// runIdempotentRequest runs the handler of the request that owns an idempotency key and records
+// its outcome with Finish. A panic in the handler is recorded as an error, which forgets the key
+// and releases the requests waiting for it, and is then re-raised.
+func runIdempotentRequest(ctx context.Context, request mcp.CallToolRequest, handler server.ToolHandlerFunc, storeKey string, recorded *idempotentRequest) (result *mcp.CallToolResult, err error) {
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		r := recover()
+		idempotencyKeys.Finish(storeKey, recorded, nil, fmt.Errorf("the request did not complete: %v", r), time.Now())
+		if r != nil {
+			panic(r)
+		}
+	}()
+	result, err = handler(ctx, request)
+	finished = true
+	idempotencyKeys.Finish(storeKey, recorded, result, err, time.Now())
+	return result, err
+}
+
+// withIdempotency wraps the handler of a generation tool so that requests carrying the same
+// 'idempotency_key' run once: a repeated request waits for the one in flight, or returns the
+// result of one that completed within GENMEDIA_IDEMPOTENCY_TTL. Keys are scoped to the tool.
+// Requests without a key, or made before the store is set up, run as usual.
+func withIdempotency(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		keyArg, _ := request.GetArguments()["idempotency_key"].(string)
+		key := strings.TrimSpace(keyArg)
+		if key == "" || idempotencyKeys == nil {
+			return handler(ctx, request)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return invalidArgumentResult(fmt.Sprintf("idempotency_key must be at most %d characters long", maxIdempotencyKeyLength)), nil
+		}
+		fingerprint, err := requestFingerprint(request.GetArguments())
+		if err != nil {
+			return invalidArgumentResult(err.Error()), nil
+		}
+
+		logger := loggerFromContext(ctx)
+		storeKey := tool + "\x00" + key
+		for {
+			recorded, owner, err := idempotencyKeys.Begin(storeKey, fingerprint, time.Now())
+			if err != nil {
+				return invalidArgumentResult(fmt.Sprintf("%v: '%s'. Use a new key for a different request.", err, key)), nil
+			}
+			if owner {
+				return runIdempotentRequest(ctx, request, handler, storeKey, recorded)
+			}
+
+			logger.Info("Waiting for the request with the same idempotency_key", "tool", tool, "idempotency_key", key)
+			select {
+			case <-recorded.done:
+			case <-ctx.Done():
+				return codedToolResultError(errorCode(ctx.Err()), fmt.Sprintf("canceled while waiting for the request with idempotency_key '%s': %v", key, ctx.Err())), nil
+			}
+			if recorded.succeeded() {
+				logger.Info("Returning the result of the request with the same idempotency_key", "tool", tool, "idempotency_key", key)
+				return replayedResult(recorded.result, key), nil
+			}
+			// The earlier request failed and was forgotten, so run this one instead.
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	now := time.Now()
+
+	request, owner, err := store.Begin("key", "a", now)
+	if err != nil || !owner {
+		t.Fatalf("expected to own a new key, but got owner %v and error %v", owner, err)
+	}
+	store.Finish("key", request, mcp.NewToolResultText("done"), nil, now)
+
+	if existing, owner, err := store.Begin("key", "a", now.Add(30*time.Minute)); err != nil || owner || existing != request {
+		t.Errorf("expected the completed request within the TTL, but got owner %v and error %v", owner, err)
+	}
+	if _, _, err := store.Begin("key", "b", now.Add(30*time.Minute)); err != errIdempotencyKeyReused {
+		t.Errorf("expected a reused key error for different arguments, but got %v", err)
+	}
+	if _, owner, err := store.Begin("key", "b", now.Add(2*time.Hour)); err != nil || !owner {
+		t.Errorf("expected an expired key to be usable again, but got owner %v and error %v", owner, err)
+	}
+}
+
+func TestIdempotencyStoreForgetsFailures(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	now := time.Now()
+
+	request, _, _ := store.Begin("key", "a", now)
+	store.Finish("key", request, mcp.NewToolResultError("failed"), nil, now)
+	if _, owner, err := store.Begin("key", "a", now); err != nil || !owner {
+		t.Errorf("expected a failed request to be retried, but got owner %v and error %v", owner, err)
+	}
+}
+
+func TestWithIdempotency(t *testing.T) {
+	idempotencyKeys = newIdempotencyStore(time.Hour)
+	defer func() { idempotencyKeys = nil }()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := withIdempotency("veo_t2v", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls.Add(1)
+		<-release
+		return mcp.NewToolResultText("generated"), nil
+	})
+	newRequest := func(args map[string]interface{}) mcp.CallToolRequest {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		return request
+	}
+	args := map[string]interface{}{"prompt": "a cat", "idempotency_key": "retry-1"}
+
+	var wg sync.WaitGroup
+	results := make([]*mcp.CallToolResult, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = handler(context.Background(), newRequest(args))
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected concurrent requests with the same key to run once, but the handler ran %d times", calls.Load())
+	}
+	replayed := 0
+	for _, result := range results {
+		if result == nil || result.IsError || result.Content[0].(mcp.TextContent).Text != "generated" {
+			t.Fatalf("expected every request to get the generated result, but got %+v", result)
+		}
+		if len(result.Content) > 1 && strings.Contains(result.Content[1].(mcp.TextContent).Text, "idempotency_key 'retry-1'") {
+			replayed++
+		}
+	}
+	if replayed != 2 {
+		t.Errorf("expected 2 results to be marked as replayed, but got %d", replayed)
+	}
+
+	if result, _ := handler(context.Background(), newRequest(args)); calls.Load() != 1 || len(result.Content) != 2 {
+		t.Errorf("expected a later retry to return the stored result, but the handler ran %d times", calls.Load())
+	}
+
+	conflicting := map[string]interface{}{"prompt": "a dog", "idempotency_key": "retry-1"}
+	if result, _ := handler(context.Background(), newRequest(conflicting)); !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "different arguments") {
+		t.Errorf("expected a reused key with different arguments to be rejected, but got %+v", result)
+	}
+
+	handler(context.Background(), newRequest(map[string]interface{}{"prompt": "a cat"}))
+	if calls.Load() != 2 {
+		t.Errorf("expected a request without a key to run, but the handler ran %d times", calls.Load())
+	}
+}
+
+func TestWithIdempotencyPanic(t *testing.T) {
+	idempotencyKeys = newIdempotencyStore(time.Hour)
+	defer func() { idempotencyKeys = nil }()
+
+	handler := withIdempotency("veo_t2v", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		panic("boom")
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"prompt": "a cat", "idempotency_key": "retry-1"}
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("expected the panic to be re-raised, but got %v", r)
+			}
+		}()
+		handler(context.Background(), request)
+	}()
+
+	fingerprint, err := requestFingerprint(request.GetArguments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, owner, err := idempotencyKeys.Begin("veo_t2v\x00retry-1", fingerprint, time.Now()); err != nil || !owner {
+		t.Errorf("expected the key of a panicked request to be released, but got owner %v and error %v", owner, err)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+// inputsPrefix is the folder, below the GCS output location, that staged input images are
+// uploaded to.
+const inputsPrefix = "inputs/"
+
+// stagedInputURI returns the GCS URI a local input image is uploaded to:
+// <gcsOutput>/inputs/<content hash><suffix>.<ext>. The content hash lets kept inputs be
+// reused by later requests for the same image; a non-empty suffix makes the name unique
+// so that an input that will be deleted is never shared with another request.
+func stagedInputURI(gcsOutput string, data []byte, mimeType, suffix string) (string, error) {
+	gcsOutput = common.EnsureGCSPathPrefix(strings.TrimSpace(gcsOutput))
+	if bucket := strings.TrimPrefix(gcsOutput, "gs://"); bucket == "" || strings.HasPrefix(bucket, "/") {
+		return "", fmt.Errorf("invalid GCS output location '%s': missing bucket name", gcsOutput)
+	}
+	if !strings.HasSuffix(gcsOutput, "/") {
+		gcsOutput += "/"
+	}
+
+	ext := ".png"
+	if mimeType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	sum := sha256.Sum256(data)
+	return gcsOutput + inputsPrefix + hex.EncodeToString(sum[:8]) + suffix + ext, nil
+}
+
+// stageInputImage uploads an input image given as bytes (a local file or inline data) to
+// the GCS output location and points the image at the uploaded object instead. Images that
+// already reference GCS are left untouched. If keep is true the object is named by content
+// hash and reused when it already exists; otherwise it gets a unique name and the returned
+// cleanup function deletes it. The cleanup function is never nil.
+func stageInputImage(ctx context.Context, image *genai.Image, gcsOutput string, keep bool) (func(), error) {
+	noop := func() {}
+	if image == nil || len(image.ImageBytes) == 0 || gcsOutput == "" {
+		return noop, nil
+	}
+
+	suffix := ""
+	if !keep {
+		suffix = fmt.Sprintf("-%d", time.Now().UnixNano())
+	}
+	gcsURI, err := stagedInputURI(gcsOutput, image.ImageBytes, image.MIMEType, suffix)
+	if err != nil {
+		return noop, err
+	}
+
+	logger := loggerFromContext(ctx)
+	if keep {
+		if exists, err := common.GCSObjectExists(ctx, gcsURI); err != nil {
+			logger.Warn("Could not check for an existing staged input, uploading it again", "gcs_uri", gcsURI, "error", err)
+		} else if exists {
+			logger.Info("Reusing staged input image", "gcs_uri", gcsURI)
+			image.GCSURI, image.ImageBytes = gcsURI, nil
+			return noop, nil
+		}
+	}
+
+	bucketName, objectName, err := common.ParseGCSPath(gcsURI)
+	if err != nil {
+		return noop, err
+	}
+	if err := common.UploadToGCS(ctx, bucketName, objectName, image.MIMEType, image.ImageBytes); err != nil {
+		return noop, fmt.Errorf("failed to upload input image to %s: %w", gcsURI, err)
+	}
+	logger.Info("Uploaded input image", "size", common.FormatBytes(int64(len(image.ImageBytes))), "gcs_uri", gcsURI)
+	image.GCSURI, image.ImageBytes = gcsURI, nil
+
+	if keep {
+		return noop, nil
+	}
+	return func() {
+		// The request context may already be done, so clean up with a fresh one.
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := common.DeleteGCSObject(cleanupCtx, gcsURI); err != nil {
+			logger.Error("Failed to delete staged input image", "gcs_uri", gcsURI, "error", err)
+		}
+	}, nil
+}
+
+// maxInputImageBytes returns the largest input image accepted as a local file or inline data,
+// from GENMEDIA_MAX_INPUT_IMAGE_BYTES.
+func maxInputImageBytes() int64 {
+	if appConfig == nil {
+		return common.DefaultMaxInputImageBytes
+	}
+	return int64(appConfig.MaxInputImageBytes)
+}
+
+// checkInputImageSize rejects an input image of the given size that is larger than
+// maxInputImageBytes, so that it fails with a clear error instead of at the API. source
+// describes the image in the error message.
+func checkInputImageSize(source string, size int64) error {
+	if limit := maxInputImageBytes(); size > limit {
+		return fmt.Errorf("input image too large: %s is %s, but the maximum is %s. Upload it to GCS and pass its gs:// URI instead", source, common.FormatBytes(size), common.FormatBytes(limit))
+	}
+	return nil
+}
+
+// loadFrameImage builds the image for an interpolation frame given as a GCS URI, a local
+// file path, or a base64 data URI (data:image/png;base64,...). The MIME type is inferred from
+// the URI, the data URI header, or the file content unless mimeOverride is set, and must be
+// image/jpeg or image/png; GENMEDIA_REQUIRE_MIME_TYPES makes mimeOverride required for GCS
+// URIs. The decoded content of a data URI must also be JPEG or PNG, and local or inline
+// frames must not exceed maxInputImageBytes. paramName is used in error messages. The
+// returned source describes the frame for logs without echoing inline data.
+func loadFrameImage(paramName, uri, mimeOverride string) (*genai.Image, string, error) {
+	uri = strings.TrimSpace(uri)
+	if uri == "" {
+		return nil, "", fmt.Errorf("%s must be a non-empty GCS URI, local file path, or data URI", paramName)
+	}
+
+	image := &genai.Image{}
+	source := uri
+	mimeParam := strings.TrimSuffix(paramName, "_uri") + "_mime_type"
+	switch {
+	case strings.HasPrefix(uri, "gs://"):
+		if strings.TrimSpace(mimeOverride) == "" && mimeTypesRequired() {
+			return nil, "", fmt.Errorf("%s is required for %s '%s' because GENMEDIA_REQUIRE_MIME_TYPES disables inferring it from the extension. Please specify it as 'image/jpeg' or 'image/png'.", mimeParam, paramName, uri)
+		}
+		image.GCSURI = uri
+		image.MIMEType = inferMimeTypeFromURI(uri)
+	case strings.HasPrefix(uri, "data:"):
+		data, headerMimeType, err := decodeDataURI(uri)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid %s: %w", paramName, err)
+		}
+		if err := checkInputImageSize(paramName, int64(len(data))); err != nil {
+			return nil, "", err
+		}
+		if detected := http.DetectContentType(data); !isSupportedInputImageMimeType(detected) {
+			return nil, "", fmt.Errorf("the data URI in %s decodes to '%s' content. Only 'image/jpeg' and 'image/png' are supported", paramName, detected)
+		}
+		image.ImageBytes = data
+		image.MIMEType = headerMimeType
+		if image.MIMEType == "" {
+			image.MIMEType = http.DetectContentType(data)
+		}
+		source = inlineDataSource(data)
+	default:
+		info, err := os.Stat(uri)
+		if err != nil || info.IsDir() {
+			return nil, "", fmt.Errorf("invalid %s '%s'. Must be a GCS URI starting with 'gs://', an existing local file, or a base64 data URI", paramName, uri)
+		}
+		if err := checkInputImageSize(fmt.Sprintf("%s '%s'", paramName, uri), info.Size()); err != nil {
+			return nil, "", err
+		}
+		data, err := os.ReadFile(uri)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s '%s': %w", paramName, uri, err)
+		}
+		image.ImageBytes = data
+		image.MIMEType = http.DetectContentType(data)
+	}
+	if mt := strings.ToLower(strings.TrimSpace(mimeOverride)); mt != "" {
+		image.MIMEType = mt
+	}
+
+	if !isSupportedInputImageMimeType(image.MIMEType) {
+		return nil, "", fmt.Errorf("MIME type for %s '%s' could not be inferred or is not supported. Please specify '%s' as 'image/jpeg' or 'image/png'.", paramName, source, mimeParam)
+	}
+	return image, source, nil
+}
+
+// frameHeaderBytes is how much of a GCS frame is read to find its dimensions. JPEG headers
+// can be preceded by large EXIF and ICC profile segments, so this is generous.
+const frameHeaderBytes = 256 << 10
+
+// frameDimensions returns the width and height of an interpolation frame, decoding only the
+// image header from its bytes or, for a GCS frame, from the start of the object.
+func frameDimensions(ctx context.Context, frame *genai.Image) (int, int, error) {
+	data := frame.ImageBytes
+	if len(data) == 0 {
+		var err error
+		data, err = common.ReadGCSObjectHeader(ctx, frame.GCSURI, frameHeaderBytes)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read image dimensions: %w", err)
+	}
+	return config.Width, config.Height, nil
+}
+
+// checkFrameDimensions compares the aspect ratios of the first and last interpolation frames
+// (see checkFrameAspectRatios). Frames whose dimensions cannot be read are not checked.
+func checkFrameDimensions(ctx context.Context, firstFrame, lastFrame *genai.Image, strict bool) (string, error) {
+	logger := loggerFromContext(ctx)
+	firstWidth, firstHeight, err := frameDimensions(ctx, firstFrame)
+	if err != nil {
+		logger.Warn("Could not read first frame dimensions, skipping the aspect ratio check", "error", err)
+		return "", nil
+	}
+	lastWidth, lastHeight, err := frameDimensions(ctx, lastFrame)
+	if err != nil {
+		logger.Warn("Could not read last frame dimensions, skipping the aspect ratio check", "error", err)
+		return "", nil
+	}
+	logger.Info("Read frame dimensions", "first_frame", fmt.Sprintf("%dx%d", firstWidth, firstHeight), "last_frame", fmt.Sprintf("%dx%d", lastWidth, lastHeight))
+	warning, err := checkFrameAspectRatios(firstWidth, firstHeight, lastWidth, lastHeight, strict)
+	if warning != "" {
+		logger.Warn(warning)
+	}
+	return warning, err
+}
+
+// checkInputImageExists verifies that an input image given as a GCS URI exists and can be
+// read, so that a bad URI fails early with a clear error instead of deep in the API. Images
+// given as bytes are not checked. paramName is used in error messages.
+func checkInputImageExists(ctx context.Context, paramName string, image *genai.Image) error {
+	if image == nil || image.GCSURI == "" {
+		return nil
+	}
+	bucketName, objectName, err := common.ParseGCSPath(image.GCSURI)
+	if err != nil {
+		return fmt.Errorf("%s '%s': %w: %v", paramName, image.GCSURI, errInputNotFound, err)
+	}
+	exists, err := common.GCSObjectExists(ctx, image.GCSURI)
+	if err != nil {
+		return fmt.Errorf("%s '%s': %w (bucket %q, object %q): %v", paramName, image.GCSURI, errInputNotFound, bucketName, objectName, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s '%s': %w (bucket %q, object %q)", paramName, image.GCSURI, errInputNotFound, bucketName, objectName)
+	}
+	return nil
+}
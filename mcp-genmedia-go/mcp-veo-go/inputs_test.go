@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestStagedInputURI(t *testing.T) {
+	data := []byte("image data")
+	testCases := []struct {
+		name           string
+		gcsOutput      string
+		mimeType       string
+		suffix         string
+		expectedPrefix string
+		expectedSuffix string
+		expectedError  bool
+	}{
+		{"default output prefix", "gs://bucket/veo_outputs/", "image/png", "", "gs://bucket/veo_outputs/inputs/", ".png", false},
+		{"bucket without scheme or slash", "bucket", "image/jpeg", "", "gs://bucket/inputs/", ".jpg", false},
+		{"unique suffix", "gs://bucket/out", "image/png", "-123", "gs://bucket/out/inputs/", "-123.png", false},
+		{"missing bucket", "gs://", "image/png", "", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			uri, err := stagedInputURI(tc.gcsOutput, data, tc.mimeType, tc.suffix)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(uri, tc.expectedPrefix) || !strings.HasSuffix(uri, tc.expectedSuffix) {
+				t.Errorf("expected '%s...%s', but got '%s'", tc.expectedPrefix, tc.expectedSuffix, uri)
+			}
+		})
+	}
+
+	first, _ := stagedInputURI("gs://bucket/", data, "image/png", "")
+	second, _ := stagedInputURI("gs://bucket/", data, "image/png", "")
+	other, _ := stagedInputURI("gs://bucket/", []byte("other image"), "image/png", "")
+	if first != second {
+		t.Errorf("expected the same image to map to the same object, but got '%s' and '%s'", first, second)
+	}
+	if first == other {
+		t.Errorf("expected different images to map to different objects, but both got '%s'", first)
+	}
+}
+
+func TestStageInputImageWithoutBucket(t *testing.T) {
+	image := &genai.Image{ImageBytes: []byte("image data"), MIMEType: "image/png"}
+	cleanup, err := stageInputImage(context.Background(), image, "", false)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	cleanup()
+	if image.GCSURI != "" || len(image.ImageBytes) == 0 {
+		t.Errorf("expected the image to be left inline, but got GCSURI '%s'", image.GCSURI)
+	}
+}
+
+func TestLoadFrameImage(t *testing.T) {
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "frame.png")
+	if err := os.WriteFile(pngPath, []byte("\x89PNG\r\n\x1a\nrest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pngData := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\nrest"))
+	textPath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textPath, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name          string
+		uri           string
+		mimeOverride  string
+		expectedMime  string
+		expectedLocal bool
+		expectedError string
+	}{
+		{"GCS URI", "gs://bucket/frame.jpg", "", "image/jpeg", false, ""},
+		{"GCS URI with override", "gs://bucket/frame", "image/png", "image/png", false, ""},
+		{"local PNG", pngPath, "", "image/png", true, ""},
+		{"missing local file", filepath.Join(dir, "missing.png"), "", "", false, "existing local file"},
+		{"unsupported local file", textPath, "", "", false, "specify 'first_frame_mime_type'"},
+		{"empty", " ", "", "", false, "must be a non-empty"},
+		{"PNG data URI", "data:image/png;base64," + pngData, "", "image/png", true, ""},
+		{"data URI without MIME type", "data:;base64," + pngData, "", "image/png", true, ""},
+		{"data URI with override", "data:image/png;base64," + pngData, "image/png", "image/png", true, ""},
+		{"data URI with text content", "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("not an image")), "", "", false, "decodes to 'text/plain"},
+		{"data URI not base64", "data:image/png,rest", "", "", false, "must be base64-encoded"},
+		{"invalid base64 data URI", "data:image/png;base64,!!!", "", "", false, "failed to decode"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			image, source, err := loadFrameImage("first_frame_uri", tc.uri, tc.mimeOverride)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if image.MIMEType != tc.expectedMime {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedMime, image.MIMEType)
+			}
+			if (len(image.ImageBytes) > 0) != tc.expectedLocal {
+				t.Errorf("expected local bytes: %v, but got GCSURI '%s' and %d bytes", tc.expectedLocal, image.GCSURI, len(image.ImageBytes))
+			}
+			if strings.Contains(source, pngData) {
+				t.Errorf("expected the source not to echo inline data, but got '%s'", source)
+			}
+		})
+	}
+}
+
+func TestLoadFrameImageRequireMimeTypes(t *testing.T) {
+	appConfig = &common.Config{RequireMimeTypes: true}
+	defer func() { appConfig = nil }()
+
+	if _, _, err := loadFrameImage("last_frame_uri", "gs://bucket/frame.png", ""); err == nil || !strings.Contains(err.Error(), "last_frame_mime_type is required") {
+		t.Errorf("expected an error asking for last_frame_mime_type, but got %v", err)
+	}
+	image, _, err := loadFrameImage("last_frame_uri", "gs://bucket/frame.png", "image/jpeg")
+	if err != nil {
+		t.Fatalf("expected an explicit MIME type to be accepted, but got %v", err)
+	}
+	if image.MIMEType != "image/jpeg" {
+		t.Errorf("expected 'image/jpeg', but got '%s'", image.MIMEType)
+	}
+}
+
+func TestFrameDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 32, 18))); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+
+	width, height, err := frameDimensions(context.Background(), &genai.Image{ImageBytes: buf.Bytes(), MIMEType: "image/png"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if width != 32 || height != 18 {
+		t.Errorf("expected 32x18, but got %dx%d", width, height)
+	}
+
+	if _, _, err := frameDimensions(context.Background(), &genai.Image{ImageBytes: []byte("not an image")}); err == nil {
+		t.Errorf("expected an error for data that is not an image")
+	}
+}
+
+func TestCheckInputImageExistsSkipsNonGCSImages(t *testing.T) {
+	testCases := []struct {
+		name  string
+		image *genai.Image
+	}{
+		{"nil image", nil},
+		{"image bytes", &genai.Image{ImageBytes: []byte("data"), MIMEType: "image/png"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkInputImageExists(context.Background(), "image_uri", tc.image); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckInputImageExistsInvalidURI(t *testing.T) {
+	err := checkInputImageExists(context.Background(), "image_uri", &genai.Image{GCSURI: "gs://bucket-only"})
+	if err == nil || errorCode(err) != codeNotFound || !strings.Contains(err.Error(), "image_uri 'gs://bucket-only'") {
+		t.Errorf("expected a NOT_FOUND error naming the parameter, but got %v", err)
+	}
+}
+
+func TestInputImageSizeLimit(t *testing.T) {
+	appConfig = &common.Config{MaxInputImageBytes: 8}
+	defer func() { appConfig = nil }()
+
+	largeImage := []byte("\x89PNG\r\n\x1a\nrest")
+	largePath := filepath.Join(t.TempDir(), "large.png")
+	if err := os.WriteFile(largePath, largeImage, 0644); err != nil {
+		t.Fatal(err)
+	}
+	largeData := base64.StdEncoding.EncodeToString(largeImage)
+
+	for _, input := range []string{largePath, "data:image/png;base64," + largeData, largeData} {
+		if _, _, _, err := loadLocalOrInlineImage(input); err == nil || !strings.Contains(err.Error(), "input image too large") {
+			t.Errorf("expected a too large error from loadLocalOrInlineImage, but got %v", err)
+		}
+	}
+	for _, uri := range []string{largePath, "data:image/png;base64," + largeData} {
+		if _, _, err := loadFrameImage("last_frame_uri", uri, ""); err == nil || !strings.Contains(err.Error(), "input image too large: last_frame_uri") {
+			t.Errorf("expected a too large error naming the parameter from loadFrameImage, but got %v", err)
+		}
+	}
+	if _, _, err := loadFrameImage("first_frame_uri", "gs://bucket/frame.png", ""); err != nil {
+		t.Errorf("expected GCS frames not to be size checked, but got %v", err)
+	}
+
+	appConfig.MaxInputImageBytes = len(largeImage)
+	if _, _, _, err := loadLocalOrInlineImage(largePath); err != nil {
+		t.Errorf("expected an image at the limit to be accepted, but got %v", err)
+	}
+}
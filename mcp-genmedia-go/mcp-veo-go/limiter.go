@@ -0,0 +1,68 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+)
+
+// generationLimiter bounds the number of video generation operations that are started and
+// polled at the same time, across all requests. This includes the operations a single
+// request fans out to, such as one per aspect ratio in veo_t2v.
+type generationLimiter struct {
+	slots chan struct{}
+}
+
+// newGenerationLimiter returns a limiter allowing up to maxConcurrent operations at once.
+// A non-positive maxConcurrent means no limit.
+func newGenerationLimiter(maxConcurrent int) *generationLimiter {
+	if maxConcurrent <= 0 {
+		return &generationLimiter{}
+	}
+	return &generationLimiter{slots: make(chan struct{}, maxConcurrent)}
+}
+
+// generationSlots is the server-wide limiter, set up from appConfig.MaxConcurrency in main.
+// It is nil (no limit) until then, e.g., in tests.
+var generationSlots *generationLimiter
+
+// Acquire blocks until a slot is free or ctx is done. If no slot is immediately available,
+// onWait (if not nil) is called once before blocking, so callers can report that the request
+// is queued. On success, the returned release function must be called to free the slot.
+func (l *generationLimiter) Acquire(ctx context.Context, onWait func()) (release func(), err error) {
+	if l == nil || l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+	if onWait != nil {
+		onWait()
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *generationLimiter) release() {
+	<-l.slots
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGenerationLimiterUnlimited(t *testing.T) {
+	for _, limiter := range []*generationLimiter{nil, newGenerationLimiter(0)} {
+		for i := 0; i < 10; i++ {
+			release, err := limiter.Acquire(t.Context(), func() { t.Error("expected no wait without a limit") })
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			defer release()
+		}
+	}
+}
+
+func TestGenerationLimiterQueuesAndCancels(t *testing.T) {
+	limiter := newGenerationLimiter(1)
+	release, err := limiter.Acquire(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	waited := false
+	if _, err := limiter.Acquire(ctx, func() { waited = true }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded while the slot is held, but got: %v", err)
+	}
+	if !waited {
+		t.Error("expected onWait to be called when no slot is free")
+	}
+
+	release()
+	release, err = limiter.Acquire(t.Context(), func() { t.Error("expected no wait once the slot is released") })
+	if err != nil {
+		t.Fatalf("expected no error after release, but got: %v", err)
+	}
+	release()
+}
+
+func TestGenerationLimiterBoundsConcurrency(t *testing.T) {
+	const maxConcurrent = 2
+	limiter := newGenerationLimiter(maxConcurrent)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(t.Context(), nil)
+			if err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > maxConcurrent {
+		t.Errorf("expected at most %d concurrent operations, but got %d", maxConcurrent, peak.Load())
+	}
+}
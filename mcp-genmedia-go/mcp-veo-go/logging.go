@@ -0,0 +1,65 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loggerKey is the context key under which a request's logger is stored.
+type loggerKey struct{}
+
+// newRequestID returns a random 16-character hex ID that identifies one tool call in the
+// logs and traces.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// startRequestLogger assigns a request ID to a tool call, records it as the request_id
+// attribute of the current span, and returns a context carrying a logger that tags every
+// line with the tool name and request ID.
+func startRequestLogger(ctx context.Context, tool string) (context.Context, *slog.Logger) {
+	requestID := newRequestID()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", requestID))
+	logger := slog.Default().With("tool", tool, "request_id", requestID)
+	return context.WithValue(ctx, loggerKey{}, logger), logger
+}
+
+// withLogAttrs returns a context whose logger adds the given key/value pairs, such as the
+// resolved model, to every line.
+func withLogAttrs(ctx context.Context, args ...any) (context.Context, *slog.Logger) {
+	logger := loggerFromContext(ctx).With(args...)
+	return context.WithValue(ctx, loggerKey{}, logger), logger
+}
+
+// loggerFromContext returns the request's logger, or the default logger outside a tool call.
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewRequestID(t *testing.T) {
+	id := newRequestID()
+	if !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(id) {
+		t.Errorf("expected a 16-character hex ID, but got '%s'", id)
+	}
+	if other := newRequestID(); other == id {
+		t.Errorf("expected distinct request IDs, but got '%s' twice", id)
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	if loggerFromContext(context.Background()) != slog.Default() {
+		t.Errorf("expected the default logger outside a tool call")
+	}
+
+	ctx, _ := startRequestLogger(context.Background(), "veo_t2v")
+	ctx, _ = withLogAttrs(ctx, "model", "veo-2.0-generate-001")
+	loggerFromContext(ctx).Info("Handling Veo request")
+
+	line := buf.String()
+	for _, expected := range []string{"tool=veo_t2v", "request_id=", "model=veo-2.0-generate-001", `msg="Handling Veo request"`} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("expected the log line to contain '%s', but got '%s'", expected, line)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Outcomes recorded in the 'outcome' attribute of the generation metrics.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeCached  = "cached"
+)
+
+// generationMetrics holds the OpenTelemetry instruments recorded for each call to
+// callGenerateVideosAPI. They are created from the global meter provider, which
+// common.InitMeterProvider points at the OTLP exporter when OTEL_ENABLED is true; until then
+// (or when it is disabled) recording is a no-op.
+type generationMetrics struct {
+	generations metric.Int64Counter
+	failures    metric.Int64Counter
+	latency     metric.Float64Histogram
+}
+
+// veoMetrics records the generation metrics of this server.
+var veoMetrics = newGenerationMetrics(otel.Meter(serviceName))
+
+// newGenerationMetrics creates the generation instruments on meter. An instrument that cannot
+// be created is logged and left nil, which disables it.
+func newGenerationMetrics(meter metric.Meter) *generationMetrics {
+	m := &generationMetrics{}
+	var err error
+	if m.generations, err = meter.Int64Counter("veo.generations",
+		metric.WithDescription("Video generation requests, by model, tool, and outcome (success, failure, or cached)."),
+		metric.WithUnit("{generation}")); err != nil {
+		log.Printf("Failed to create the veo.generations counter: %v", err)
+	}
+	if m.failures, err = meter.Int64Counter("veo.generation.failures",
+		metric.WithDescription("Failed video generation requests, by model, tool, and error code."),
+		metric.WithUnit("{generation}")); err != nil {
+		log.Printf("Failed to create the veo.generation.failures counter: %v", err)
+	}
+	if m.latency, err = meter.Float64Histogram("veo.generation.duration",
+		metric.WithDescription("Time taken by video generation requests, from queueing to the result, by model, tool, and outcome."),
+		metric.WithUnit("s")); err != nil {
+		log.Printf("Failed to create the veo.generation.duration histogram: %v", err)
+	}
+	return m
+}
+
+// Record records one call to callGenerateVideosAPI that took elapsed and returned result. A
+// cached result counts as a generation with the 'cached' outcome; a failure is also counted
+// by the [code=...] of its error result, or the code of err.
+func (m *generationMetrics) Record(ctx context.Context, modelName, callType string, cached bool, result *mcp.CallToolResult, err error, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	outcome := outcomeSuccess
+	switch {
+	case err != nil || result == nil || result.IsError:
+		outcome = outcomeFailure
+	case cached:
+		outcome = outcomeCached
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("model", modelName),
+		attribute.String("tool", toolForCallType(callType)),
+	}
+	withOutcome := metric.WithAttributes(append(attrs, attribute.String("outcome", outcome))...)
+
+	if m.generations != nil {
+		m.generations.Add(ctx, 1, withOutcome)
+	}
+	if m.latency != nil {
+		m.latency.Record(ctx, elapsed.Seconds(), withOutcome)
+	}
+	if outcome == outcomeFailure && m.failures != nil {
+		code := codeUnknown
+		if err != nil {
+			code = errorCode(err)
+		} else if result != nil {
+			code = resultErrorCode(result)
+		}
+		m.failures.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("error_code", code))...))
+	}
+}
+
+// toolForCallType returns the name of the tool that makes calls of callType, e.g. veo_t2v for
+// "t2v 16:9" and veo_batch_t2v for "batch t2v 2", keeping the metric attributes low in
+// cardinality.
+func toolForCallType(callType string) string {
+	kind, _, _ := strings.Cut(callType, " ")
+	if kind == "batch" {
+		return "veo_batch_t2v"
+	}
+	return "veo_" + kind
+}
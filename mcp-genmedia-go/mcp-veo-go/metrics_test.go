@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestToolForCallType(t *testing.T) {
+	testCases := []struct {
+		callType string
+		expected string
+	}{
+		{"t2v", "veo_t2v"},
+		{"t2v 16:9", "veo_t2v"},
+		{"batch t2v 2", "veo_batch_t2v"},
+		{"i2v", "veo_i2v"},
+		{"interpolate", "veo_interpolate"},
+		{"extend", "veo_extend"},
+	}
+
+	for _, tc := range testCases {
+		if actual := toolForCallType(tc.callType); actual != tc.expected {
+			t.Errorf("toolForCallType(%q): expected '%s', but got '%s'", tc.callType, tc.expected, actual)
+		}
+	}
+}
+
+// collectCounts reads the int64 counter named name from reader and returns its values keyed by
+// the value of the attribute key.
+func collectCounts(t *testing.T, reader sdkmetric.Reader, name string, key attribute.Key) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("expected %s to be an int64 sum, but got %T", name, m.Data)
+			}
+			for _, point := range sum.DataPoints {
+				value, _ := point.Attributes.Value(key)
+				counts[value.AsString()] += point.Value
+			}
+		}
+	}
+	return counts
+}
+
+func TestGenerationMetricsRecord(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+	m := newGenerationMetrics(provider.Meter(serviceName))
+
+	ctx := t.Context()
+	succeeded := mcp.NewToolResultText("generated")
+	m.Record(ctx, "veo-2.0-generate-001", "t2v", false, succeeded, nil, time.Second)
+	m.Record(ctx, "veo-2.0-generate-001", "t2v", false, succeeded, nil, time.Second)
+	m.Record(ctx, "veo-2.0-generate-001", "t2v", true, succeeded, nil, time.Millisecond)
+	m.Record(ctx, "veo-2.0-generate-001", "t2v", false, invalidArgumentResult("duration is not supported"), nil, time.Second)
+	m.Record(ctx, "veo-2.0-generate-001", "t2v", false, nil, context.DeadlineExceeded, time.Minute)
+
+	outcomes := collectCounts(t, reader, "veo.generations", "outcome")
+	expectedOutcomes := map[string]int64{outcomeSuccess: 2, outcomeCached: 1, outcomeFailure: 2}
+	for outcome, expected := range expectedOutcomes {
+		if outcomes[outcome] != expected {
+			t.Errorf("expected %d generations with outcome '%s', but got %d (all: %v)", expected, outcome, outcomes[outcome], outcomes)
+		}
+	}
+
+	codes := collectCounts(t, reader, "veo.generation.failures", "error_code")
+	expectedCodes := map[string]int64{codeInvalidArgument: 1, codeDeadlineExceeded: 1}
+	if len(codes) != len(expectedCodes) {
+		t.Errorf("expected failures with error codes %v, but got %v", expectedCodes, codes)
+	}
+	for code, expected := range expectedCodes {
+		if codes[code] != expected {
+			t.Errorf("expected %d failures with error_code '%s', but got %d", expected, code, codes[code])
+		}
+	}
+}
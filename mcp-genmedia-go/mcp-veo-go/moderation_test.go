@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+func TestParseModerationVerdict(t *testing.T) {
+	testCases := []struct {
+		name               string
+		text               string
+		expectedDecision   string
+		expectedCategories []string
+		expectedError      string
+	}{
+		{"pass", `{"decision": "pass", "categories": [], "reason": "A landscape."}`, "pass", nil, ""},
+		{"flag", `{"decision": "FLAG", "categories": ["Violence", "hate", "violence"], "reason": "Graphic gore."}`, "flag", []string{"violence", "hate"}, ""},
+		{"unknown category", `{"decision": "flag", "categories": ["self harm", "spam"]}`, "flag", []string{"self_harm", "other"}, ""},
+		{"pass drops categories", `{"decision": "pass", "categories": ["violence"]}`, "pass", nil, ""},
+		{"code fence", "```json\n{\"decision\": \"pass\"}\n```", "pass", nil, ""},
+		{"invalid decision", `{"decision": "maybe"}`, "", nil, "returned decision 'maybe'"},
+		{"not JSON", "The prompt is fine.", "", nil, "did not return a valid JSON verdict"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := parseModerationVerdict(tc.text)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verdict.Decision != tc.expectedDecision {
+				t.Errorf("expected decision '%s', but got '%s'", tc.expectedDecision, verdict.Decision)
+			}
+			if !slices.Equal(verdict.Categories, tc.expectedCategories) {
+				t.Errorf("expected categories %v, but got %v", tc.expectedCategories, verdict.Categories)
+			}
+		})
+	}
+}
+
+func TestResolveModerationModel(t *testing.T) {
+	appConfig = &common.Config{ModerationModel: "Gemini 3 Pro"}
+	defer func() { appConfig = nil }()
+
+	testCases := []struct {
+		name          string
+		model         string
+		expected      string
+		expectedError string
+	}{
+		{"configured default", "", "gemini-3-pro-preview", ""},
+		{"explicit model", "gemini-3-pro-preview", "gemini-3-pro-preview", ""},
+		{"image model", "nano-banana", "", "image generation model"},
+		{"unknown model", "gpt", "", "not a supported Gemini model"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := resolveModerationModel(tc.model)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if model != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, model)
+			}
+		})
+	}
+}
+
+func TestModerationBlockResultDisabled(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	if result := moderationBlockResult(t.Context(), "a cat"); result != nil {
+		t.Errorf("expected no moderation when GENMEDIA_MODERATE_PROMPTS is off, but got %v", result)
+	}
+	appConfig.ModeratePrompts = true
+	if result := moderationBlockResult(t.Context(), " "); result != nil {
+		t.Errorf("expected empty prompts not to be screened, but got %v", result)
+	}
+}
+
+func TestModerationVerdictSummary(t *testing.T) {
+	verdict := &moderationVerdict{Decision: moderationFlag, Categories: []string{"violence"}, Reason: "Graphic gore.", Model: "gemini-3-pro-preview"}
+	expected := "FLAGGED: the prompt was flagged by gemini-3-pro-preview for: violence. Reason: Graphic gore."
+	if summary := verdict.Summary(); summary != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, summary)
+	}
+}
+
+func TestModerationClientConfig(t *testing.T) {
+	appConfig = &common.Config{ProjectID: "my-project", Location: "us-central1"}
+	defer func() { appConfig = nil }()
+
+	clientConfig := moderationClientConfig()
+	if clientConfig.Location != "global" || clientConfig.Project != "my-project" {
+		t.Errorf("expected the moderation client in my-project/global regardless of LOCATION, but got %s/%s", clientConfig.Project, clientConfig.Location)
+	}
+	appConfig.ModerationLocation = "europe-west4"
+	if clientConfig := moderationClientConfig(); clientConfig.Location != "europe-west4" {
+		t.Errorf("expected GENMEDIA_MODERATION_LOCATION to set the location, but got %s", clientConfig.Location)
+	}
+}
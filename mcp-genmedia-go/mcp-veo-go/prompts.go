@@ -0,0 +1,182 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+// templatePlaceholder matches a {{name}} placeholder in a prompt template. Spaces inside the
+// braces are ignored.
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// expandPromptTemplate replaces each {{name}} placeholder in template with the value of the
+// variable of that name. Every placeholder must have a variable; unused variables are ignored.
+func expandPromptTemplate(template string, vars map[string]string) (string, error) {
+	var missing []string
+	expanded := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return placeholder
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt_template uses variables missing from prompt_vars: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// parsePromptVars parses the optional 'prompt_vars' argument, an object of variable names to
+// string, number, or boolean values. The argument may be a native object or a JSON string
+// encoding the object.
+func parsePromptVars(args map[string]interface{}) (map[string]string, error) {
+	var raw map[string]interface{}
+	switch v := args["prompt_vars"].(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		raw = v
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(v), &raw); err != nil {
+			return nil, fmt.Errorf("Failed to parse 'prompt_vars' JSON: %v. It must be an object of variable names and values, e.g. '{\"animal\": \"fox\"}'", err)
+		}
+	default:
+		return nil, fmt.Errorf("prompt_vars must be an object of variable names and values, e.g. '{\"animal\": \"fox\"}'")
+	}
+
+	vars := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			vars[name] = v
+		case float64:
+			vars[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			vars[name] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("prompt_vars value for '%s' must be a string, number, or boolean", name)
+		}
+	}
+	return vars, nil
+}
+
+// resolvePromptArg returns the prompt of a request: the 'prompt' argument or, when
+// 'prompt_template' is set instead, the template expanded with 'prompt_vars'. The returned
+// template is empty if none was used.
+func resolvePromptArg(args map[string]interface{}) (string, string, error) {
+	prompt, _ := args["prompt"].(string)
+	template, _ := args["prompt_template"].(string)
+	prompt, template = strings.TrimSpace(prompt), strings.TrimSpace(template)
+
+	if template == "" {
+		if isArgProvided(args["prompt_vars"]) {
+			return "", "", fmt.Errorf("prompt_vars is only used with prompt_template")
+		}
+		if prompt == "" {
+			return "", "", fmt.Errorf("prompt must be a non-empty string and is required for text-to-video unless prompt_template is provided")
+		}
+		return prompt, "", nil
+	}
+	if prompt != "" {
+		return "", "", fmt.Errorf("provide either prompt or prompt_template, not both")
+	}
+
+	vars, err := parsePromptVars(args)
+	if err != nil {
+		return "", "", err
+	}
+	expanded, err := expandPromptTemplate(template, vars)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.TrimSpace(expanded) == "" {
+		return "", "", fmt.Errorf("prompt_template expanded to an empty prompt")
+	}
+	return strings.TrimSpace(expanded), template, nil
+}
+
+// promptStyles returns the style tags that the 'style' argument accepts, from
+// GENMEDIA_PROMPT_STYLES and the built-in styles.
+func promptStyles(appConfig *common.Config) map[string]string {
+	if appConfig == nil || appConfig.PromptStyles == nil {
+		return common.DefaultPromptStyles
+	}
+	return appConfig.PromptStyles
+}
+
+// parseStyle reads the optional 'style' argument and returns the lowercased style tag and the
+// text it appends to the prompt. Unknown tags are rejected with the list of known styles.
+func parseStyle(args map[string]interface{}, styles map[string]string) (string, string, error) {
+	styleArg, _ := args["style"].(string)
+	style := strings.ToLower(strings.TrimSpace(styleArg))
+	if style == "" {
+		return "", "", nil
+	}
+	text, ok := styles[style]
+	if !ok {
+		return "", "", fmt.Errorf("style '%s' is not supported. Accepted values are: [%s]", styleArg, strings.Join(slices.Sorted(maps.Keys(styles)), ", "))
+	}
+	return style, text, nil
+}
+
+// applyStyleText appends the text of a style to a prompt, ending the prompt with a period
+// first if it has no closing punctuation. An empty prompt becomes the style text alone.
+func applyStyleText(prompt, styleText string) string {
+	prompt = strings.TrimSpace(prompt)
+	if styleText == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return styleText
+	}
+	if !strings.ContainsAny(prompt[len(prompt)-1:], ".!?") {
+		prompt += "."
+	}
+	return prompt + " " + styleText
+}
+
+// finalPromptNote describes the prompt sent to Veo when it differs from the 'prompt' argument
+// because it was expanded from prompt_template or a style was applied, for the tool result.
+func finalPromptNote(prompt string, fromTemplate bool, style string) string {
+	var changes []string
+	if fromTemplate {
+		changes = append(changes, "expanded from prompt_template")
+	}
+	if style != "" {
+		changes = append(changes, fmt.Sprintf("styled as '%s'", style))
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Prompt %s: %q.", strings.Join(changes, " and "), prompt)
+}
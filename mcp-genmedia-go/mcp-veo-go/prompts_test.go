@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandPromptTemplate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		template      string
+		vars          map[string]string
+		expected      string
+		expectedError string
+	}{
+		{"all variables", "A {{animal}} in {{ place }}", map[string]string{"animal": "fox", "place": "the snow"}, "A fox in the snow", ""},
+		{"repeated variable", "{{x}} and {{x}}", map[string]string{"x": "a"}, "a and a", ""},
+		{"unused variables", "A fox", map[string]string{"x": "a"}, "A fox", ""},
+		{"missing variables", "A {{animal}} in {{place}} with {{animal}}", map[string]string{}, "", "missing from prompt_vars: animal, place"},
+		{"not a placeholder", "Braces {{ not-a-name }} stay", nil, "Braces {{ not-a-name }} stay", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded, err := expandPromptTemplate(tc.template, tc.vars)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expanded != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, expanded)
+			}
+		})
+	}
+}
+
+func TestResolvePromptArg(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             map[string]interface{}
+		expectedPrompt   string
+		expectedTemplate string
+		expectedError    string
+	}{
+		{"prompt", map[string]interface{}{"prompt": " a fox "}, "a fox", "", ""},
+		{"template with JSON vars", map[string]interface{}{"prompt_template": "a {{animal}}, {{n}} times, {{ok}}", "prompt_vars": `{"animal": "fox", "n": 2, "ok": true}`}, "a fox, 2 times, true", "a {{animal}}, {{n}} times, {{ok}}", ""},
+		{"template with object vars", map[string]interface{}{"prompt_template": "a {{animal}}", "prompt_vars": map[string]interface{}{"animal": "cat"}}, "a cat", "a {{animal}}", ""},
+		{"template missing a variable", map[string]interface{}{"prompt_template": "a {{animal}}"}, "", "", "missing from prompt_vars: animal"},
+		{"both prompt and template", map[string]interface{}{"prompt": "a fox", "prompt_template": "a {{animal}}"}, "", "", "not both"},
+		{"vars without template", map[string]interface{}{"prompt": "a fox", "prompt_vars": `{"animal": "fox"}`}, "", "", "only used with prompt_template"},
+		{"invalid vars JSON", map[string]interface{}{"prompt_template": "a {{animal}}", "prompt_vars": "{"}, "", "", "Failed to parse 'prompt_vars'"},
+		{"nested var value", map[string]interface{}{"prompt_template": "a {{animal}}", "prompt_vars": `{"animal": ["fox"]}`}, "", "", "value for 'animal'"},
+		{"empty expansion", map[string]interface{}{"prompt_template": "{{animal}}", "prompt_vars": `{"animal": " "}`}, "", "", "empty prompt"},
+		{"no prompt", map[string]interface{}{}, "", "", "prompt must be a non-empty string"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt, template, err := resolvePromptArg(tc.args)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prompt != tc.expectedPrompt || template != tc.expectedTemplate {
+				t.Errorf("expected ('%s', '%s'), but got ('%s', '%s')", tc.expectedPrompt, tc.expectedTemplate, prompt, template)
+			}
+		})
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	styles := map[string]string{"cinematic": "Cinematic style.", "anime": "Anime style."}
+	testCases := []struct {
+		name          string
+		args          map[string]interface{}
+		expectedStyle string
+		expectedText  string
+		expectedError string
+	}{
+		{"no style", map[string]interface{}{}, "", "", ""},
+		{"known style", map[string]interface{}{"style": " Cinematic "}, "cinematic", "Cinematic style.", ""},
+		{"unknown style", map[string]interface{}{"style": "pixel"}, "", "", "Accepted values are: [anime, cinematic]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			style, text, err := parseStyle(tc.args, styles)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if style != tc.expectedStyle || text != tc.expectedText {
+				t.Errorf("expected ('%s', '%s'), but got ('%s', '%s')", tc.expectedStyle, tc.expectedText, style, text)
+			}
+		})
+	}
+}
+
+func TestApplyStyleText(t *testing.T) {
+	testCases := []struct {
+		prompt    string
+		styleText string
+		expected  string
+	}{
+		{"a fox", "", "a fox"},
+		{"a fox ", "Anime style.", "a fox. Anime style."},
+		{"a fox!", "Anime style.", "a fox! Anime style."},
+		{"", "Anime style.", "Anime style."},
+	}
+
+	for _, tc := range testCases {
+		if actual := applyStyleText(tc.prompt, tc.styleText); actual != tc.expected {
+			t.Errorf("applyStyleText(%q, %q): expected %q, but got %q", tc.prompt, tc.styleText, tc.expected, actual)
+		}
+	}
+}
+
+func TestFinalPromptNote(t *testing.T) {
+	if note := finalPromptNote("a fox", false, ""); note != "" {
+		t.Errorf("expected no note for an unchanged prompt, but got '%s'", note)
+	}
+	if note := finalPromptNote("a fox. Anime style.", true, "anime"); note != `Prompt expanded from prompt_template and styled as 'anime': "a fox. Anime style.".` {
+		t.Errorf("unexpected note: '%s'", note)
+	}
+}
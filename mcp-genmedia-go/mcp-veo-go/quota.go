@@ -0,0 +1,329 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// quotaCheckTimeout bounds the Cloud Quotas and Cloud Monitoring calls made by veo_quota.
+	quotaCheckTimeout = 30 * time.Second
+	// cloudQuotasBaseURL is the Cloud Quotas API, which reports quota limits.
+	cloudQuotasBaseURL = "https://cloudquotas.googleapis.com"
+	// monitoringBaseURL is the Cloud Monitoring API, which reports quota usage.
+	monitoringBaseURL = "https://monitoring.googleapis.com"
+	// vertexAIService is the service whose quotas cover Veo generation.
+	vertexAIService = "aiplatform.googleapis.com"
+	// maxQuotaInfoPages caps the pages of quota infos fetched for the service.
+	maxQuotaInfoPages = 20
+	// quotaUsageWindow is how far back veo_quota looks for the latest usage sample.
+	quotaUsageWindow = 10 * time.Minute
+)
+
+// veoQuota is one Veo-related quota limit in a location, with the usage reported for its
+// quota metric.
+type veoQuota struct {
+	QuotaID         string `json:"quota_id"`
+	Metric          string `json:"metric"`
+	DisplayName     string `json:"display_name,omitempty"`
+	Location        string `json:"location,omitempty"`
+	Model           string `json:"model,omitempty"`
+	RefreshInterval string `json:"refresh_interval,omitempty"`
+	// Limit is the quota limit; -1 means unlimited.
+	Limit int64 `json:"limit"`
+	// MetricUsage is the latest per-minute usage of the quota metric in the location, summed
+	// across all of its dimensions (e.g., every base model), or nil if none was reported.
+	MetricUsage *int64 `json:"metric_usage,omitempty"`
+}
+
+// quotaResult is the machine-readable result of the veo_quota tool. Status is "ok", "partial"
+// (limits without usage), or "unavailable" (the limits could not be read).
+type quotaResult struct {
+	Status   string     `json:"status"`
+	Project  string     `json:"project"`
+	Location string     `json:"location"`
+	Quotas   []veoQuota `json:"quotas"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// quotaInfo is the subset of a Cloud Quotas API QuotaInfo used by veo_quota.
+type quotaInfo struct {
+	QuotaID          string `json:"quotaId"`
+	Metric           string `json:"metric"`
+	QuotaDisplayName string `json:"quotaDisplayName"`
+	RefreshInterval  string `json:"refreshInterval"`
+	DimensionsInfos  []struct {
+		Dimensions map[string]string `json:"dimensions"`
+		Details    struct {
+			Value json.Number `json:"value"`
+		} `json:"details"`
+	} `json:"dimensionsInfos"`
+}
+
+// getJSON sends a GET request and decodes the JSON response into out. Error responses are
+// returned as a genai.APIError.
+func getJSON(ctx context.Context, httpClient *http.Client, requestURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return apiErrorFromResponse(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// isVeoQuota reports whether a quota applies to Veo: either its metric or ID is about video,
+// or it is a per-model quota whose model is a Veo model.
+func isVeoQuota(info quotaInfo, dimensions map[string]string) bool {
+	text := strings.ToLower(info.Metric + " " + info.QuotaID)
+	if strings.Contains(text, "video") || strings.Contains(text, "veo") {
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(dimensions["base_model"]), "veo")
+}
+
+// fetchVeoQuotaLimits lists the Vertex AI quotas of project with the Cloud Quotas API and
+// returns the Veo-related limits that apply in location.
+func fetchVeoQuotaLimits(ctx context.Context, httpClient *http.Client, baseURL, project, location string) ([]veoQuota, error) {
+	var quotas []veoQuota
+	pageToken := ""
+	for page := 0; page < maxQuotaInfoPages; page++ {
+		requestURL := fmt.Sprintf("%s/v1/projects/%s/locations/global/services/%s/quotaInfos", strings.TrimSuffix(baseURL, "/"), url.PathEscape(project), vertexAIService)
+		if pageToken != "" {
+			requestURL += "?pageToken=" + url.QueryEscape(pageToken)
+		}
+		var response struct {
+			QuotaInfos    []quotaInfo `json:"quotaInfos"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := getJSON(ctx, httpClient, requestURL, &response); err != nil {
+			return nil, err
+		}
+		for _, info := range response.QuotaInfos {
+			for _, dimensionsInfo := range info.DimensionsInfos {
+				region, regional := dimensionsInfo.Dimensions["region"]
+				if regional && region != location {
+					continue
+				}
+				if !isVeoQuota(info, dimensionsInfo.Dimensions) {
+					continue
+				}
+				limit, err := strconv.ParseInt(dimensionsInfo.Details.Value.String(), 10, 64)
+				if err != nil {
+					continue
+				}
+				quotas = append(quotas, veoQuota{
+					QuotaID:         info.QuotaID,
+					Metric:          info.Metric,
+					DisplayName:     info.QuotaDisplayName,
+					Location:        region,
+					Model:           dimensionsInfo.Dimensions["base_model"],
+					RefreshInterval: info.RefreshInterval,
+					Limit:           limit,
+				})
+			}
+		}
+		if response.NextPageToken == "" {
+			break
+		}
+		pageToken = response.NextPageToken
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].Metric != quotas[j].Metric {
+			return quotas[i].Metric < quotas[j].Metric
+		}
+		return quotas[i].Model < quotas[j].Model
+	})
+	return quotas, nil
+}
+
+// fetchQuotaMetricUsage reads the Vertex AI quota usage of project in location from Cloud
+// Monitoring, returning the latest per-minute usage of each quota metric.
+func fetchQuotaMetricUsage(ctx context.Context, httpClient *http.Client, baseURL, project, location string, now time.Time) (map[string]int64, error) {
+	filter := fmt.Sprintf(`metric.type="serviceruntime.googleapis.com/quota/rate/net_usage" AND resource.type="consumer_quota" AND resource.labels.service="%s" AND resource.labels.location="%s"`, vertexAIService, location)
+	query := url.Values{
+		"filter":                       {filter},
+		"interval.startTime":           {now.Add(-quotaUsageWindow).UTC().Format(time.RFC3339)},
+		"interval.endTime":             {now.UTC().Format(time.RFC3339)},
+		"aggregation.alignmentPeriod":  {"60s"},
+		"aggregation.perSeriesAligner": {"ALIGN_SUM"},
+	}
+	requestURL := fmt.Sprintf("%s/v3/projects/%s/timeSeries?%s", strings.TrimSuffix(baseURL, "/"), url.PathEscape(project), query.Encode())
+	var response struct {
+		TimeSeries []struct {
+			Metric struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metric"`
+			// Points are returned newest first.
+			Points []struct {
+				Value struct {
+					Int64Value json.Number `json:"int64Value"`
+				} `json:"value"`
+			} `json:"points"`
+		} `json:"timeSeries"`
+	}
+	if err := getJSON(ctx, httpClient, requestURL, &response); err != nil {
+		return nil, err
+	}
+	usage := make(map[string]int64)
+	for _, series := range response.TimeSeries {
+		if len(series.Points) == 0 {
+			continue
+		}
+		value, err := strconv.ParseInt(series.Points[0].Value.Int64Value.String(), 10, 64)
+		if err != nil {
+			continue
+		}
+		usage[series.Metric.Labels["quota_metric"]] += value
+	}
+	return usage, nil
+}
+
+// formatQuotaLimit returns a quota limit for display, spelling out unlimited quotas.
+func formatQuotaLimit(limit int64) string {
+	if limit < 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+// quotaSummary returns a human-readable summary of a quota result.
+func quotaSummary(result quotaResult) string {
+	if result.Status == "unavailable" {
+		return fmt.Sprintf("UNAVAILABLE: could not read Veo quotas for project %s (%s): %s", result.Project, result.Location, result.Error)
+	}
+	if len(result.Quotas) == 0 {
+		return fmt.Sprintf("No Veo-related quotas were found for project %s in %s.", result.Project, result.Location)
+	}
+	lines := []string{fmt.Sprintf("Veo quotas for project %s in %s:", result.Project, result.Location)}
+	for _, quota := range result.Quotas {
+		name := quota.DisplayName
+		if name == "" {
+			name = quota.Metric
+		}
+		if quota.Model != "" {
+			name += " (" + quota.Model + ")"
+		}
+		line := fmt.Sprintf("- %s: limit %s", name, formatQuotaLimit(quota.Limit))
+		if quota.RefreshInterval != "" {
+			line += " per " + quota.RefreshInterval
+		}
+		if quota.MetricUsage != nil {
+			line += fmt.Sprintf(", current metric usage %d", *quota.MetricUsage)
+		}
+		lines = append(lines, line)
+	}
+	if result.Status == "partial" {
+		lines = append(lines, fmt.Sprintf("Usage is unavailable: %s", result.Error))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// veoQuotaHandler is the handler for the 'veo_quota' tool. It reads the Veo-related Vertex AI
+// quota limits of the project from the Cloud Quotas API and their recent usage from Cloud
+// Monitoring. Neither API is required by the generation tools, so if they are unreachable
+// (e.g., not enabled, or the credentials lack access) the result reports the status as
+// "unavailable" or "partial" rather than failing.
+func veoQuotaHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_quota")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_quota")
+
+	args := request.GetArguments()
+	project, _ := args["project"].(string)
+	if project = strings.TrimSpace(project); project == "" {
+		project = appConfig.ProjectID
+	}
+	location, _ := args["location"].(string)
+	if location = strings.TrimSpace(location); location == "" {
+		location = appConfig.Location
+	}
+	logger.Info("Handling veo_quota request", "project", project, "location", location)
+
+	result := quotaResult{Status: "ok", Project: project, Location: location, Quotas: []veoQuota{}}
+	httpClient, err := restHTTPClient(ctx, args)
+	if err != nil {
+		if errorCode(err) == codeInvalidArgument {
+			return invalidArgumentResult(err.Error()), nil
+		}
+		result.Status = "unavailable"
+		result.Error = fmt.Sprintf("failed to load credentials: %v", err)
+	}
+
+	if httpClient != nil {
+		checkCtx, cancel := context.WithTimeout(ctx, quotaCheckTimeout)
+		defer cancel()
+		quotas, err := fetchVeoQuotaLimits(checkCtx, httpClient, cloudQuotasBaseURL, project, location)
+		if err != nil {
+			logger.Warn("Could not read quota limits", "error", err)
+			result.Status = "unavailable"
+			result.Error = fmt.Sprintf("the Cloud Quotas API (cloudquotas.googleapis.com) could not be queried. Make sure it is enabled and the credentials have a role such as roles/cloudquotas.viewer: %v", err)
+		} else {
+			result.Quotas = quotas
+			usage, err := fetchQuotaMetricUsage(checkCtx, httpClient, monitoringBaseURL, project, location, time.Now())
+			if err != nil {
+				logger.Warn("Could not read quota usage", "error", err)
+				result.Status = "partial"
+				result.Error = fmt.Sprintf("the Cloud Monitoring API (monitoring.googleapis.com) could not be queried. Make sure the credentials have a role such as roles/monitoring.viewer: %v", err)
+			} else {
+				for i := range result.Quotas {
+					if value, ok := usage[result.Quotas[i].Metric]; ok {
+						result.Quotas[i].MetricUsage = &value
+					}
+				}
+			}
+		}
+	}
+	span.SetAttributes(
+		attribute.String("status", result.Status),
+		attribute.Int("quota_count", len(result.Quotas)),
+	)
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal quota result: %v", err)), nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: quotaSummary(result)},
+			mcp.TextContent{Type: "text", Text: string(resultJSON)},
+		},
+	}, nil
+}
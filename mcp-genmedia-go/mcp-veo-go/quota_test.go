@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const testQuotaInfosPage1 = `{
+  "quotaInfos": [
+    {
+      "quotaId": "OnlinePredictionRequestsPerMinutePerProjectPerBaseModel",
+      "metric": "aiplatform.googleapis.com/online_prediction_requests_per_base_model",
+      "refreshInterval": "minute",
+      "dimensionsInfos": [
+        {"dimensions": {"region": "us-central1", "base_model": "veo-3.0-generate"}, "details": {"value": "10"}},
+        {"dimensions": {"region": "europe-west4", "base_model": "veo-3.0-generate"}, "details": {"value": "5"}},
+        {"dimensions": {"region": "us-central1", "base_model": "gemini-2.5-pro"}, "details": {"value": "100"}}
+      ]
+    }
+  ],
+  "nextPageToken": "page2"
+}`
+
+const testQuotaInfosPage2 = `{
+  "quotaInfos": [
+    {
+      "quotaId": "VideoGenerationRequestsPerMinute",
+      "metric": "aiplatform.googleapis.com/video_generation_requests",
+      "quotaDisplayName": "Video generation requests per minute",
+      "refreshInterval": "minute",
+      "dimensionsInfos": [
+        {"dimensions": {}, "details": {"value": "-1"}}
+      ]
+    }
+  ]
+}`
+
+func TestFetchVeoQuotaLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/v1/projects/p/locations/global/services/aiplatform.googleapis.com/quotaInfos") {
+			t.Errorf("unexpected path '%s'", r.URL.Path)
+		}
+		if r.URL.Query().Get("pageToken") == "page2" {
+			w.Write([]byte(testQuotaInfosPage2))
+			return
+		}
+		w.Write([]byte(testQuotaInfosPage1))
+	}))
+	defer server.Close()
+
+	quotas, err := fetchVeoQuotaLimits(t.Context(), server.Client(), server.URL, "p", "us-central1")
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if len(quotas) != 2 {
+		t.Fatalf("expected 2 Veo quotas, but got %d: %+v", len(quotas), quotas)
+	}
+	if quotas[0].Model != "veo-3.0-generate" || quotas[0].Limit != 10 || quotas[0].Location != "us-central1" {
+		t.Errorf("unexpected per-model quota: %+v", quotas[0])
+	}
+	if quotas[1].QuotaID != "VideoGenerationRequestsPerMinute" || quotas[1].Limit != -1 {
+		t.Errorf("unexpected video quota: %+v", quotas[1])
+	}
+}
+
+func TestFetchVeoQuotaLimitsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "Cloud Quotas API has not been used in project p", "status": "PERMISSION_DENIED"}}`))
+	}))
+	defer server.Close()
+
+	_, err := fetchVeoQuotaLimits(t.Context(), server.Client(), server.URL, "p", "us-central1")
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Fatalf("expected a 403 genai.APIError, but got: %v", err)
+	}
+}
+
+func TestFetchQuotaMetricUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if filter := r.URL.Query().Get("filter"); !strings.Contains(filter, `resource.labels.location="us-central1"`) {
+			t.Errorf("expected the filter to select the location, but got '%s'", filter)
+		}
+		w.Write([]byte(`{
+  "timeSeries": [
+    {"metric": {"labels": {"quota_metric": "aiplatform.googleapis.com/online_prediction_requests_per_base_model"}}, "points": [{"value": {"int64Value": "3"}}, {"value": {"int64Value": "9"}}]},
+    {"metric": {"labels": {"quota_metric": "aiplatform.googleapis.com/online_prediction_requests_per_base_model"}}, "points": [{"value": {"int64Value": "2"}}]},
+    {"metric": {"labels": {"quota_metric": "aiplatform.googleapis.com/video_generation_requests"}}, "points": []}
+  ]
+}`))
+	}))
+	defer server.Close()
+
+	usage, err := fetchQuotaMetricUsage(t.Context(), server.Client(), server.URL, "p", "us-central1", time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if value := usage["aiplatform.googleapis.com/online_prediction_requests_per_base_model"]; value != 5 {
+		t.Errorf("expected the latest points to sum to 5, but got %d", value)
+	}
+	if _, ok := usage["aiplatform.googleapis.com/video_generation_requests"]; ok {
+		t.Error("expected no usage for a series without points")
+	}
+}
+
+func TestQuotaSummary(t *testing.T) {
+	usage := int64(4)
+	result := quotaResult{
+		Status:   "partial",
+		Project:  "p",
+		Location: "us-central1",
+		Quotas:   []veoQuota{{Metric: "m", Model: "veo-3.0-generate", RefreshInterval: "minute", Limit: -1, MetricUsage: &usage}},
+		Error:    "monitoring unavailable",
+	}
+	summary := quotaSummary(result)
+	for _, expected := range []string{"m (veo-3.0-generate): limit unlimited per minute", "current metric usage 4", "Usage is unavailable: monitoring unavailable"} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expected the summary to contain '%s', but got '%s'", expected, summary)
+		}
+	}
+
+	unavailable := quotaSummary(quotaResult{Status: "unavailable", Project: "p", Location: "us-central1", Error: "denied"})
+	if !strings.HasPrefix(unavailable, "UNAVAILABLE") || !strings.Contains(unavailable, "denied") {
+		t.Errorf("unexpected unavailable summary: '%s'", unavailable)
+	}
+}
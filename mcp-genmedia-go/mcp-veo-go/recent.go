@@ -0,0 +1,116 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recentOutputsURI is the URI of the MCP resource that lists the most recent outputs.
+const recentOutputsURI = "veo://recent-outputs"
+
+// defaultRecentOutputs is the number of outputs remembered before appConfig is loaded.
+const defaultRecentOutputs = 20
+
+// recentOutput records one completed generation for the recent outputs resource.
+type recentOutput struct {
+	Prompt        string    `json:"prompt"`
+	Model         string    `json:"model"`
+	CallType      string    `json:"call_type"`
+	OperationName string    `json:"operation_name"`
+	GCSURIs       []string  `json:"gcs_uris,omitempty"`
+	LocalPaths    []string  `json:"local_paths,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// recentOutputBuffer is a fixed-size, concurrency-safe ring buffer of the most recent
+// generations. Once full, each new entry replaces the oldest one.
+type recentOutputBuffer struct {
+	mu      sync.Mutex
+	entries []recentOutput
+	next    int
+	full    bool
+}
+
+// newRecentOutputBuffer returns a buffer that remembers up to capacity outputs.
+func newRecentOutputBuffer(capacity int) *recentOutputBuffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &recentOutputBuffer{entries: make([]recentOutput, capacity)}
+}
+
+// Add records an output, evicting the oldest one if the buffer is full.
+func (b *recentOutputBuffer) Add(output recentOutput) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.next] = output
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// List returns the recorded outputs, newest first.
+func (b *recentOutputBuffer) List() []recentOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+	outputs := make([]recentOutput, 0, count)
+	for i := 1; i <= count; i++ {
+		outputs = append(outputs, b.entries[(b.next-i+len(b.entries))%len(b.entries)])
+	}
+	return outputs
+}
+
+// recentOutputs holds the outputs of the most recent generations made by this server.
+// It is resized to GENMEDIA_RECENT_OUTPUTS in main.
+var recentOutputs = newRecentOutputBuffer(defaultRecentOutputs)
+
+// recentOutputsResource returns the MCP resource that lists the most recent outputs.
+func recentOutputsResource() mcp.Resource {
+	return mcp.NewResource(
+		recentOutputsURI,
+		"Recent Veo Outputs",
+		mcp.WithResourceDescription("The most recently generated videos, newest first, with their prompts, models, output URIs, and creation times."),
+		mcp.WithMIMEType("application/json"),
+	)
+}
+
+// recentOutputsResourceHandler serves the recent outputs resource as a JSON array.
+func recentOutputsResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	jsonData, err := json.MarshalIndent(recentOutputs.List(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal recent outputs: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      recentOutputsURI,
+			MIMEType: "application/json",
+			Text:     string(jsonData),
+		},
+	}, nil
+}
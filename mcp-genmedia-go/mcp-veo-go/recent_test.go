@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRecentOutputBuffer(t *testing.T) {
+	buffer := newRecentOutputBuffer(3)
+	if outputs := buffer.List(); len(outputs) != 0 {
+		t.Fatalf("expected an empty buffer, but got %+v", outputs)
+	}
+
+	for i := 0; i < 5; i++ {
+		buffer.Add(recentOutput{Prompt: fmt.Sprintf("prompt %d", i)})
+	}
+	outputs := buffer.List()
+	expected := []string{"prompt 4", "prompt 3", "prompt 2"}
+	if len(outputs) != len(expected) {
+		t.Fatalf("expected %d outputs, but got %d", len(expected), len(outputs))
+	}
+	for i, prompt := range expected {
+		if outputs[i].Prompt != prompt {
+			t.Errorf("expected '%s', but got '%s'", prompt, outputs[i].Prompt)
+		}
+	}
+}
+
+func TestRecentOutputBufferConcurrentAdds(t *testing.T) {
+	buffer := newRecentOutputBuffer(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer.Add(recentOutput{Model: "veo-2.0-generate-001"})
+			buffer.List()
+		}()
+	}
+	wg.Wait()
+	if outputs := buffer.List(); len(outputs) != 10 {
+		t.Errorf("expected 10 outputs, but got %d", len(outputs))
+	}
+}
+
+func TestRecentOutputsResourceHandler(t *testing.T) {
+	previous := recentOutputs
+	defer func() { recentOutputs = previous }()
+	recentOutputs = newRecentOutputBuffer(2)
+	recentOutputs.Add(recentOutput{Prompt: "a fox", GCSURIs: []string{"gs://bucket/out/sample_0.mp4"}})
+
+	contents, err := recentOutputsResourceHandler(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text, ok := contents[0].(mcp.TextResourceContents)
+	if !ok {
+		t.Fatalf("expected text resource contents, but got %T", contents[0])
+	}
+	var outputs []recentOutput
+	if err := json.Unmarshal([]byte(text.Text), &outputs); err != nil {
+		t.Fatalf("failed to unmarshal resource: %v", err)
+	}
+	if len(outputs) != 1 || outputs[0].Prompt != "a fox" || outputs[0].GCSURIs[0] != "gs://bucket/out/sample_0.mp4" {
+		t.Errorf("expected the recorded output, but got %+v", outputs)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// retryableStatusCodes are the HTTP status codes returned by the Vertex AI API that
+// indicate a transient failure worth retrying.
+var retryableStatusCodes = []int{429, 500, 503}
+
+// retryBaseDelay and retryMaxDelay bound the exponential backoff between attempts.
+// They are variables so tests can shorten them.
+var (
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// isRetryableError reports whether err is a transient API or network error.
+// Context cancellation and deadline errors are never retried.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(retryableStatusCodes, apiErr.Code)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoffDelay returns the jittered delay to wait before the given retry attempt (starting at 1).
+// The delay doubles with each attempt up to retryMaxDelay, and a random jitter of up to half the
+// delay is applied so that concurrent callers do not retry in lockstep.
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryWithBackoff calls fn until it succeeds, returns a non-retryable error, or maxAttempts
+// is reached. The last error is returned unchanged so callers see the original message.
+func retryWithBackoff[T any](ctx context.Context, maxAttempts int, description string, fn func() (T, error)) (T, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	var result T
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		result, err = fn()
+		if err == nil || !isRetryableError(err) || attempt == maxAttempts {
+			return result, err
+		}
+		delay := backoffDelay(attempt)
+		loggerFromContext(ctx).Warn(description+" failed with a transient error, retrying", "attempt", attempt, "max_attempts", maxAttempts, "error", err, "delay", delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"429", genai.APIError{Code: 429}, true},
+		{"500", genai.APIError{Code: 500}, true},
+		{"503", genai.APIError{Code: 503}, true},
+		{"400", genai.APIError{Code: 400}, false},
+		{"403", genai.APIError{Code: 403}, false},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := isRetryableError(tc.err)
+			if actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	retryMaxDelay = 5 * time.Millisecond
+	defer func() {
+		retryBaseDelay = 2 * time.Second
+		retryMaxDelay = 30 * time.Second
+	}()
+
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		calls := 0
+		result, err := retryWithBackoff(context.Background(), 3, "test", func() (string, error) {
+			calls++
+			if calls < 3 {
+				return "", genai.APIError{Code: 503}
+			}
+			return "ok", nil
+		})
+		if err != nil || result != "ok" {
+			t.Errorf("expected 'ok' and no error, but got '%s' and %v", result, err)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 calls, but got %d", calls)
+		}
+	})
+
+	t.Run("stops at max attempts", func(t *testing.T) {
+		calls := 0
+		_, err := retryWithBackoff(context.Background(), 2, "test", func() (string, error) {
+			calls++
+			return "", genai.APIError{Code: 429}
+		})
+		if err == nil {
+			t.Error("expected an error, but got nil")
+		}
+		if calls != 2 {
+			t.Errorf("expected 2 calls, but got %d", calls)
+		}
+	})
+
+	t.Run("fails fast on non-retryable error", func(t *testing.T) {
+		calls := 0
+		_, err := retryWithBackoff(context.Background(), 3, "test", func() (string, error) {
+			calls++
+			return "", genai.APIError{Code: 400, Message: "bad request"}
+		})
+		if err == nil || err.Error() != "bad request" {
+			t.Errorf("expected original error 'bad request', but got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, but got %d", calls)
+		}
+	})
+}
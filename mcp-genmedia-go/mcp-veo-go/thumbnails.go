@@ -0,0 +1,125 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+)
+
+// thumbnailSuffix is appended to a video's name, without its extension, to name its thumbnail.
+const thumbnailSuffix = "_thumb.jpg"
+
+// videoThumbnailsEnabled reports whether a poster frame is extracted from each video saved
+// to a local directory (GENMEDIA_VIDEO_THUMBNAILS). It is off until appConfig is loaded.
+func videoThumbnailsEnabled() bool {
+	return appConfig != nil && appConfig.VideoThumbnails
+}
+
+// thumbnailPath returns the path of the thumbnail of a video, e.g. "out/veo_0_thumb.jpg" for
+// "out/veo_0.mp4". The same naming is used for the GCS object next to a video's URI.
+func thumbnailPath(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + thumbnailSuffix
+}
+
+// extractThumbnail decodes the first keyframe of the local video at videoPath and writes it
+// as a JPEG next to the video, returning its path. Decoding uses ffmpeg, which must be on the
+// PATH; the written file is checked to be a valid JPEG.
+func extractThumbnail(ctx context.Context, videoPath string) (string, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", fmt.Errorf("ffmpeg is required to extract thumbnails but was not found on the PATH: %w", err)
+	}
+	thumbPath := thumbnailPath(videoPath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-loglevel", "error", "-skip_frame", "nokey", "-i", videoPath, "-frames:v", "1", "-q:v", "2", thumbPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(thumbPath)
+		return "", fmt.Errorf("ffmpeg failed to extract the first keyframe of %s: %w. Output: %s", videoPath, err, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(thumbPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read thumbnail %s: %w", thumbPath, err)
+	}
+	if _, err := jpeg.DecodeConfig(bytes.NewReader(data)); err != nil {
+		os.Remove(thumbPath)
+		return "", fmt.Errorf("thumbnail %s is not a valid JPEG: %w", thumbPath, err)
+	}
+	return thumbPath, nil
+}
+
+// addThumbnail extracts the thumbnail of the video with the given index saved at videoPath
+// and records it in outputs. If the video is also in GCS, the thumbnail is uploaded next to
+// it. Failures are recorded as errors without affecting the video itself.
+func (o *videoOutputs) addThumbnail(ctx context.Context, index int, videoPath, videoGCSURI string) {
+	logger := loggerFromContext(ctx)
+	thumbPath, err := extractThumbnail(ctx, videoPath)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error extracting thumbnail for video %d: %v", index, err)
+		logger.Warn(errMsg)
+		o.Errors = append(o.Errors, errMsg)
+		return
+	}
+	logger.Info("Saved video thumbnail", "index", index, "path", thumbPath)
+	o.Thumbnails = append(o.Thumbnails, thumbPath)
+
+	if videoGCSURI == "" {
+		return
+	}
+	thumbURI := thumbnailPath(videoGCSURI)
+	if err := uploadThumbnail(ctx, thumbPath, thumbURI); err != nil {
+		errMsg := fmt.Sprintf("Error uploading thumbnail for video %d to %s: %v", index, thumbURI, err)
+		logger.Warn(errMsg)
+		o.Errors = append(o.Errors, errMsg)
+		return
+	}
+	logger.Info("Uploaded video thumbnail", "index", index, "gcs_uri", thumbURI)
+	o.ThumbnailURIs = append(o.ThumbnailURIs, thumbURI)
+}
+
+// uploadThumbnail uploads the local thumbnail at thumbPath to the GCS URI thumbURI.
+func uploadThumbnail(ctx context.Context, thumbPath, thumbURI string) error {
+	bucket, object, err := common.ParseGCSPath(thumbURI)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(thumbPath)
+	if err != nil {
+		return err
+	}
+	return common.UploadToGCS(ctx, bucket, object, "image/jpeg", data)
+}
+
+// thumbnailsMessage describes the thumbnails saved for the videos, or returns an empty string
+// if there are none.
+func thumbnailsMessage(outputs *videoOutputs) string {
+	if len(outputs.Thumbnails) == 0 {
+		return ""
+	}
+	message := fmt.Sprintf("Thumbnails saved locally: %s.", strings.Join(outputs.Thumbnails, ", "))
+	if len(outputs.ThumbnailURIs) > 0 {
+		message += fmt.Sprintf(" Thumbnails saved to GCS: %s.", strings.Join(outputs.ThumbnailURIs, ", "))
+	}
+	return message
+}
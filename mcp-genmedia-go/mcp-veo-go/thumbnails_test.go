@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnailPath(t *testing.T) {
+	testCases := []struct {
+		videoPath string
+		expected  string
+	}{
+		{"out/veo_0.mp4", "out/veo_0_thumb.jpg"},
+		{"gs://bucket/videos/veo_0.mp4", "gs://bucket/videos/veo_0_thumb.jpg"},
+		{"out/video", "out/video_thumb.jpg"},
+	}
+
+	for _, tc := range testCases {
+		if actual := thumbnailPath(tc.videoPath); actual != tc.expected {
+			t.Errorf("thumbnailPath(%q): expected '%s', but got '%s'", tc.videoPath, tc.expected, actual)
+		}
+	}
+}
+
+func TestExtractThumbnail(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	videoPath := filepath.Join(t.TempDir(), "veo_0.mp4")
+	if output, err := exec.Command("ffmpeg", "-loglevel", "error", "-f", "lavfi", "-i", "testsrc=duration=1:size=320x180:rate=24", "-pix_fmt", "yuv420p", videoPath).CombinedOutput(); err != nil {
+		t.Fatalf("failed to create a test video: %v: %s", err, output)
+	}
+
+	thumbPath, err := extractThumbnail(t.Context(), videoPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if thumbPath != filepath.Join(filepath.Dir(videoPath), "veo_0_thumb.jpg") {
+		t.Errorf("unexpected thumbnail path '%s'", thumbPath)
+	}
+	if _, err := os.Stat(thumbPath); err != nil {
+		t.Errorf("expected the thumbnail to be written: %v", err)
+	}
+
+	if _, err := extractThumbnail(t.Context(), filepath.Join(t.TempDir(), "missing.mp4")); err == nil {
+		t.Error("expected an error for a missing video")
+	}
+}
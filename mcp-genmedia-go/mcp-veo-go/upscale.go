@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements an MCP server for Google's Veo models.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// upscaleVideoMimeTypes lists the MIME types of source videos that veo_upscale accepts.
+var upscaleVideoMimeTypes = []string{"video/mp4"}
+
+// veoModelsSupportingUpscale returns the sorted names of the non-deprecated models that can
+// upscale videos, for error messages.
+func veoModelsSupportingUpscale() []string {
+	var names []string
+	for name, info := range common.SupportedVeoModels {
+		if info.SupportsUpscale && !info.Deprecated {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// upscaleParams holds the validated arguments of a veo_upscale request.
+type upscaleParams struct {
+	VideoURI   string
+	Model      string
+	Resolution string
+}
+
+// parseUpscaleParams extracts and validates the arguments of a veo_upscale request. The source
+// video must be an MP4 in GCS, the model must support upscaling, and the target resolution
+// must be one the model supports.
+func parseUpscaleParams(ctx context.Context, args map[string]interface{}) (*upscaleParams, error) {
+	videoURI, _ := args["video_uri"].(string)
+	videoURI = strings.TrimSpace(videoURI)
+	if videoURI == "" {
+		return nil, fmt.Errorf("video_uri must be a non-empty GCS URI of the video to upscale")
+	}
+	if !strings.HasPrefix(videoURI, "gs://") {
+		return nil, fmt.Errorf("video_uri '%s' must be a GCS URI (gs://...); only videos stored in Cloud Storage can be upscaled", videoURI)
+	}
+	if !common.IsAllowedMimeType(inferMimeTypeFromURI(videoURI), upscaleVideoMimeTypes) {
+		return nil, fmt.Errorf("video_uri '%s' is not a supported video. Supported types are: [%s]", videoURI, strings.Join(upscaleVideoMimeTypes, ", "))
+	}
+	resolutionArg, _ := args["resolution"].(string)
+	if strings.TrimSpace(resolutionArg) == "" {
+		return nil, fmt.Errorf("resolution must be a non-empty target resolution (e.g., '1080p')")
+	}
+
+	model, modelDetails, err := resolveModelArg(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if !modelDetails.SupportsUpscale {
+		capable := veoModelsSupportingUpscale()
+		if len(capable) == 0 {
+			return nil, fmt.Errorf("model %s does not support video upscaling, and no supported Veo model does yet because the genai SDK has no video upscale method. Generate the video at the target resolution instead", model)
+		}
+		return nil, fmt.Errorf("model %s does not support video upscaling. Models that do: [%s]", model, strings.Join(capable, ", "))
+	}
+	resolution, err := resolveResolution(args, model, modelDetails)
+	if err != nil {
+		return nil, err
+	}
+	return &upscaleParams{VideoURI: videoURI, Model: model, Resolution: resolution}, nil
+}
+
+// veoUpscaleHandler is the handler for the 'veo_upscale' tool. Requests are validated, including
+// whether the model supports upscaling (VeoModelInfo.SupportsUpscale), so callers get a clear
+// error. No model supports it yet; once one does, the upscale call belongs after the checks.
+func veoUpscaleHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tr := otel.Tracer(serviceName)
+	ctx, span := tr.Start(ctx, "veo_upscale")
+	defer span.End()
+	ctx, logger := startRequestLogger(ctx, "veo_upscale")
+
+	params, err := parseUpscaleParams(ctx, request.GetArguments())
+	if err != nil {
+		return invalidArgumentResult(err.Error()), nil
+	}
+	span.SetAttributes(attribute.String("model", params.Model), attribute.String("resolution", params.Resolution))
+	logger.Info("Handling Veo upscale request", "model", params.Model, "video_uri", params.VideoURI, "resolution", params.Resolution)
+	return codedToolResultError(codeUnimplemented, fmt.Sprintf("upscaling '%s' to %s with model %s is not implemented: the genai SDK has no Veo video upscale method", params.VideoURI, params.Resolution, params.Model)), nil
+}
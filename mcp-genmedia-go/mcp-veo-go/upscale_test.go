@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestVeoUpscaleHandler(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]interface{}
+		expected string
+	}{
+		{"missing video", map[string]interface{}{"resolution": "1080p"}, "video_uri must be a non-empty GCS URI"},
+		{"local video", map[string]interface{}{"video_uri": "/tmp/video.mp4", "resolution": "1080p"}, "must be a GCS URI"},
+		{"unsupported video", map[string]interface{}{"video_uri": "gs://bucket/video.mov", "resolution": "1080p"}, "is not a supported video"},
+		{"missing resolution", map[string]interface{}{"video_uri": "gs://bucket/video.mp4"}, "resolution must be a non-empty target resolution"},
+		{"invalid model", map[string]interface{}{"video_uri": "gs://bucket/video.mp4", "resolution": "1080p", "model": "not-a-model"}, "is not a valid or supported model name"},
+		{"unsupported model", map[string]interface{}{"video_uri": "gs://bucket/video.mp4", "resolution": "1080p", "model": "veo-3.1-generate-preview"}, "model veo-3.1-generate-preview does not support video upscaling"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tc.args
+			result, err := veoUpscaleHandler(t.Context(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !result.IsError || !strings.HasPrefix(text, "[code=INVALID_ARGUMENT]") || !strings.Contains(text, tc.expected) {
+				t.Errorf("expected an INVALID_ARGUMENT result containing '%s', but got '%s'", tc.expected, text)
+			}
+		})
+	}
+}
@@ -17,101 +17,926 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"log"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
 )
 
 // inferMimeTypeFromURI attempts to determine the MIME type of a file based on its extension.
+// Whether the type is accepted is decided separately by each tool's allowlist.
 func inferMimeTypeFromURI(uri string) string {
-	ext := strings.ToLower(filepath.Ext(uri))
-	switch ext {
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
+	return common.InferMimeTypeFromPath(uri)
+}
+
+// mimeTypesRequired reports whether GENMEDIA_REQUIRE_MIME_TYPES disables inferring the MIME
+// type of a GCS input image from its extension. It is off until appConfig is loaded.
+func mimeTypesRequired() bool {
+	return appConfig != nil && appConfig.RequireMimeTypes
+}
+
+// loadLocalOrInlineImage reads image bytes from a local file path, a data URI
+// (data:image/png;base64,...), or a raw base64-encoded string. It returns the bytes,
+// the MIME type detected from the content, and a short description of the source
+// that is safe to log (inline data is not echoed back). Images larger than
+// maxInputImageBytes are rejected.
+func loadLocalOrInlineImage(input string) ([]byte, string, string, error) {
+	if strings.HasPrefix(input, "data:") {
+		data, _, err := decodeDataURI(input)
+		if err != nil {
+			return nil, "", "", err
+		}
+		source := inlineDataSource(data)
+		if err := checkInputImageSize(source, int64(len(data))); err != nil {
+			return nil, "", "", err
+		}
+		return data, http.DetectContentType(data), source, nil
+	}
+
+	if info, statErr := os.Stat(input); statErr == nil {
+		if err := checkInputImageSize(input, info.Size()); err != nil {
+			return nil, "", "", err
+		}
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to read local file %s: %w", input, err)
+		}
+		return data, http.DetectContentType(data), input, nil
+	}
+
+	// Not an existing file; try to interpret the input as raw base64 image data.
+	if data, err := base64.StdEncoding.DecodeString(input); err == nil && len(data) > 0 {
+		detected := http.DetectContentType(data)
+		if strings.HasPrefix(detected, "image/") {
+			source := fmt.Sprintf("<inline base64 data, %s>", common.FormatBytes(int64(len(data))))
+			if err := checkInputImageSize(source, int64(len(data))); err != nil {
+				return nil, "", "", err
+			}
+			return data, detected, source, nil
+		}
+	}
+
+	return nil, "", "", fmt.Errorf("'%s' is not a GCS URI, an existing local file, or valid base64 image data", input)
+}
+
+// decodeDataURI decodes a base64 data URI (data:<mime>;base64,<data>). It returns the bytes
+// and the MIME type declared in the header, lowercased, which may be empty.
+func decodeDataURI(input string) ([]byte, string, error) {
+	commaIdx := strings.Index(input, ",")
+	if !strings.HasPrefix(input, "data:") || commaIdx == -1 || !strings.HasSuffix(input[:commaIdx], ";base64") {
+		return nil, "", fmt.Errorf("data URI must be base64-encoded (data:<mime>;base64,<data>)")
+	}
+	data, err := base64.StdEncoding.DecodeString(input[commaIdx+1:])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode base64 data URI: %w", err)
+	}
+	mimeType := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(input[len("data:"):commaIdx], ";base64")))
+	return data, mimeType, nil
+}
+
+// inlineDataSource describes inline image data for logs and span attributes without
+// echoing the data back.
+func inlineDataSource(data []byte) string {
+	return fmt.Sprintf("<inline data URI, %s>", common.FormatBytes(int64(len(data))))
+}
+
+// supportedOutputFormats lists the accepted values for the output_format parameter.
+var supportedOutputFormats = []string{"text", "json"}
+
+// parseOutputFormat reads the optional 'output_format' argument, defaulting to "text".
+func parseOutputFormat(args map[string]interface{}) (string, error) {
+	formatArg, _ := args["output_format"].(string)
+	outputFormat := strings.ToLower(strings.TrimSpace(formatArg))
+	if outputFormat == "" {
+		return "text", nil
+	}
+	if !slices.Contains(supportedOutputFormats, outputFormat) {
+		return "", fmt.Errorf("output_format '%s' is not supported. Accepted values are: [%s]", formatArg, strings.Join(supportedOutputFormats, ", "))
+	}
+	return outputFormat, nil
+}
+
+// parseLabels parses the optional 'labels' argument, a JSON string holding an object of
+// string keys and values, and validates it against the Google Cloud label constraints.
+func parseLabels(args map[string]interface{}) (map[string]string, error) {
+	labelsJSON, ok := args["labels"].(string)
+	if !ok || strings.TrimSpace(labelsJSON) == "" {
+		return nil, nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(labelsJSON), &labels); err != nil {
+		return nil, fmt.Errorf("Failed to parse 'labels' JSON: %v. It must be an object of string keys and values, e.g. '{\"team\": \"marketing\"}'", err)
+	}
+	if err := common.ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// parseReferenceImages parses the optional 'reference_images' argument, a JSON string
+// holding an array of objects with a 'uri' (GCS URI) and a 'type' ('ASSET' or 'STYLE').
+// The argument may be a native array or a JSON string encoding the array. Each entry may
+// carry an optional 'weight' between 0 and 1; the genai SDK has no field for reference
+// weights, so valid weights are accepted but ignored with a warning. Each entry may also carry
+// a 'mime_type', which is otherwise inferred from the URI and is required when
+// GENMEDIA_REQUIRE_MIME_TYPES is set.
+// It returns an error if reference images are provided for a model that does not support
+// them, if the array is malformed, or if every entry is invalid. Individual entries with an
+// invalid URI, MIME type, reference type, or weight are skipped, and a warning describing each
+// skipped entry is returned so it can be surfaced to the user.
+func parseReferenceImages(ctx context.Context, args map[string]interface{}, modelName string) ([]*genai.VideoGenerationReferenceImage, []string, error) {
+	var refImagesJSON []byte
+	switch v := args["reference_images"].(type) {
+	case nil:
+		return nil, nil, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil, nil
+		}
+		refImagesJSON = []byte(v)
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, nil, nil
+		}
+		// Re-encode the decoded array so both forms share the same validation below.
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to read 'reference_images': %v", err)
+		}
+		refImagesJSON = encoded
 	default:
-		return ""
+		return nil, nil, fmt.Errorf("'reference_images' must be an array of objects or a JSON string, got %T", v)
+	}
+
+	modelInfo := common.SupportedVeoModels[modelName]
+	if !modelInfo.SupportsReferenceImages {
+		return nil, nil, fmt.Errorf("Providing reference images is not supported on model '%s'.", modelName)
+	}
+
+	var refImageInputs []struct {
+		URI      string   `json:"uri"`
+		Type     string   `json:"type"`
+		Weight   *float64 `json:"weight"`
+		MimeType string   `json:"mime_type"`
+	}
+
+	if err := json.Unmarshal(refImagesJSON, &refImageInputs); err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse 'reference_images': %v. Please provide an array of objects, each with 'uri' and 'type'.", err)
+	}
+	if len(refImageInputs) > modelInfo.MaxReferenceImages {
+		return nil, nil, fmt.Errorf("%d reference images were provided, but model '%s' accepts at most %d.", len(refImageInputs), modelName, modelInfo.MaxReferenceImages)
+	}
+
+	var referenceImages []*genai.VideoGenerationReferenceImage
+	var warnings []string
+	skip := func(i int, reason string) {
+		warning := fmt.Sprintf("reference image %d skipped: %s", i, reason)
+		loggerFromContext(ctx).Warn("Skipping reference image", "index", i, "reason", reason)
+		warnings = append(warnings, warning)
+	}
+	weighted := 0
+	for i, input := range refImageInputs {
+		trimmedURI := strings.TrimSpace(input.URI)
+		if !strings.HasPrefix(trimmedURI, "gs://") {
+			skip(i, fmt.Sprintf("invalid URI '%s' (must be a GCS URI starting with 'gs://')", trimmedURI))
+			continue
+		}
+		mimeType := strings.ToLower(strings.TrimSpace(input.MimeType))
+		if mimeType == "" {
+			if mimeTypesRequired() {
+				return nil, nil, fmt.Errorf("reference_images[%d] needs a mime_type for '%s' because GENMEDIA_REQUIRE_MIME_TYPES disables inferring it from the extension. Supported types for model %s are: [%s]", i, trimmedURI, modelName, strings.Join(modelInfo.ReferenceImageMimeTypes, ", "))
+			}
+			mimeType = inferMimeTypeFromURI(trimmedURI)
+		}
+		if mimeType == "" {
+			skip(i, fmt.Sprintf("could not infer MIME type for '%s'", trimmedURI))
+			continue
+		}
+		if !slices.Contains(modelInfo.ReferenceImageMimeTypes, mimeType) {
+			skip(i, fmt.Sprintf("MIME type %s of '%s' is not supported by model %s", mimeType, trimmedURI, modelName))
+			continue
+		}
+
+		var refType genai.VideoGenerationReferenceType
+		switch strings.ToUpper(strings.TrimSpace(input.Type)) {
+		case "ASSET":
+			refType = genai.VideoGenerationReferenceTypeAsset
+		case "STYLE":
+			refType = genai.VideoGenerationReferenceTypeStyle
+		default:
+			skip(i, fmt.Sprintf("invalid type '%s' for '%s' (must be 'ASSET' or 'STYLE')", input.Type, trimmedURI))
+			continue
+		}
+
+		if input.Weight != nil {
+			if *input.Weight < 0 || *input.Weight > 1 {
+				skip(i, fmt.Sprintf("weight %v for '%s' is out of range (must be between 0 and 1)", *input.Weight, trimmedURI))
+				continue
+			}
+			weighted++
+		}
+
+		imageForRef := &genai.Image{GCSURI: trimmedURI, MIMEType: mimeType}
+		referenceImages = append(referenceImages, &genai.VideoGenerationReferenceImage{Image: imageForRef, ReferenceType: refType})
+	}
+
+	if len(refImageInputs) > 0 && len(referenceImages) == 0 {
+		return nil, nil, fmt.Errorf("all %d reference images were invalid: %s", len(refImageInputs), strings.Join(warnings, "; "))
+	}
+	if weighted > 0 {
+		warning := fmt.Sprintf("weights on %d reference image(s) were ignored: the Veo API does not support weighting reference images, so all references are applied unweighted", weighted)
+		loggerFromContext(ctx).Warn("Ignoring reference image weights; the Veo API does not support them", "weighted_images", weighted)
+		warnings = append(warnings, warning)
 	}
+	return referenceImages, warnings, nil
 }
 
-// parseCommonVideoParams extracts and validates video generation parameters from the request arguments.
-func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Config) (string, string, string, string, int32, int32, bool, error) {
-	// Model
+// addResultWarnings appends a warning summary to a successful tool result.
+// Error results and empty warning lists are left unchanged.
+func addResultWarnings(result *mcp.CallToolResult, warnings []string) *mcp.CallToolResult {
+	if result == nil || result.IsError || len(warnings) == 0 {
+		return result
+	}
+	result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("Warnings: %s.", strings.Join(warnings, "; "))))
+	return result
+}
+
+// addResultNote appends an informational note to a successful tool result.
+func addResultNote(result *mcp.CallToolResult, note string) *mcp.CallToolResult {
+	if result == nil || result.IsError || note == "" {
+		return result
+	}
+	result.Content = append(result.Content, mcp.NewTextContent(note))
+	return result
+}
+
+// modelFromOperationName extracts the model ID from a long-running operation name of the form
+// "projects/.../locations/.../publishers/google/models/<model>/operations/<id>".
+// It returns "veo" if the model segment cannot be found.
+func modelFromOperationName(operationName string) string {
+	parts := strings.Split(operationName, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "models" && parts[i+1] != "" {
+			return parts[i+1]
+		}
+	}
+	return "veo"
+}
+
+// supportedPersonGeneration lists the accepted values for the person_generation parameter.
+var supportedPersonGeneration = []string{"allow_adult", "dont_allow", "allow_all"}
+
+// supportedCompressionQuality lists the accepted values for the compression_quality parameter.
+var supportedCompressionQuality = []string{"optimized", "lossless"}
+
+// inputImageMimeTypes is the allowlist of MIME types for i2v input images and interpolation
+// frames. Veo only accepts JPEG and PNG here, even though other types are recognized.
+var inputImageMimeTypes = []string{"image/jpeg", "image/png"}
+
+// isSupportedInputImageMimeType reports whether a MIME type is accepted for i2v input
+// images and interpolation frames.
+func isSupportedInputImageMimeType(mimeType string) bool {
+	return common.IsAllowedMimeType(mimeType, inputImageMimeTypes)
+}
+
+// checkFrameMimeTypes compares the MIME types of the first and last interpolation frames.
+// Mismatched types return an error if strict is true, or otherwise a warning to surface to the user.
+func checkFrameMimeTypes(ctx context.Context, firstFrameMimeType, lastFrameMimeType string, strict bool) (string, error) {
+	if firstFrameMimeType == lastFrameMimeType {
+		return "", nil
+	}
+	msg := fmt.Sprintf("first and last frames have different MIME types (%s and %s); using the same format for both frames is recommended", firstFrameMimeType, lastFrameMimeType)
+	if strict {
+		return "", fmt.Errorf("%s", msg)
+	}
+	loggerFromContext(ctx).Warn("First and last frames have different MIME types", "first_frame_mime_type", firstFrameMimeType, "last_frame_mime_type", lastFrameMimeType)
+	return msg, nil
+}
+
+// frameAspectRatioTolerance is the relative difference between the aspect ratios of the first
+// and last interpolation frames above which they are reported as incompatible. It allows for
+// the rounding of pixel dimensions (e.g., 1920x1080 and 1280x720 are both 16:9).
+const frameAspectRatioTolerance = 0.01
+
+// checkFrameAspectRatios compares the dimensions of the first and last interpolation frames.
+// Aspect ratios that differ by more than frameAspectRatioTolerance return an error if strict is
+// true, or otherwise a warning to surface to the user.
+func checkFrameAspectRatios(firstWidth, firstHeight, lastWidth, lastHeight int, strict bool) (string, error) {
+	if firstWidth <= 0 || firstHeight <= 0 || lastWidth <= 0 || lastHeight <= 0 {
+		return "", nil
+	}
+	firstRatio := float64(firstWidth) / float64(firstHeight)
+	lastRatio := float64(lastWidth) / float64(lastHeight)
+	if math.Abs(firstRatio-lastRatio)/math.Max(firstRatio, lastRatio) <= frameAspectRatioTolerance {
+		return "", nil
+	}
+	msg := fmt.Sprintf("first and last frames have different aspect ratios (%dx%d and %dx%d), which can cause artifacts or API errors; using frames of the same dimensions is recommended", firstWidth, firstHeight, lastWidth, lastHeight)
+	if strict {
+		return "", fmt.Errorf("%s", msg)
+	}
+	return msg, nil
+}
+
+// VideoParams holds the generation parameters shared by all Veo tools.
+type VideoParams struct {
+	GCSBucket   string
+	OutputDir   string
+	Model       string
+	AspectRatio string
+	// AspectRatios lists every requested aspect ratio; AspectRatio is the first.
+	// Only veo_t2v accepts more than one.
+	AspectRatios     []string
+	Resolution       string
+	NumberOfVideos   int32
+	DurationSecs     int32
+	GenerateAudio    bool
+	Seed             *int32
+	PersonGeneration string
+	// Style is the lowercased 'style' tag, or empty if none was requested. StyleText is the
+	// text it appends to the prompt.
+	Style     string
+	StyleText string
+	// CompressionQuality is "optimized" or "lossless", or empty for the API default.
+	CompressionQuality string
+	// Labels are applied as custom metadata to the generated GCS objects.
+	Labels map[string]string
+	// StorageClass is the GCS storage class the outputs are moved to, or empty to keep the bucket default.
+	StorageClass string
+	// RetentionDays, if positive, marks the GCS outputs for deletion after that many days.
+	RetentionDays int
+	// OutputFilenamePrefix, if set, names the outputs <prefix>_<index>.mp4. It is already sanitized.
+	OutputFilenamePrefix string
+	// OutputURI, if set, is the GCS object (gs://bucket/path/name.mp4) the single output is
+	// moved to. GCSBucket is then the folder that contains it.
+	OutputURI string
+	// OutputFormat is "text" (human-readable) or "json" (machine-readable) tool results.
+	OutputFormat string
+	// DryRun validates and resolves the request without calling the Veo API.
+	DryRun bool
+	// ForceRegenerate skips any cached result for the request and always calls the Veo API.
+	// It has no effect unless result caching is enabled.
+	ForceRegenerate bool
+	// Timeout, if positive, caps how long the generation may run, including queueing and polling.
+	Timeout time.Duration
+	// Warnings are non-fatal notices, such as a model deprecation, to include in the result.
+	Warnings []string
+}
+
+// requestTimeoutError is the cause of a context canceled by the timeout_seconds argument,
+// which lets a stopped generation be told apart from a client cancellation.
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e requestTimeoutError) Error() string {
+	return fmt.Sprintf("the request timeout of %v was exceeded", e.timeout)
+}
+
+// WithTimeout returns a child of ctx that is canceled once the request's timeout_seconds have
+// elapsed, or ctx itself if no timeout was given. The caller must call the returned cancel function.
+func (p *VideoParams) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, p.Timeout, requestTimeoutError{timeout: p.Timeout})
+}
+
+// StyledPrompt returns prompt with the text of the requested style appended, or prompt
+// itself if no style was requested.
+func (p *VideoParams) StyledPrompt(prompt string) string {
+	return applyStyleText(prompt, p.StyleText)
+}
+
+// OutputObjectOptions returns the settings applied to the generated GCS objects.
+func (p *VideoParams) OutputObjectOptions() outputObjectOptions {
+	return outputObjectOptions{
+		Labels:         p.Labels,
+		StorageClass:   p.StorageClass,
+		RetentionDays:  p.RetentionDays,
+		FilenamePrefix: p.OutputFilenamePrefix,
+		OutputURI:      p.OutputURI,
+	}
+}
+
+// GenerateVideosConfig builds the base genai.GenerateVideosConfig for these parameters.
+// Tool-specific fields (e.g., LastFrame, NegativePrompt) are set by the caller.
+func (p *VideoParams) GenerateVideosConfig() *genai.GenerateVideosConfig {
+	durationSecs := p.DurationSecs
+	config := &genai.GenerateVideosConfig{
+		NumberOfVideos:  p.NumberOfVideos,
+		AspectRatio:     p.AspectRatio,
+		OutputGCSURI:    p.GCSBucket,
+		DurationSeconds: &durationSecs,
+		Seed:            p.Seed,
+	}
+	if p.Resolution != "" {
+		config.Resolution = p.Resolution
+	}
+	if p.PersonGeneration != "" {
+		config.PersonGeneration = p.PersonGeneration
+	}
+	if p.CompressionQuality != "" {
+		config.CompressionQuality = genai.VideoCompressionQuality(strings.ToUpper(p.CompressionQuality))
+	}
+	if p.GenerateAudio {
+		generateAudio := true
+		config.GenerateAudio = &generateAudio
+	}
+	return config
+}
+
+// parseOutputURI validates an output_uri, which must name a single .mp4 object in GCS, and
+// returns it along with the folder that contains it, for use as the API's output location.
+func parseOutputURI(outputURI string) (string, string, error) {
+	outputURI = strings.TrimSpace(outputURI)
+	if _, objectName, err := common.ParseGCSPath(outputURI); err != nil {
+		return "", "", fmt.Errorf("output_uri must be a full GCS object URI such as gs://bucket/path/name.mp4: %v", err)
+	} else if strings.HasSuffix(objectName, "/") || !strings.EqualFold(path.Ext(objectName), ".mp4") {
+		return "", "", fmt.Errorf("output_uri '%s' must name a .mp4 object, such as gs://bucket/path/name.mp4", outputURI)
+	}
+	return outputURI, outputURI[:strings.LastIndex(outputURI, "/")+1], nil
+}
+
+// maxFilenamePrefixLength caps the sanitized output_filename_prefix, leaving room for the
+// index and extension within common filesystem name limits.
+const maxFilenamePrefixLength = 100
+
+// sanitizeFilenamePrefix makes an output filename prefix safe for local filesystems and GCS
+// object names. ASCII letters, digits, '-', '_' and '.' are kept. Other characters are replaced
+// with '_', without doubling an underscore that precedes them, and leading or trailing
+// separators and dots are trimmed so the prefix can never name a hidden file or a parent
+// directory.
+func sanitizeFilenamePrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(prefix) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			if !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+		}
+	}
+	sanitized := strings.Trim(b.String(), "_-.")
+	if len(sanitized) > maxFilenamePrefixLength {
+		sanitized = strings.TrimRight(sanitized[:maxFilenamePrefixLength], "_-.")
+	}
+	return sanitized
+}
+
+// prefixedFilename returns the name of the output at index for a sanitized prefix, e.g. "promo_0.mp4".
+func prefixedFilename(prefix string, index int, ext string) string {
+	return fmt.Sprintf("%s_%d%s", prefix, index, ext)
+}
+
+// datePrefixedGCSURI inserts a YYYY/MM/DD folder for the UTC date of t between the bucket
+// and the rest of a GCS URI, so that gs://bucket/veo_outputs/ becomes
+// gs://bucket/2025/01/15/veo_outputs/. The result always ends with a slash.
+func datePrefixedGCSURI(gcsURI string, t time.Time) string {
+	bucket, folder, _ := strings.Cut(strings.TrimPrefix(gcsURI, "gs://"), "/")
+	uri := fmt.Sprintf("gs://%s/%s/", bucket, t.UTC().Format("2006/01/02"))
+	if folder = strings.Trim(folder, "/"); folder != "" {
+		uri += folder + "/"
+	}
+	return uri
+}
+
+// gcsBucketName matches a valid GCS bucket name: lowercase letters, digits, dashes,
+// underscores, and dots, starting and ending with a letter or digit.
+var gcsBucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*[a-z0-9]$`)
+
+// normalizeGCSBucket turns the 'bucket' argument into the canonical gs://bucket/path form
+// used as the output GCS URI: the gs:// scheme is added when missing and surrounding spaces
+// and trailing slashes are removed, so that "my-bucket/videos/" becomes
+// gs://my-bucket/videos. Other URI schemes, invalid bucket names, and empty path segments
+// are rejected.
+func normalizeGCSBucket(bucket string) (string, error) {
+	trimmed := strings.TrimSpace(bucket)
+	if scheme, rest, found := strings.Cut(trimmed, "://"); found {
+		if !strings.EqualFold(scheme, "gs") {
+			return "", fmt.Errorf("bucket '%s' must be a GCS bucket name or gs:// URI, such as my-bucket/videos or gs://my-bucket/videos", bucket)
+		}
+		trimmed = rest
+	}
+	trimmed = strings.TrimRight(trimmed, "/")
+
+	name, folder, _ := strings.Cut(trimmed, "/")
+	if len(name) < 3 || len(name) > 222 || !gcsBucketName.MatchString(name) || (!strings.Contains(name, ".") && len(name) > 63) {
+		return "", fmt.Errorf("bucket '%s' does not start with a valid GCS bucket name: bucket names are 3-63 lowercase letters, digits, dashes, underscores, or dots, and start and end with a letter or digit", bucket)
+	}
+	if folder == "" {
+		return "gs://" + name, nil
+	}
+	if slices.Contains(strings.Split(folder, "/"), "") {
+		return "", fmt.Errorf("bucket '%s' contains an empty path segment ('//')", bucket)
+	}
+	return "gs://" + name + "/" + folder, nil
+}
+
+// maxModelSuggestions is the number of closest model names offered when a model does not resolve.
+const maxModelSuggestions = 3
+
+// fallbackVeoModel is the model used when neither the request nor GENMEDIA_VEO_DEFAULT_MODEL
+// names one.
+const fallbackVeoModel = "veo-2.0-generate-001"
+
+// defaultVeoModel is the canonical model used when a request omits 'model'. It is set at
+// startup from GENMEDIA_VEO_DEFAULT_MODEL by resolveDefaultVeoModel.
+var defaultVeoModel = fallbackVeoModel
+
+// resolveDefaultVeoModel resolves the configured default model to its canonical name. An
+// empty value selects the fallback model; an unsupported one returns the fallback model
+// along with an error describing the problem. The fallback is fallbackVeoModel, or the first
+// allowed model by name if the model allowlist excludes it.
+func resolveDefaultVeoModel(configured string) (string, error) {
+	fallback := fallbackVeoModel
+	if _, ok := common.SupportedVeoModels[fallback]; !ok {
+		fallback = slices.Sorted(maps.Keys(common.SupportedVeoModels))[0]
+	}
+	configured = strings.TrimSpace(configured)
+	if configured == "" {
+		return fallback, nil
+	}
+	canonicalName, found := common.ResolveVeoModel(configured)
+	if !found {
+		if suggestions := common.SuggestVeoModels(configured, maxModelSuggestions); len(suggestions) > 0 {
+			return fallback, fmt.Errorf("GENMEDIA_VEO_DEFAULT_MODEL '%s' is not a supported Veo model. Did you mean %s?", configured, strings.Join(suggestions, ", "))
+		}
+		return fallback, fmt.Errorf("GENMEDIA_VEO_DEFAULT_MODEL '%s' is not a supported Veo model", configured)
+	}
+	return canonicalName, nil
+}
+
+// toolParamScope lists the generation tools that accept a parameter. Reason, if set, explains
+// why the other tools do not.
+type toolParamScope struct {
+	Tools  []string
+	Reason string
+}
+
+// toolSpecificParams lists the parameters that only some generation tools accept. Parameters
+// not listed here are accepted by every generation tool.
+var toolSpecificParams = map[string]toolParamScope{
+	"prompt":                {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_extend"}, Reason: "use 'prompts' to pass the prompts of a batch"},
+	"prompts":               {Tools: []string{"veo_batch_t2v"}},
+	"prompt_template":       {Tools: []string{"veo_t2v"}},
+	"prompt_vars":           {Tools: []string{"veo_t2v"}},
+	"max_concurrency":       {Tools: []string{"veo_batch_t2v"}},
+	"image_uri":             {Tools: []string{"veo_i2v"}},
+	"mime_type":             {Tools: []string{"veo_i2v"}},
+	"first_frame_uri":       {Tools: []string{"veo_interpolate"}},
+	"first_frame_mime_type": {Tools: []string{"veo_interpolate"}},
+	"last_frame_uri":        {Tools: []string{"veo_interpolate"}},
+	"last_frame_mime_type":  {Tools: []string{"veo_interpolate"}},
+	"video_uri":             {Tools: []string{"veo_extend"}},
+	"extension_duration":    {Tools: []string{"veo_extend"}},
+	"source_duration":       {Tools: []string{"veo_extend"}},
+	"negative_prompt":       {Tools: []string{"veo_t2v", "veo_i2v", "veo_batch_t2v"}},
+	"enhance_prompt":        {Tools: []string{"veo_t2v", "veo_i2v", "veo_batch_t2v"}},
+	"reference_images":      {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_batch_t2v"}},
+	"duration":              {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_batch_t2v"}, Reason: "use 'extension_duration' to set the length of an extension"},
+	"aspect_ratio":          {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_batch_t2v"}, Reason: "extensions keep the aspect ratio of the source video"},
+	"resolution":            {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_batch_t2v"}, Reason: "extensions keep the resolution of the source video"},
+	"style":                 {Tools: []string{"veo_t2v", "veo_i2v", "veo_interpolate", "veo_batch_t2v"}, Reason: "extensions continue the style of the source video"},
+}
+
+// checkToolParams rejects arguments that the tool does not accept but another generation tool
+// does (e.g., 'last_frame_uri' on veo_t2v), instead of silently ignoring them. Null and empty
+// values are treated as not provided.
+func checkToolParams(tool string, args map[string]interface{}) error {
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(toolSpecificParams)) {
+		scope := toolSpecificParams[name]
+		if slices.Contains(scope.Tools, tool) || !isArgProvided(args[name]) {
+			continue
+		}
+		problem := fmt.Sprintf("'%s' is only accepted by %s", name, strings.Join(scope.Tools, ", "))
+		if scope.Reason != "" {
+			problem += "; " + scope.Reason
+		}
+		problems = append(problems, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s does not accept some of the provided parameters: %s", tool, strings.Join(problems, ". "))
+	}
+	return nil
+}
+
+// isArgProvided reports whether an argument has a value, treating null, blank strings, and
+// empty arrays as not provided.
+func isArgProvided(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// resolveModelArg resolves the 'model' argument (defaulting to defaultVeoModel) to its
+// canonical name and model details.
+func resolveModelArg(ctx context.Context, args map[string]interface{}) (string, common.VeoModelInfo, error) {
 	modelInput, ok := args["model"].(string)
 	if !ok || modelInput == "" {
-		modelInput = "veo-2.0-generate-001"
+		modelInput = defaultVeoModel
 	}
 	canonicalName, found := common.ResolveVeoModel(modelInput)
 	if !found {
-		return "", "", "", "", 0, 0, false, fmt.Errorf("model '%s' is not a valid or supported model name", modelInput)
+		if suggestions := common.SuggestVeoModels(modelInput, maxModelSuggestions); len(suggestions) > 0 {
+			return "", common.VeoModelInfo{}, fmt.Errorf("model '%s' is not a valid or supported model name. Did you mean %s?", modelInput, strings.Join(suggestions, ", "))
+		}
+		return "", common.VeoModelInfo{}, fmt.Errorf("model '%s' is not a valid or supported model name", modelInput)
 	}
-	model := canonicalName
-	modelDetails := common.SupportedVeoModels[model]
-
-	// GCS Bucket
-	gcsBucket, _ := args["bucket"].(string)
-	if gcsBucket != "" {
-		gcsBucket = common.EnsureGCSPathPrefix(gcsBucket)
-	} else if appConfig.GenmediaBucket != "" {
-		gcsBucket = fmt.Sprintf("gs://%s/veo_outputs/", appConfig.GenmediaBucket)
-		log.Printf("Handler: 'bucket' parameter not provided, using default constructed from GENMEDIA_BUCKET: %s", gcsBucket)
+	if !strings.EqualFold(modelInput, canonicalName) {
+		loggerFromContext(ctx).Info("Interpreted model name", "model_input", modelInput, "model", canonicalName)
 	}
+	return canonicalName, common.SupportedVeoModels[canonicalName], nil
+}
 
-	// Output Directory
-	outputDir, _ := args["output_directory"].(string)
+// numVideosAliases lists the accepted names for the video count argument, most specific
+// first. 'num_videos' is the canonical name advertised in the tool schemas.
+var numVideosAliases = []string{"num_videos", "number_of_videos", "sample_count"}
 
-	// Number of Videos
+// readNumVideosArg returns the video count from whichever of numVideosAliases are present.
+// Aliases that agree are accepted; conflicting values are an error rather than a silent pick.
+func readNumVideosArg(args map[string]interface{}) (float64, bool, error) {
+	var value float64
+	var found string
+	for _, name := range numVideosAliases {
+		arg, ok := args[name].(float64)
+		if !ok {
+			continue
+		}
+		if found == "" {
+			value, found = arg, name
+			continue
+		}
+		if arg != value {
+			return 0, false, fmt.Errorf("conflicting video counts: %s=%v and %s=%v; provide only num_videos", found, value, name, arg)
+		}
+	}
+	return value, found != "", nil
+}
+
+// resolveNumberOfVideos reads the 'num_videos' argument (or one of its aliases) and checks it
+// against the model's MaxVideos. Requests above the cap are clamped to it if clamp is true, or
+// rejected otherwise.
+func resolveNumberOfVideos(ctx context.Context, args map[string]interface{}, model string, modelDetails common.VeoModelInfo, clamp bool) (int32, error) {
 	var numberOfVideos int32 = 1
-	if numVideosArg, ok := args["num_videos"].(float64); ok {
+	numVideosArg, ok, err := readNumVideosArg(args)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if numVideosArg != math.Trunc(numVideosArg) {
+			return 0, fmt.Errorf("num_videos must be a whole number, got %v", numVideosArg)
+		}
 		numberOfVideos = int32(numVideosArg)
 	}
 	if numberOfVideos < 1 {
 		numberOfVideos = 1
 	}
 	if numberOfVideos > modelDetails.MaxVideos {
-		log.Printf("Warning: Requested %d videos, but model %s only supports up to %d. Adjusting to max.", numberOfVideos, model, modelDetails.MaxVideos)
+		if !clamp {
+			return 0, fmt.Errorf("num_videos %d exceeds the maximum of %d videos per request for model %s", numberOfVideos, modelDetails.MaxVideos, model)
+		}
+		loggerFromContext(ctx).Warn("Requested more videos than the model supports; adjusting to the maximum", "requested", numberOfVideos, "model", model, "max_videos", modelDetails.MaxVideos)
 		numberOfVideos = modelDetails.MaxVideos
 	}
+	return numberOfVideos, nil
+}
 
-	// Duration
+// resolveDuration reads the 'duration' argument, defaulting to the model's default duration,
+// and validates it against the model's supported durations or, for models without a list of
+// durations, its duration range.
+func resolveDuration(ctx context.Context, args map[string]interface{}, model string, modelDetails common.VeoModelInfo) (int32, error) {
 	var durationSecs int32 = modelDetails.DefaultDuration
 	if durationArg, ok := args["duration"].(float64); ok {
+		if durationArg != math.Trunc(durationArg) {
+			return 0, fmt.Errorf("duration must be a whole number of seconds, got %v", durationArg)
+		}
 		durationSecs = int32(durationArg)
+	} else {
+		loggerFromContext(ctx).Info("'duration' parameter not provided; using the model's default", "duration_secs", durationSecs, "model", model)
+	}
+	if !modelDetails.SupportsDuration(durationSecs) {
+		return 0, fmt.Errorf("duration '%d' is not supported by model %s. Supported durations are: %s", durationSecs, model, modelDetails.DurationsDescription())
 	}
-	validDuration := false
-	for _, d := range modelDetails.SupportedDurations {
-		if d == durationSecs {
-			validDuration = true
-			break
+	return durationSecs, nil
+}
+
+// parseAspectRatios reads the 'aspect_ratio' argument, which may be a single ratio, a
+// comma-separated list, a JSON array string, or a native array of strings. Duplicates
+// are removed while preserving order. It falls back to defaultAspectRatio, normally the
+// model's DefaultAspectRatio, when no ratio is given.
+func parseAspectRatios(args map[string]interface{}, defaultAspectRatio string) ([]string, error) {
+	var raw []string
+	switch v := args["aspect_ratio"].(type) {
+	case nil:
+	case string:
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "[") {
+			if err := json.Unmarshal([]byte(v), &raw); err != nil {
+				return nil, fmt.Errorf("invalid JSON array for aspect_ratio: %w", err)
+			}
+		} else if v != "" {
+			raw = strings.Split(v, ",")
+		}
+	case []interface{}:
+		for _, item := range v {
+			r, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("aspect_ratio array entries must be strings, got %T", item)
+			}
+			raw = append(raw, r)
 		}
+	default:
+		return nil, fmt.Errorf("aspect_ratio must be a string or an array of strings, got %T", v)
 	}
-	if !validDuration {
-		// Create a string representation of the supported durations for the error message
-		durationsStr := make([]string, len(modelDetails.SupportedDurations))
-		for i, d := range modelDetails.SupportedDurations {
-			durationsStr[i] = fmt.Sprintf("%d", d)
+
+	var aspectRatios []string
+	for _, r := range raw {
+		r = strings.TrimSpace(r)
+		if r != "" && !slices.Contains(aspectRatios, r) {
+			aspectRatios = append(aspectRatios, r)
+		}
+	}
+	if len(aspectRatios) == 0 {
+		aspectRatios = []string{defaultAspectRatio}
+	}
+	return aspectRatios, nil
+}
+
+// autoAspectRatio is the aspect_ratio value that asks veo_i2v to match the input image.
+const autoAspectRatio = "auto"
+
+// isAutoAspectRatio reports whether the 'aspect_ratio' argument is "auto".
+func isAutoAspectRatio(args map[string]interface{}) bool {
+	v, ok := args["aspect_ratio"].(string)
+	return ok && strings.EqualFold(strings.TrimSpace(v), autoAspectRatio)
+}
+
+// closestAspectRatio returns the ratio in supported (e.g., "16:9") closest to width:height.
+// Ratios are compared on a log scale, so that 16:9 and 9:16 are equally far from 1:1.
+// It returns an empty string if no entry of supported can be parsed.
+func closestAspectRatio(width, height int, supported []string) string {
+	target := math.Log(float64(width) / float64(height))
+	best, bestDistance := "", math.Inf(1)
+	for _, ratio := range supported {
+		var w, h float64
+		if _, err := fmt.Sscanf(ratio, "%g:%g", &w, &h); err != nil || w <= 0 || h <= 0 {
+			continue
 		}
-		return "", "", "", "", 0, 0, false, fmt.Errorf("duration '%d' is not supported by model %s. Supported durations are: [%s]", durationSecs, model, strings.Join(durationsStr, ", "))
+		if d := math.Abs(math.Log(w/h) - target); d < bestDistance {
+			best, bestDistance = ratio, d
+		}
+	}
+	return best
+}
+
+// resolveAutoAspectRatio replaces aspect_ratio "auto" with the model's supported ratio closest
+// to the input image's dimensions, returning a copy of args and a note describing the choice.
+// If the dimensions cannot be read, the model's default aspect ratio is used and ok is false,
+// so the note should be reported as a warning.
+func resolveAutoAspectRatio(ctx context.Context, args map[string]interface{}, image *genai.Image) (resolved map[string]interface{}, note string, ok bool) {
+	resolved = maps.Clone(args)
+	delete(resolved, "aspect_ratio")
+	_, modelDetails, err := resolveModelArg(ctx, args)
+	if err != nil {
+		// The invalid model is reported by parseCommonVideoParams.
+		return resolved, "", true
+	}
+	width, height, err := frameDimensions(ctx, image)
+	if err != nil {
+		resolved["aspect_ratio"] = modelDetails.DefaultAspectRatio
+		return resolved, fmt.Sprintf("aspect_ratio auto could not read the input image dimensions (%v), so the model default %s was used", err, modelDetails.DefaultAspectRatio), false
+	}
+	ratio := closestAspectRatio(width, height, modelDetails.SupportedAspectRatios)
+	if ratio == "" {
+		ratio = modelDetails.DefaultAspectRatio
+	}
+	resolved["aspect_ratio"] = ratio
+	return resolved, fmt.Sprintf("Aspect ratio: %s (selected automatically for the %dx%d input image).", ratio, width, height), true
+}
+
+// aspectRatioOutputDir returns the local output directory for one aspect ratio of a batch,
+// a subdirectory such as "16x9" so that concurrent generations do not overwrite each other.
+func aspectRatioOutputDir(outputDir, aspectRatio string) string {
+	if outputDir == "" {
+		return ""
+	}
+	return filepath.Join(outputDir, strings.ReplaceAll(aspectRatio, ":", "x"))
+}
+
+// resolveResolution reads the 'resolution' argument, defaulting to the model's native
+// resolution, and validates it against the model's supported resolutions.
+func resolveResolution(args map[string]interface{}, model string, modelDetails common.VeoModelInfo) (string, error) {
+	resolutionArg, _ := args["resolution"].(string)
+	resolution := strings.ToLower(strings.TrimSpace(resolutionArg))
+	if resolution == "" && len(modelDetails.SupportedResolutions) > 0 {
+		resolution = modelDetails.SupportedResolutions[0]
+	}
+	if resolution != "" && !slices.Contains(modelDetails.SupportedResolutions, resolution) {
+		if len(modelDetails.SupportedResolutions) == 0 {
+			return "", fmt.Errorf("resolution selection is not supported by model %s", model)
+		}
+		return "", fmt.Errorf("resolution '%s' is not supported by model %s. Supported resolutions are: [%s]", resolution, model, strings.Join(modelDetails.SupportedResolutions, ", "))
+	}
+	return resolution, nil
+}
+
+// parseCommonVideoParams extracts and validates video generation parameters from the request arguments.
+func parseCommonVideoParams(ctx context.Context, args map[string]interface{}, appConfig *common.Config) (*VideoParams, error) {
+	// Model
+	model, modelDetails, err := resolveModelArg(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	if warning := modelDetails.DeprecationWarning(); warning != "" {
+		loggerFromContext(ctx).Warn(warning, "model", model)
+		warnings = append(warnings, warning)
+	}
+
+	// Style
+	style, styleText, err := parseStyle(args, promptStyles(appConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	// Prompt Length, including any style text
+	prompt, _ := args["prompt"].(string)
+	if err := common.CheckPromptLength(model, applyStyleText(prompt, styleText), modelDetails.MaxPromptChars); err != nil {
+		return nil, err
+	}
+
+	// GCS Bucket
+	gcsBucket, _ := args["bucket"].(string)
+	if strings.TrimSpace(gcsBucket) != "" {
+		if gcsBucket, err = normalizeGCSBucket(gcsBucket); err != nil {
+			return nil, err
+		}
+	} else if appConfig.GenmediaBucket != "" {
+		if gcsBucket, err = normalizeGCSBucket(strings.TrimRight(appConfig.GenmediaBucket, "/") + "/veo_outputs"); err != nil {
+			return nil, fmt.Errorf("GENMEDIA_BUCKET is invalid: %w", err)
+		}
+		loggerFromContext(ctx).Info("'bucket' parameter not provided; using the default constructed from GENMEDIA_BUCKET", "gcs_bucket", gcsBucket)
+	}
+
+	if gcsBucket != "" && appConfig.GCSDatePrefix {
+		gcsBucket = datePrefixedGCSURI(gcsBucket, time.Now())
+	}
+
+	// Output Directory
+	outputDir, _ := args["output_directory"].(string)
+
+	// Number of Videos
+	numberOfVideos, err := resolveNumberOfVideos(ctx, args, model, modelDetails, appConfig.ClampNumVideos)
+	if err != nil {
+		return nil, err
+	}
+
+	// Duration
+	durationSecs, err := resolveDuration(ctx, args, model, modelDetails)
+	if err != nil {
+		return nil, err
 	}
 
 	// Aspect Ratio
-	finalAspectRatio, _ := args["aspect_ratio"].(string)
-	if finalAspectRatio == "" {
-		finalAspectRatio = "16:9"
+	aspectRatios, err := parseAspectRatios(args, modelDetails.DefaultAspectRatio)
+	if err != nil {
+		return nil, err
 	}
-	validRatio := false
-	for _, r := range modelDetails.SupportedAspectRatios {
-		if r == finalAspectRatio {
-			validRatio = true
-			break
+	for _, r := range aspectRatios {
+		if !slices.Contains(modelDetails.SupportedAspectRatios, r) {
+			return nil, fmt.Errorf("aspect ratio '%s' is not supported by model %s. Supported aspect ratios are: [%s]", r, model, strings.Join(modelDetails.SupportedAspectRatios, ", "))
 		}
 	}
-	if !validRatio {
-		return "", "", "", "", 0, 0, false, fmt.Errorf("aspect ratio '%s' is not supported by model %s", finalAspectRatio, model)
+	finalAspectRatio := aspectRatios[0]
+
+	// Resolution
+	finalResolution, err := resolveResolution(args, model, modelDetails)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate Audio
@@ -121,8 +946,267 @@ func parseCommonVideoParams(args map[string]interface{}, appConfig *common.Confi
 	}
 
 	if generateAudio && !modelDetails.SupportsGenerateAudio {
-		return "", "", "", "", 0, 0, false, fmt.Errorf("generate_audio is set to true, but is not supported by model %s", model)
+		return nil, fmt.Errorf("generate_audio is set to true, but is not supported by model %s", model)
+	}
+
+	// Seed
+	// The API accepts a uint32 seed, but the genai SDK transmits it as an int32,
+	// so values are limited to the range the SDK can represent without overflow.
+	var seed *int32
+	if seedArg, ok := args["seed"].(float64); ok {
+		if seedArg != math.Trunc(seedArg) {
+			return nil, fmt.Errorf("seed must be a whole number, got %v", seedArg)
+		}
+		if seedArg < 0 || seedArg > math.MaxInt32 {
+			return nil, fmt.Errorf("seed %v is out of range. It must be between 0 and %d", seedArg, math.MaxInt32)
+		}
+		seedVal := int32(seedArg)
+		seed = &seedVal
+	}
+
+	// Person Generation
+	var personGeneration string
+	if pgArg, ok := args["person_generation"].(string); ok && strings.TrimSpace(pgArg) != "" {
+		personGeneration = strings.ToLower(strings.TrimSpace(pgArg))
+		validPersonGeneration := false
+		for _, v := range supportedPersonGeneration {
+			if v == personGeneration {
+				validPersonGeneration = true
+				break
+			}
+		}
+		if !validPersonGeneration {
+			return nil, fmt.Errorf("person_generation '%s' is not supported. Accepted values are: [%s]", pgArg, strings.Join(supportedPersonGeneration, ", "))
+		}
+	}
+
+	// Compression Quality
+	var compressionQuality string
+	if cqArg, ok := args["compression_quality"].(string); ok && strings.TrimSpace(cqArg) != "" {
+		compressionQuality = strings.ToLower(strings.TrimSpace(cqArg))
+		if !slices.Contains(supportedCompressionQuality, compressionQuality) {
+			return nil, fmt.Errorf("compression_quality '%s' is not supported. Accepted values are: [%s]", cqArg, strings.Join(supportedCompressionQuality, ", "))
+		}
+	}
+
+	// Labels
+	labels, err := parseLabels(args)
+	if err != nil {
+		return nil, err
 	}
 
-	return gcsBucket, outputDir, model, finalAspectRatio, numberOfVideos, durationSecs, generateAudio, nil
-}
\ No newline at end of file
+	// Storage Class
+	var storageClass string
+	if scArg, ok := args["storage_class"].(string); ok && strings.TrimSpace(scArg) != "" {
+		storageClass = strings.ToUpper(strings.TrimSpace(scArg))
+		if !slices.Contains(common.SupportedStorageClasses, storageClass) {
+			return nil, fmt.Errorf("storage_class '%s' is not supported. Accepted values are: [%s]", scArg, strings.Join(common.SupportedStorageClasses, ", "))
+		}
+	}
+
+	// Retention Days
+	var retentionDays int
+	if rdArg, ok := args["retention_days"].(float64); ok {
+		if rdArg != math.Trunc(rdArg) || rdArg < 1 {
+			return nil, fmt.Errorf("retention_days must be a positive whole number of days, got %v", rdArg)
+		}
+		retentionDays = int(rdArg)
+	}
+
+	// Output Filename Prefix
+	var outputFilenamePrefix string
+	if prefixArg, ok := args["output_filename_prefix"].(string); ok && strings.TrimSpace(prefixArg) != "" {
+		outputFilenamePrefix = sanitizeFilenamePrefix(prefixArg)
+		if outputFilenamePrefix == "" {
+			return nil, fmt.Errorf("output_filename_prefix '%s' contains no usable characters. Use letters, digits, '-', '_' or '.'", prefixArg)
+		}
+	}
+
+	// Output URI
+	var outputURI string
+	if outputURIArg, ok := args["output_uri"].(string); ok && strings.TrimSpace(outputURIArg) != "" {
+		var outputFolder string
+		outputURI, outputFolder, err = parseOutputURI(outputURIArg)
+		if err != nil {
+			return nil, err
+		}
+		if bucketArg, _ := args["bucket"].(string); strings.TrimSpace(bucketArg) != "" {
+			return nil, fmt.Errorf("bucket and output_uri cannot both be set. output_uri already determines where the video is saved")
+		}
+		if outputFilenamePrefix != "" {
+			return nil, fmt.Errorf("output_filename_prefix and output_uri cannot both be set. output_uri already names the video")
+		}
+		// Several videos would collide on the one object name.
+		if numberOfVideos != 1 {
+			return nil, fmt.Errorf("output_uri names a single object, but %d videos were requested. Set num_videos to 1 or use bucket and output_filename_prefix instead", numberOfVideos)
+		}
+		if len(aspectRatios) > 1 {
+			return nil, fmt.Errorf("output_uri names a single object, but %d aspect ratios were requested. Request a single aspect_ratio or use bucket and output_filename_prefix instead", len(aspectRatios))
+		}
+		gcsBucket = outputFolder
+	}
+
+	// Output Format
+	outputFormat, err := parseOutputFormat(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dry Run
+	dryRun, _ := args["dry_run"].(bool)
+
+	// Force Regenerate
+	forceRegenerate, _ := args["force_regenerate"].(bool)
+
+	// Timeout
+	var timeout time.Duration
+	if timeoutArg, ok := args["timeout_seconds"].(float64); ok {
+		if timeoutArg <= 0 || math.IsInf(timeoutArg, 0) || math.IsNaN(timeoutArg) {
+			return nil, fmt.Errorf("timeout_seconds must be a positive number of seconds, got %v", timeoutArg)
+		}
+		timeout = time.Duration(timeoutArg * float64(time.Second))
+	}
+
+	return &VideoParams{
+		GCSBucket:            gcsBucket,
+		OutputDir:            outputDir,
+		Model:                model,
+		AspectRatio:          finalAspectRatio,
+		AspectRatios:         aspectRatios,
+		Resolution:           finalResolution,
+		NumberOfVideos:       numberOfVideos,
+		DurationSecs:         durationSecs,
+		GenerateAudio:        generateAudio,
+		Seed:                 seed,
+		PersonGeneration:     personGeneration,
+		Style:                style,
+		StyleText:            styleText,
+		CompressionQuality:   compressionQuality,
+		Labels:               labels,
+		StorageClass:         storageClass,
+		RetentionDays:        retentionDays,
+		OutputFilenamePrefix: outputFilenamePrefix,
+		OutputURI:            outputURI,
+		OutputFormat:         outputFormat,
+		DryRun:               dryRun,
+		ForceRegenerate:      forceRegenerate,
+		Timeout:              timeout,
+		Warnings:             warnings,
+	}, nil
+}
+
+// extendVideoMimeTypes lists the MIME types of source videos that veo_extend accepts.
+var extendVideoMimeTypes = []string{"video/mp4"}
+
+// ExtendParams holds the parameters of a veo_extend request. The embedded VideoParams
+// carry the shared options, with DurationSecs set to the length of the extension.
+type ExtendParams struct {
+	*VideoParams
+	VideoURI      string
+	VideoMimeType string
+	// SourceDurationSecs is the length of the source video, or 0 if it was not provided.
+	SourceDurationSecs int32
+	Prompt             string
+}
+
+// veoModelsSupportingExtend returns the sorted names of the non-deprecated models that can
+// extend videos, for error messages.
+func veoModelsSupportingExtend() []string {
+	var names []string
+	for name, info := range common.SupportedVeoModels {
+		if info.SupportsExtend && !info.Deprecated {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveExtensionDuration reads the 'extension_duration' argument, defaulting to the longest
+// extension the model supports, and validates it against the model's supported extensions.
+func resolveExtensionDuration(args map[string]interface{}, model string, modelDetails common.VeoModelInfo) (int32, error) {
+	if len(modelDetails.SupportedExtendDurations) == 0 {
+		return 0, fmt.Errorf("model %s does not support video extension", model)
+	}
+	extensionSecs := slices.Max(modelDetails.SupportedExtendDurations)
+	if durationArg, ok := args["extension_duration"].(float64); ok {
+		if durationArg != math.Trunc(durationArg) {
+			return 0, fmt.Errorf("extension_duration must be a whole number of seconds, got %v", durationArg)
+		}
+		extensionSecs = int32(durationArg)
+	}
+	if !slices.Contains(modelDetails.SupportedExtendDurations, extensionSecs) {
+		durationsStr := make([]string, len(modelDetails.SupportedExtendDurations))
+		for i, d := range modelDetails.SupportedExtendDurations {
+			durationsStr[i] = fmt.Sprintf("%d", d)
+		}
+		return 0, fmt.Errorf("extension_duration '%d' is not supported by model %s. Supported extension durations are: [%s]", extensionSecs, model, strings.Join(durationsStr, ", "))
+	}
+	return extensionSecs, nil
+}
+
+// parseExtendParams extracts and validates the parameters of a veo_extend request. The source
+// video must be an MP4 in GCS, and the extended video must stay within the model's
+// MaxTotalDuration when the source duration is known.
+func parseExtendParams(ctx context.Context, args map[string]interface{}, appConfig *common.Config) (*ExtendParams, error) {
+	videoURI, _ := args["video_uri"].(string)
+	videoURI = strings.TrimSpace(videoURI)
+	if videoURI == "" {
+		return nil, fmt.Errorf("video_uri must be a non-empty GCS URI of the video to extend")
+	}
+	if !strings.HasPrefix(videoURI, "gs://") {
+		return nil, fmt.Errorf("video_uri '%s' must be a GCS URI (gs://...); only videos stored in Cloud Storage can be extended", videoURI)
+	}
+	videoMimeType := inferMimeTypeFromURI(videoURI)
+	if !common.IsAllowedMimeType(videoMimeType, extendVideoMimeTypes) {
+		return nil, fmt.Errorf("video_uri '%s' is not a supported video. Supported types are: [%s]", videoURI, strings.Join(extendVideoMimeTypes, ", "))
+	}
+
+	model, modelDetails, err := resolveModelArg(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if !modelDetails.SupportsExtend {
+		return nil, fmt.Errorf("model %s does not support video extension. Models that do: [%s]", model, strings.Join(veoModelsSupportingExtend(), ", "))
+	}
+	extensionSecs, err := resolveExtensionDuration(args, model, modelDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceSecs int32
+	if sourceArg, ok := args["source_duration"].(float64); ok {
+		if sourceArg != math.Trunc(sourceArg) || sourceArg < 1 {
+			return nil, fmt.Errorf("source_duration must be a positive whole number of seconds, got %v", sourceArg)
+		}
+		if sourceArg+float64(extensionSecs) > float64(modelDetails.MaxTotalDuration) {
+			return nil, fmt.Errorf("the extended video would be %vs long (%vs source + %ds extension), which exceeds the maximum of %ds for model %s", sourceArg+float64(extensionSecs), sourceArg, extensionSecs, modelDetails.MaxTotalDuration, model)
+		}
+		sourceSecs = int32(sourceArg)
+	}
+
+	// The remaining options are shared with the generation tools. The aspect ratio and
+	// resolution follow the source video, and audio defaults to what the model supports.
+	commonArgs := maps.Clone(args)
+	delete(commonArgs, "duration")
+	delete(commonArgs, "aspect_ratio")
+	delete(commonArgs, "resolution")
+	if _, ok := commonArgs["generate_audio"].(bool); !ok {
+		commonArgs["generate_audio"] = modelDetails.SupportsGenerateAudio
+	}
+	params, err := parseCommonVideoParams(ctx, commonArgs, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	params.DurationSecs = extensionSecs
+	params.AspectRatio, params.AspectRatios, params.Resolution = "", nil, ""
+
+	prompt, _ := args["prompt"].(string)
+	return &ExtendParams{
+		VideoParams:        params,
+		VideoURI:           videoURI,
+		VideoMimeType:      videoMimeType,
+		SourceDurationSecs: sourceSecs,
+		Prompt:             strings.TrimSpace(prompt),
+	}, nil
+}
@@ -0,0 +1,942 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	common "github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
+	"google.golang.org/genai"
+)
+
+func TestInferMimeTypeFromURI(t *testing.T) {
+	testCases := []struct {
+		uri      string
+		expected string
+	}{
+		{"gs://bucket/image.png", "image/png"},
+		{"gs://bucket/image.JPG", "image/jpeg"},
+		{"gs://bucket/image.jpeg", "image/jpeg"},
+		{"gs://bucket/image.webp", "image/webp"},
+		{"gs://bucket/image.gif", "image/gif"},
+		{"gs://bucket/image.bmp", "image/bmp"},
+		{"gs://bucket/image.tiff", "image/tiff"},
+		{"gs://bucket/image", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			actual := inferMimeTypeFromURI(tc.uri)
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsSupportedInputImageMimeType(t *testing.T) {
+	testCases := []struct {
+		mimeType string
+		expected bool
+	}{
+		{"image/jpeg", true},
+		{"image/png", true},
+		{"image/webp", false},
+		{"image/tiff", false},
+		{"image/bmp", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mimeType, func(t *testing.T) {
+			if actual := isSupportedInputImageMimeType(tc.mimeType); actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveDuration(t *testing.T) {
+	modelDetails := common.SupportedVeoModels["veo-3.0-fast-generate-001"]
+	testCases := []struct {
+		name        string
+		args        map[string]interface{}
+		expected    int32
+		expectError bool
+	}{
+		{"default", map[string]interface{}{}, 8, false},
+		{"supported", map[string]interface{}{"duration": float64(4)}, 4, false},
+		{"unsupported", map[string]interface{}{"duration": float64(5)}, 0, true},
+		{"fractional", map[string]interface{}{"duration": 4.5}, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveDuration(t.Context(), tc.args, "veo-3.0-fast-generate-001", modelDetails)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveDurationRange(t *testing.T) {
+	modelDetails := common.VeoModelInfo{CanonicalName: "veo-range", DefaultDuration: 6, MinDuration: 4, MaxDuration: 8}
+	testCases := []struct {
+		name          string
+		args          map[string]interface{}
+		expected      int32
+		expectedError string
+	}{
+		{"default", map[string]interface{}{}, 6, ""},
+		{"minimum", map[string]interface{}{"duration": float64(4)}, 4, ""},
+		{"within range", map[string]interface{}{"duration": float64(5)}, 5, ""},
+		{"maximum", map[string]interface{}{"duration": float64(8)}, 8, ""},
+		{"below range", map[string]interface{}{"duration": float64(3)}, 0, "Supported durations are: 4-8"},
+		{"above range", map[string]interface{}{"duration": float64(9)}, 0, "Supported durations are: 4-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveDuration(t.Context(), tc.args, "veo-range", modelDetails)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        map[string]interface{}
+		expectedLen int
+		expectError bool
+	}{
+		{"absent", map[string]interface{}{}, 0, false},
+		{"valid", map[string]interface{}{"labels": `{"team": "marketing", "campaign": "spring-2026"}`}, 2, false},
+		{"malformed", map[string]interface{}{"labels": `{"team": }`}, 0, true},
+		{"non-string value", map[string]interface{}{"labels": `{"team": 1}`}, 0, true},
+		{"invalid key", map[string]interface{}{"labels": `{"Team": "marketing"}`}, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, err := parseLabels(tc.args)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if len(labels) != tc.expectedLen {
+				t.Errorf("expected %d labels, but got %d", tc.expectedLen, len(labels))
+			}
+		})
+	}
+}
+
+func TestResolveNumberOfVideos(t *testing.T) {
+	modelDetails := common.SupportedVeoModels["veo-3.1-generate-preview"]
+	testCases := []struct {
+		name        string
+		args        map[string]interface{}
+		clamp       bool
+		expected    int32
+		expectError bool
+	}{
+		{"default", map[string]interface{}{}, false, 1, false},
+		{"within cap", map[string]interface{}{"num_videos": float64(2)}, false, 2, false},
+		{"clamped", map[string]interface{}{"num_videos": float64(4)}, true, 2, false},
+		{"rejected", map[string]interface{}{"num_videos": float64(4)}, false, 0, true},
+		{"fractional", map[string]interface{}{"num_videos": 1.5}, true, 0, true},
+		{"number_of_videos alias", map[string]interface{}{"number_of_videos": float64(2)}, false, 2, false},
+		{"sample_count alias", map[string]interface{}{"sample_count": float64(2)}, false, 2, false},
+		{"agreeing aliases", map[string]interface{}{"num_videos": float64(2), "sample_count": float64(2)}, false, 2, false},
+		{"conflicting aliases", map[string]interface{}{"num_videos": float64(1), "number_of_videos": float64(2)}, true, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveNumberOfVideos(t.Context(), tc.args, "veo-3.1-generate-preview", modelDetails, tc.clamp)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckFrameMimeTypes(t *testing.T) {
+	testCases := []struct {
+		name          string
+		first, last   string
+		strict        bool
+		expectWarning bool
+		expectError   bool
+	}{
+		{"matching", "image/png", "image/png", true, false, false},
+		{"mismatch warns", "image/png", "image/jpeg", false, true, false},
+		{"mismatch strict", "image/png", "image/jpeg", true, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := checkFrameMimeTypes(t.Context(), tc.first, tc.last, tc.strict)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if (warning != "") != tc.expectWarning {
+				t.Errorf("expected warning: %v, but got '%s'", tc.expectWarning, warning)
+			}
+		})
+	}
+}
+
+func TestCheckFrameAspectRatios(t *testing.T) {
+	testCases := []struct {
+		name          string
+		first, last   [2]int
+		strict        bool
+		expectWarning bool
+		expectError   bool
+	}{
+		{"same dimensions", [2]int{1280, 720}, [2]int{1280, 720}, false, false, false},
+		{"same aspect ratio", [2]int{1920, 1080}, [2]int{1280, 720}, true, false, false},
+		{"within tolerance", [2]int{1920, 1080}, [2]int{1920, 1088}, true, false, false},
+		{"different aspect ratios", [2]int{1280, 720}, [2]int{720, 1280}, false, true, false},
+		{"different aspect ratios strict", [2]int{1280, 720}, [2]int{1024, 1024}, true, false, true},
+		{"unknown dimensions", [2]int{0, 0}, [2]int{720, 1280}, true, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := checkFrameAspectRatios(tc.first[0], tc.first[1], tc.last[0], tc.last[1], tc.strict)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %v, but got %v", tc.expectError, err)
+			}
+			if (warning != "") != tc.expectWarning {
+				t.Errorf("expected warning: %v, but got '%s'", tc.expectWarning, warning)
+			}
+		})
+	}
+}
+
+func TestVideoParamsGenerateVideosConfig(t *testing.T) {
+	params := &VideoParams{
+		GCSBucket:          "gs://bucket/veo_outputs/",
+		AspectRatio:        "16:9",
+		Resolution:         "1080p",
+		NumberOfVideos:     1,
+		DurationSecs:       8,
+		CompressionQuality: "lossless",
+	}
+	config := params.GenerateVideosConfig()
+	if config.CompressionQuality != genai.VideoCompressionQualityLossless {
+		t.Errorf("expected '%s', but got '%s'", genai.VideoCompressionQualityLossless, config.CompressionQuality)
+	}
+	if config.Resolution != "1080p" {
+		t.Errorf("expected '1080p', but got '%s'", config.Resolution)
+	}
+	if config.GenerateAudio != nil {
+		t.Errorf("expected GenerateAudio to be unset, but got %v", *config.GenerateAudio)
+	}
+}
+
+func TestParseAspectRatios(t *testing.T) {
+	testCases := []struct {
+		name          string
+		arg           interface{}
+		expected      []string
+		expectedError bool
+	}{
+		{"not provided", nil, []string{"9:16"}, false},
+		{"single", "9:16", []string{"9:16"}, false},
+		{"comma-separated", "16:9, 9:16", []string{"16:9", "9:16"}, false},
+		{"JSON array", `["16:9","9:16"]`, []string{"16:9", "9:16"}, false},
+		{"native array", []interface{}{"9:16", "16:9"}, []string{"9:16", "16:9"}, false},
+		{"duplicates removed", "16:9,16:9,9:16", []string{"16:9", "9:16"}, false},
+		{"invalid JSON", `["16:9"`, nil, true},
+		{"non-string entry", []interface{}{"16:9", 1.0}, nil, true},
+		{"wrong type", 16.9, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{}
+			if tc.arg != nil {
+				args["aspect_ratio"] = tc.arg
+			}
+			actual, err := parseAspectRatios(args, "9:16")
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if strings.Join(actual, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseReferenceImages(t *testing.T) {
+	testCases := []struct {
+		name          string
+		arg           interface{}
+		expectedCount int
+		expectedError bool
+	}{
+		{"not provided", nil, 0, false},
+		{"JSON string", `[{"uri": "gs://bucket/ref.png", "type": "ASSET"}]`, 1, false},
+		{"native array", []interface{}{
+			map[string]interface{}{"uri": "gs://bucket/ref.png", "type": "ASSET"},
+			map[string]interface{}{"uri": "gs://bucket/style.jpg", "type": "style"},
+		}, 2, false},
+		{"empty array", []interface{}{}, 0, false},
+		{"malformed JSON string", `[{"uri": "gs://bucket/ref.png"`, 0, true},
+		{"array entry with wrong field type", []interface{}{map[string]interface{}{"uri": 1.0, "type": "ASSET"}}, 0, true},
+		{"wrong type", 1.0, 0, true},
+		{"more than the model's maximum", `[{"uri": "gs://bucket/1.png", "type": "ASSET"}, {"uri": "gs://bucket/2.png", "type": "ASSET"}, {"uri": "gs://bucket/3.png", "type": "ASSET"}, {"uri": "gs://bucket/4.png", "type": "ASSET"}]`, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{}
+			if tc.arg != nil {
+				args["reference_images"] = tc.arg
+			}
+			images, _, err := parseReferenceImages(t.Context(), args, "veo-3.1-generate-preview")
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if len(images) != tc.expectedCount {
+				t.Errorf("expected %d reference image(s), but got %d", tc.expectedCount, len(images))
+			}
+		})
+	}
+}
+
+func TestParseReferenceImagesMimeTypes(t *testing.T) {
+	appConfig = &common.Config{}
+	defer func() { appConfig = nil }()
+
+	testCases := []struct {
+		name          string
+		arg           string
+		require       bool
+		expectedMime  string
+		expectedError string
+	}{
+		{"inferred", `[{"uri": "gs://bucket/ref.png", "type": "ASSET"}]`, false, "image/png", ""},
+		{"explicit without extension", `[{"uri": "gs://bucket/ref", "type": "ASSET", "mime_type": " Image/WebP "}]`, false, "image/webp", ""},
+		{"explicit overrides extension", `[{"uri": "gs://bucket/ref.png", "type": "ASSET", "mime_type": "image/jpeg"}]`, true, "image/jpeg", ""},
+		{"unsupported explicit type", `[{"uri": "gs://bucket/ref.png", "type": "ASSET", "mime_type": "image/gif"}]`, false, "", "all 1 reference images were invalid"},
+		{"required but missing", `[{"uri": "gs://bucket/ref.png", "type": "ASSET"}]`, true, "", "reference_images[0] needs a mime_type"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			appConfig.RequireMimeTypes = tc.require
+			images, _, err := parseReferenceImages(t.Context(), map[string]interface{}{"reference_images": tc.arg}, "veo-3.1-generate-preview")
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(images) != 1 || images[0].Image.MIMEType != tc.expectedMime {
+				t.Errorf("expected one reference image of type '%s', but got %+v", tc.expectedMime, images)
+			}
+		})
+	}
+}
+
+func TestParseReferenceImagesWeights(t *testing.T) {
+	testCases := []struct {
+		name             string
+		arg              interface{}
+		expectedCount    int
+		expectedWarnings []string
+		expectedError    bool
+	}{
+		{"unweighted", `[{"uri": "gs://bucket/ref.png", "type": "ASSET"}]`, 1, nil, false},
+		{"valid weights", []interface{}{
+			map[string]interface{}{"uri": "gs://bucket/ref.png", "type": "ASSET", "weight": 0.0},
+			map[string]interface{}{"uri": "gs://bucket/ref2.png", "type": "ASSET", "weight": 1.0},
+		}, 2, []string{"weights on 2 reference image(s) were ignored"}, false},
+		{"out of range weight skipped", `[{"uri": "gs://bucket/ref.png", "type": "ASSET", "weight": 1.5}, {"uri": "gs://bucket/ref2.png", "type": "ASSET"}]`, 1, []string{"reference image 0 skipped: weight 1.5"}, false},
+		{"negative weight only", `[{"uri": "gs://bucket/ref.png", "type": "ASSET", "weight": -0.1}]`, 0, nil, true},
+		{"non-numeric weight", `[{"uri": "gs://bucket/ref.png", "type": "ASSET", "weight": "high"}]`, 0, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			images, warnings, err := parseReferenceImages(t.Context(), map[string]interface{}{"reference_images": tc.arg}, "veo-3.1-generate-preview")
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if len(images) != tc.expectedCount {
+				t.Errorf("expected %d reference image(s), but got %d", tc.expectedCount, len(images))
+			}
+			if len(warnings) != len(tc.expectedWarnings) {
+				t.Fatalf("expected %d warning(s), but got %v", len(tc.expectedWarnings), warnings)
+			}
+			for i, expected := range tc.expectedWarnings {
+				if !strings.Contains(warnings[i], expected) {
+					t.Errorf("expected warning containing '%s', but got '%s'", expected, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsStorageOptions(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		args                  map[string]interface{}
+		expectedStorageClass  string
+		expectedRetentionDays int
+		expectedError         bool
+	}{
+		{"not provided", map[string]interface{}{}, "", 0, false},
+		{"storage class normalized", map[string]interface{}{"storage_class": "nearline"}, "NEARLINE", 0, false},
+		{"retention days", map[string]interface{}{"retention_days": 30.0}, "", 30, false},
+		{"unsupported storage class", map[string]interface{}{"storage_class": "GLACIER"}, "", 0, true},
+		{"zero retention days", map[string]interface{}{"retention_days": 0.0}, "", 0, true},
+		{"fractional retention days", map[string]interface{}{"retention_days": 1.5}, "", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["generate_audio"] = false
+			params, err := parseCommonVideoParams(t.Context(), tc.args, &common.Config{})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if params.StorageClass != tc.expectedStorageClass {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedStorageClass, params.StorageClass)
+			}
+			if params.RetentionDays != tc.expectedRetentionDays {
+				t.Errorf("expected %d, but got %d", tc.expectedRetentionDays, params.RetentionDays)
+			}
+		})
+	}
+}
+
+func TestResolveDefaultVeoModel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		configured    string
+		expected      string
+		expectedError string
+	}{
+		{"unset", "", fallbackVeoModel, ""},
+		{"canonical name", "veo-3.1-generate-preview", "veo-3.1-generate-preview", ""},
+		{"alias", " Veo 3 Fast ", "veo-3.0-fast-generate-001", ""},
+		{"unsupported", "not-a-model", fallbackVeoModel, "GENMEDIA_VEO_DEFAULT_MODEL 'not-a-model' is not a supported Veo model"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := resolveDefaultVeoModel(tc.configured)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsDefaultModel(t *testing.T) {
+	previous := defaultVeoModel
+	defer func() { defaultVeoModel = previous }()
+	defaultVeoModel = "veo-3.1-fast-generate-preview"
+
+	params, err := parseCommonVideoParams(t.Context(), map[string]interface{}{"generate_audio": false}, &common.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if params.Model != "veo-3.1-fast-generate-preview" {
+		t.Errorf("expected 'veo-3.1-fast-generate-preview', but got '%s'", params.Model)
+	}
+
+	params, err = parseCommonVideoParams(t.Context(), map[string]interface{}{"model": "Veo 2", "generate_audio": false}, &common.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if params.Model != "veo-2.0-generate-001" {
+		t.Errorf("expected the requested model to take precedence, but got '%s'", params.Model)
+	}
+}
+
+func TestParseCommonVideoParamsDeprecationWarning(t *testing.T) {
+	testCases := []struct {
+		name             string
+		model            string
+		expectedWarnings int
+	}{
+		{"current model", "veo-3.1-generate-preview", 0},
+		{"deprecated model", "veo-2.0-generate-exp", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseCommonVideoParams(t.Context(), map[string]interface{}{"model": tc.model, "generate_audio": false}, &common.Config{})
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if len(params.Warnings) != tc.expectedWarnings {
+				t.Fatalf("expected %d warnings, but got %v", tc.expectedWarnings, params.Warnings)
+			}
+			if tc.expectedWarnings > 0 && !strings.Contains(params.Warnings[0], "migrate to veo-2.0-generate-001") {
+				t.Errorf("expected deprecation warning naming the replacement, but got '%s'", params.Warnings[0])
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsPromptLength(t *testing.T) {
+	maxChars := common.SupportedVeoModels["veo-3.1-generate-preview"].MaxPromptChars
+	testCases := []struct {
+		name          string
+		prompt        string
+		expectedError string
+	}{
+		{"no prompt", "", ""},
+		{"at limit", strings.Repeat("a", maxChars), ""},
+		{"surrounding whitespace ignored", "  " + strings.Repeat("a", maxChars) + "  ", ""},
+		{"over limit", strings.Repeat("a", maxChars+1), fmt.Sprintf("prompt is %d characters long, which exceeds the limit of %d characters for model veo-3.1-generate-preview", maxChars+1, maxChars)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"model": "veo-3.1-generate-preview", "prompt": tc.prompt, "generate_audio": false}
+			_, err := parseCommonVideoParams(t.Context(), args, &common.Config{})
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but got '%v'", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("expected error '%s', but got '%v'", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsStyle(t *testing.T) {
+	config := &common.Config{PromptStyles: map[string]string{"anime": "Anime style."}}
+	params, err := parseCommonVideoParams(t.Context(), map[string]interface{}{"prompt": "a fox", "style": "Anime", "generate_audio": false}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params.Style != "anime" || params.StyledPrompt("a fox") != "a fox. Anime style." {
+		t.Errorf("expected the anime style to be applied, but got style '%s' and prompt '%s'", params.Style, params.StyledPrompt("a fox"))
+	}
+
+	if _, err := parseCommonVideoParams(t.Context(), map[string]interface{}{"style": "cinematic", "generate_audio": false}, config); err == nil || !strings.Contains(err.Error(), "style 'cinematic' is not supported") {
+		t.Errorf("expected a style missing from the configured styles to be rejected, but got %v", err)
+	}
+
+	maxChars := common.SupportedVeoModels["veo-3.1-generate-preview"].MaxPromptChars
+	args := map[string]interface{}{"model": "veo-3.1-generate-preview", "prompt": strings.Repeat("a", maxChars), "style": "anime", "generate_audio": false}
+	if _, err := parseCommonVideoParams(t.Context(), args, config); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("expected the style text to count toward the prompt length, but got %v", err)
+	}
+}
+
+func TestSanitizeFilenamePrefix(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"unchanged", "promo-v2_final.1", "promo-v2_final.1"},
+		{"spaces and symbols", " summer sale!! ad ", "summer_sale_ad"},
+		{"underscores", "shot__01_ final", "shot__01_final"},
+		{"path traversal", "../../etc/passwd", "etc_passwd"},
+		{"leading dot", ".hidden", "hidden"},
+		{"unicode", "café☕shot", "caf_shot"},
+		{"no usable characters", "/// ", ""},
+		{"truncated", strings.Repeat("a", 150), strings.Repeat("a", maxFilenamePrefixLength)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeFilenamePrefix(tc.input); got != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsOutputFilenamePrefix(t *testing.T) {
+	testCases := []struct {
+		name           string
+		prefix         interface{}
+		expectedPrefix string
+		expectedError  bool
+	}{
+		{"unset", nil, "", false},
+		{"blank", "  ", "", false},
+		{"sanitized", "my campaign/shot 1", "my_campaign_shot_1", false},
+		{"no usable characters", "???", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"generate_audio": false}
+			if tc.prefix != nil {
+				args["output_filename_prefix"] = tc.prefix
+			}
+			params, err := parseCommonVideoParams(t.Context(), args, &common.Config{})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if params.OutputFilenamePrefix != tc.expectedPrefix {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedPrefix, params.OutputFilenamePrefix)
+			}
+			if params.OutputObjectOptions().FilenamePrefix != tc.expectedPrefix {
+				t.Errorf("expected object options prefix '%s', but got '%s'", tc.expectedPrefix, params.OutputObjectOptions().FilenamePrefix)
+			}
+		})
+	}
+}
+
+func TestParseExtendParams(t *testing.T) {
+	testCases := []struct {
+		name              string
+		args              map[string]interface{}
+		expectedExtension int32
+		expectedError     string
+	}{
+		{"default extension", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4"}, 7, ""},
+		{"explicit extension", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "extension_duration": 4.0, "source_duration": 8.0}, 4, ""},
+		{"veo 3.1", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "model": "veo-3.1-generate-preview"}, 7, ""},
+		{"missing video", map[string]interface{}{}, 0, "video_uri must be a non-empty GCS URI"},
+		{"local video", map[string]interface{}{"video_uri": "/tmp/clip.mp4"}, 0, "must be a GCS URI"},
+		{"not a video", map[string]interface{}{"video_uri": "gs://bucket/frame.png"}, 0, "is not a supported video"},
+		{"unsupported model", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "model": "veo-3.0-fast-generate-001"}, 0, "does not support video extension. Models that do: [veo-2.0-generate-001, veo-3.1-fast-generate-preview, veo-3.1-generate-preview]"},
+		{"unsupported extension", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "extension_duration": 8.0}, 0, "Supported extension durations are: [4, 5, 6, 7]"},
+		{"too long", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "source_duration": 145.0}, 0, "exceeds the maximum of 148s"},
+		{"invalid source duration", map[string]interface{}{"video_uri": "gs://bucket/clip.mp4", "source_duration": 2.5}, 0, "source_duration must be a positive whole number"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := parseExtendParams(t.Context(), tc.args, &common.Config{})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing '%s', but got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if params.DurationSecs != tc.expectedExtension {
+				t.Errorf("expected extension of %ds, but got %ds", tc.expectedExtension, params.DurationSecs)
+			}
+			if params.VideoMimeType != "video/mp4" {
+				t.Errorf("expected 'video/mp4', but got '%s'", params.VideoMimeType)
+			}
+			if config := params.GenerateVideosConfig(); config.AspectRatio != "" || config.Resolution != "" {
+				t.Errorf("expected the aspect ratio and resolution to follow the source video, but got '%s' and '%s'", config.AspectRatio, config.Resolution)
+			}
+		})
+	}
+}
+
+func TestDatePrefixedGCSURI(t *testing.T) {
+	date := time.Date(2025, 1, 15, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	testCases := []struct {
+		uri      string
+		expected string
+	}{
+		{"gs://bucket", "gs://bucket/2025/01/16/"},
+		{"gs://bucket/", "gs://bucket/2025/01/16/"},
+		{"gs://bucket/veo_outputs/", "gs://bucket/2025/01/16/veo_outputs/"},
+		{"gs://bucket/campaign/shots", "gs://bucket/2025/01/16/campaign/shots/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			actual := datePrefixedGCSURI(tc.uri, date)
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsGCSDatePrefix(t *testing.T) {
+	args := map[string]interface{}{"generate_audio": false, "bucket": "my-bucket/campaign"}
+	before := datePrefixedGCSURI("gs://my-bucket/campaign", time.Now())
+	params, err := parseCommonVideoParams(t.Context(), args, &common.Config{GCSDatePrefix: true})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	// Accept either date in case the request straddled midnight UTC.
+	if after := datePrefixedGCSURI("gs://my-bucket/campaign", time.Now()); params.GCSBucket != before && params.GCSBucket != after {
+		t.Errorf("expected '%s', but got '%s'", after, params.GCSBucket)
+	}
+
+	params, err = parseCommonVideoParams(t.Context(), args, &common.Config{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if params.GCSBucket != "gs://my-bucket/campaign" {
+		t.Errorf("expected 'gs://my-bucket/campaign' without the option, but got '%s'", params.GCSBucket)
+	}
+}
+
+func TestParseCommonVideoParamsDefaultBucket(t *testing.T) {
+	testCases := []struct {
+		genmediaBucket string
+		expected       string
+		expectedError  bool
+	}{
+		{"default-bucket", "gs://default-bucket/veo_outputs", false},
+		{"default-bucket/", "gs://default-bucket/veo_outputs", false},
+		{"gs://default-bucket/team", "gs://default-bucket/team/veo_outputs", false},
+		{"Default_Bucket", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.genmediaBucket, func(t *testing.T) {
+			params, err := parseCommonVideoParams(t.Context(), map[string]interface{}{"generate_audio": false}, &common.Config{GenmediaBucket: tc.genmediaBucket})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err == nil && params.GCSBucket != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, params.GCSBucket)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsTimeout(t *testing.T) {
+	testCases := []struct {
+		name            string
+		timeout         interface{}
+		expectedTimeout time.Duration
+		expectedError   bool
+	}{
+		{"unset", nil, 0, false},
+		{"whole seconds", 90.0, 90 * time.Second, false},
+		{"fractional seconds", 1.5, 1500 * time.Millisecond, false},
+		{"zero", 0.0, 0, true},
+		{"negative", -5.0, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := map[string]interface{}{"generate_audio": false}
+			if tc.timeout != nil {
+				args["timeout_seconds"] = tc.timeout
+			}
+			params, err := parseCommonVideoParams(t.Context(), args, &common.Config{})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err == nil && params.Timeout != tc.expectedTimeout {
+				t.Errorf("expected %v, but got %v", tc.expectedTimeout, params.Timeout)
+			}
+		})
+	}
+}
+
+func TestParseCommonVideoParamsOutputURI(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           map[string]interface{}
+		expectedBucket string
+		expectedError  bool
+	}{
+		{"single video", map[string]interface{}{"output_uri": "gs://bucket/renders/launch.mp4"}, "gs://bucket/renders/", false},
+		{"bucket root", map[string]interface{}{"output_uri": "gs://bucket/launch.MP4"}, "gs://bucket/", false},
+		{"not gcs", map[string]interface{}{"output_uri": "bucket/launch.mp4"}, "", true},
+		{"folder", map[string]interface{}{"output_uri": "gs://bucket/renders/"}, "", true},
+		{"not mp4", map[string]interface{}{"output_uri": "gs://bucket/launch.mov"}, "", true},
+		{"multiple videos", map[string]interface{}{"output_uri": "gs://bucket/launch.mp4", "num_videos": float64(2)}, "", true},
+		{"multiple aspect ratios", map[string]interface{}{"output_uri": "gs://bucket/launch.mp4", "aspect_ratio": "16:9,9:16"}, "", true},
+		{"with bucket", map[string]interface{}{"output_uri": "gs://bucket/launch.mp4", "bucket": "other-bucket"}, "", true},
+		{"with blank bucket", map[string]interface{}{"output_uri": "gs://bucket/launch.mp4", "bucket": "  "}, "gs://bucket/", false},
+		{"with prefix", map[string]interface{}{"output_uri": "gs://bucket/launch.mp4", "output_filename_prefix": "campaign"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["generate_audio"] = false
+			params, err := parseCommonVideoParams(t.Context(), tc.args, &common.Config{GenmediaBucket: "default-bucket", GCSDatePrefix: true})
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, but got: %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if params.GCSBucket != tc.expectedBucket {
+				t.Errorf("expected bucket '%s', but got '%s'", tc.expectedBucket, params.GCSBucket)
+			}
+			if params.OutputObjectOptions().OutputURI != tc.args["output_uri"] {
+				t.Errorf("expected output URI '%v', but got '%s'", tc.args["output_uri"], params.OutputObjectOptions().OutputURI)
+			}
+		})
+	}
+}
+
+func TestClosestAspectRatio(t *testing.T) {
+	testCases := []struct {
+		name      string
+		width     int
+		height    int
+		supported []string
+		expected  string
+	}{
+		{"landscape", 1920, 1080, []string{"16:9", "9:16"}, "16:9"},
+		{"portrait", 1080, 1920, []string{"16:9", "9:16"}, "9:16"},
+		{"near landscape", 1600, 1000, []string{"16:9", "9:16"}, "16:9"},
+		{"square prefers closest", 1000, 1000, []string{"16:9", "1:1", "9:16"}, "1:1"},
+		{"only landscape", 1080, 1920, []string{"16:9"}, "16:9"},
+		{"unparseable", 100, 100, []string{"wide"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := closestAspectRatio(tc.width, tc.height, tc.supported); actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveAutoAspectRatio(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 18, 32))); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	args := map[string]interface{}{"aspect_ratio": "Auto", "model": "veo-3.1-generate-preview"}
+	if !isAutoAspectRatio(args) {
+		t.Fatalf("expected 'Auto' to be recognized as auto")
+	}
+
+	resolved, note, ok := resolveAutoAspectRatio(context.Background(), args, &genai.Image{ImageBytes: buf.Bytes(), MIMEType: "image/png"})
+	if !ok || resolved["aspect_ratio"] != "9:16" || !strings.Contains(note, "9:16") {
+		t.Errorf("expected 9:16 to be selected for a portrait image, but got %v ('%s', %v)", resolved["aspect_ratio"], note, ok)
+	}
+	if args["aspect_ratio"] != "Auto" {
+		t.Errorf("expected the request arguments to be left unchanged, but got %v", args["aspect_ratio"])
+	}
+
+	resolved, note, ok = resolveAutoAspectRatio(context.Background(), args, &genai.Image{ImageBytes: []byte("not an image")})
+	if ok || resolved["aspect_ratio"] != "16:9" || !strings.Contains(note, "model default 16:9") {
+		t.Errorf("expected a fallback to the model default, but got %v ('%s', %v)", resolved["aspect_ratio"], note, ok)
+	}
+}
+
+func TestCheckToolParams(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tool     string
+		args     map[string]interface{}
+		expected []string
+	}{
+		{"accepted parameters", "veo_i2v", map[string]interface{}{"image_uri": "gs://bucket/image.png", "prompt": "a cat", "reference_images": []interface{}{}}, nil},
+		{"empty values are ignored", "veo_t2v", map[string]interface{}{"prompt": "a cat", "image_uri": " ", "last_frame_uri": nil, "prompts": []interface{}{}}, nil},
+		{"frame on i2v", "veo_i2v", map[string]interface{}{"image_uri": "gs://bucket/image.png", "last_frame_uri": "gs://bucket/last.png"}, []string{"'last_frame_uri' is only accepted by veo_interpolate"}},
+		{"several problems", "veo_extend", map[string]interface{}{"video_uri": "gs://bucket/video.mp4", "duration": 8.0, "negative_prompt": "blur"}, []string{
+			"'duration' is only accepted by veo_t2v, veo_i2v, veo_interpolate, veo_batch_t2v; use 'extension_duration'",
+			"'negative_prompt' is only accepted by veo_t2v, veo_i2v, veo_batch_t2v",
+		}},
+		{"enhance prompt false is still provided", "veo_interpolate", map[string]interface{}{"enhance_prompt": false}, []string{"'enhance_prompt'"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkToolParams(tc.tool, tc.args)
+			if len(tc.expected) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, but got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error, but got none")
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected the error to contain '%s', but got '%s'", expected, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeGCSBucket(t *testing.T) {
+	testCases := []struct {
+		bucket        string
+		expected      string
+		expectedError string
+	}{
+		{"my-bucket", "gs://my-bucket", ""},
+		{"my-bucket/", "gs://my-bucket", ""},
+		{" gs://my-bucket/videos/campaign// ", "gs://my-bucket/videos/campaign", ""},
+		{"GS://my_bucket.example.com/videos", "gs://my_bucket.example.com/videos", ""},
+		{"https://storage.googleapis.com/my-bucket", "", "must be a GCS bucket name or gs:// URI"},
+		{"gs://", "", "valid GCS bucket name"},
+		{"My-Bucket/videos", "", "valid GCS bucket name"},
+		{"-bucket", "", "valid GCS bucket name"},
+		{"ab", "", "valid GCS bucket name"},
+		{"my bucket", "", "valid GCS bucket name"},
+		{"my-bucket//videos", "", "empty path segment"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.bucket, func(t *testing.T) {
+			actual, err := normalizeGCSBucket(tc.bucket)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected error containing '%s', but got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -43,7 +45,7 @@ var (
 
 const (
 	serviceName = "mcp-veo-go"
-	version     = "1.13.0" // generate audio and Veo 3.1
+	version     = "1.92.0" // prompt moderation
 )
 
 // init handles command-line flags and initial logging setup.
@@ -53,7 +55,6 @@ func init() {
 	flag.StringVar(&transport, "transport", "stdio", "Transport type (stdio, sse, or http)")
 	flag.IntVar(&port, "p", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
 	flag.IntVar(&port, "port", 0, "Port for SSE/HTTP server (defaults to PORT env var or 8080/8081)")
-	flag.Parse()
 }
 
 // main is the entry point for the mcp-veo-go service.
@@ -61,8 +62,38 @@ func init() {
 // It then creates an MCP server, registers the 'veo_t2v' and 'veo_i2v' tools,
 // and starts listening for requests on the configured transport.
 func main() {
+	flag.Parse()
+
 	var err error
 	appConfig = common.LoadConfig()
+	generationSlots = newGenerationLimiter(appConfig.MaxConcurrency)
+	recentOutputs = newRecentOutputBuffer(appConfig.RecentOutputs)
+	idempotencyKeys = newIdempotencyStore(appConfig.IdempotencyTTL)
+	videoResults, err = newResultCache(appConfig.ResultCache, appConfig.ResultCacheTTL)
+	if err != nil {
+		log.Printf("Error: %v. Result caching is disabled.", err)
+	} else if videoResults != nil {
+		log.Printf("Caching generation results in memory for %v", appConfig.ResultCacheTTL)
+	}
+	if unknown := common.ApplyModelAllowlist(appConfig.ModelAllowlist); len(unknown) > 0 {
+		log.Printf("Error: GENMEDIA_MODEL_ALLOWLIST entries %s are not supported Veo or Imagen models and were ignored", strings.Join(unknown, ", "))
+	}
+	if unknown := common.ApplyModelPrices(appConfig.VeoPricePerSecond, appConfig.ImagenPricePerImage); len(unknown) > 0 {
+		log.Printf("Error: price entries %s in GENMEDIA_VEO_PRICE_PER_SECOND or GENMEDIA_IMAGEN_PRICE_PER_IMAGE are not supported models and were ignored", strings.Join(unknown, ", "))
+	}
+	defaultVeoModel, err = resolveDefaultVeoModel(appConfig.VeoDefaultModel)
+	if err != nil {
+		log.Printf("Error: %v. Falling back to the default model %s.", err, defaultVeoModel)
+	} else if appConfig.VeoDefaultModel != "" {
+		log.Printf("Using %s as the default Veo model", defaultVeoModel)
+	}
+	if appConfig.ModeratePrompts {
+		moderationModel, err := resolveModerationModel("")
+		if err != nil {
+			log.Fatalf("GENMEDIA_MODERATE_PROMPTS is enabled but GENMEDIA_MODERATION_MODEL is invalid: %v", err)
+		}
+		log.Printf("Prompt moderation is enabled with model %s", moderationModel)
+	}
 
 	// Initialize OpenTelemetry
 	if otel_enabled {
@@ -77,24 +108,28 @@ func main() {
 				}
 			}()
 		}
+		mp, err := common.InitMeterProvider(serviceName, version)
+		if err != nil {
+			log.Fatalf("failed to initialize meter provider: %v", err)
+		}
+		if mp != nil {
+			defer func() {
+				if err := mp.Shutdown(context.Background()); err != nil {
+					log.Printf("Error shutting down meter provider: %v", err)
+				}
+			}()
+		}
 	}
 
 	log.Printf("Initializing global GenAI client...")
 	clientCtx, clientCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer clientCancel()
 
-	clientConfig := &genai.ClientConfig{
-		Backend:  genai.BackendVertexAI,
-		Project:  appConfig.ProjectID,
-		Location: appConfig.Location,
-	}
-
 	if appConfig.ApiEndpoint != "" {
 		log.Printf("Using custom Vertex AI endpoint: %s", appConfig.ApiEndpoint)
-		clientConfig.HTTPOptions.BaseURL = appConfig.ApiEndpoint
 	}
 
-	genAIClient, err = genai.NewClient(clientCtx, clientConfig)
+	genAIClient, err = genai.NewClient(clientCtx, newGenAIClientConfig(appConfig.ProjectID, appConfig.Location))
 	if err != nil {
 		log.Fatalf("Error creating global GenAI client: %v", err)
 	}
@@ -103,43 +138,132 @@ func main() {
 	s := server.NewMCPServer(
 		"Veo", // Standardized name
 		version,
+		server.WithResourceCapabilities(true, true),
 	)
 
+	s.AddResource(recentOutputsResource(), recentOutputsResourceHandler)
+
+	styleTags := slices.Sorted(maps.Keys(appConfig.PromptStyles))
 	commonVideoParams := []mcp.ToolOption{
 		mcp.WithString("bucket",
-			mcp.Description("Google Cloud Storage bucket where the API will save the generated video(s) (e.g., your-bucket/output-folder or gs://your-bucket/output-folder). If not provided, GENMEDIA_BUCKET env var will be used. One of them is required."),
+			mcp.Description("Google Cloud Storage bucket where the API will save the generated video(s) (e.g., your-bucket/output-folder or gs://your-bucket/output-folder). The gs:// scheme is added when missing and trailing slashes are removed. If not provided, GENMEDIA_BUCKET env var will be used. If neither is set, the videos are returned inline as base64-encoded data (or saved to output_directory if provided)."),
 		),
 		mcp.WithString("output_directory",
 			mcp.Description("Optional. If provided, specifies a local directory to download the generated video(s) to. Filenames will be generated automatically."),
 		),
+		mcp.WithString("output_filename_prefix",
+			mcp.Description("Optional. Names the generated videos <prefix>_<index>.mp4 (index starting at 0) in the GCS output folder and in output_directory, so they can be correlated with the request. Characters other than letters, digits, '-', '_' and '.' are replaced with '_'. If not provided, GCS objects keep their API-assigned names and local files get generated names."),
+		),
+		mcp.WithString("output_uri",
+			mcp.Description("Optional. Full GCS object URI (e.g., gs://your-bucket/path/name.mp4) to save the generated video to, replacing any existing object. Requires num_videos to be 1 and a single aspect ratio, and cannot be combined with bucket or output_filename_prefix. The local copy in output_directory uses the same file name. Not supported by veo_batch_t2v."),
+		),
 		mcp.WithString("model",
-			mcp.DefaultString("veo-2.0-generate-001"),
+			mcp.DefaultString(defaultVeoModel),
 			mcp.Description(common.BuildVeoModelDescription()),
 		),
 		mcp.WithNumber("num_videos",
 			mcp.DefaultNumber(1),
-			mcp.Description("Number of videos to generate. Note: the maximum is model-dependent."),
+			mcp.Description("Number of videos to generate (aliases: number_of_videos, sample_count). Note: the maximum is model-dependent. Requests above the model's maximum are reduced to it, or rejected if GENMEDIA_CLAMP_NUM_VIDEOS is false."),
 		),
 		mcp.WithString("aspect_ratio",
-			mcp.DefaultString("16:9"),
-			mcp.Description("Aspect ratio of the generated videos. Note: supported aspect ratios are model-dependent."),
+			mcp.Description("Aspect ratio of the generated videos. Defaults to the model's default aspect ratio (16:9 for all current models). Note: supported aspect ratios are model-dependent. For veo_t2v only, a comma-separated list or JSON array (e.g., '16:9,9:16') renders the prompt once per aspect ratio and aggregates the results. For veo_i2v only, 'auto' picks the supported ratio closest to the input image."),
+		),
+		mcp.WithString("resolution",
+			mcp.Enum("720p", "1080p"),
+			mcp.Description("Optional. Output resolution of the generated videos. Note: supported resolutions are model-dependent. If not provided, the model's native resolution is used."),
 		),
 		mcp.WithNumber("duration",
-			mcp.DefaultNumber(5),
-			mcp.Description("Duration of the generated video in seconds. Note: the supported duration range is model-dependent."),
+			mcp.Description("Duration of the generated video in seconds. Note: the supported durations are model-dependent. If not provided, the model's default duration is used."),
 		),
 		mcp.WithBoolean("generate_audio",
 			mcp.DefaultBool(true),
 			mcp.Description("Optional. Generate audio for the video. Only supported by Veo 3 models. Defaults to true."),
 		),
+		mcp.WithNumber("seed",
+			mcp.Description("Optional. Seed for reproducible generation. Must be a whole number between 0 and 2147483647."),
+		),
+		mcp.WithArray("reference_images",
+			mcp.Description("Optional. An array of reference image objects. Each object must have a 'uri' (GCS URI) and a 'type' ('ASSET' or 'STYLE'), and may have a 'mime_type' and a 'weight' between 0 and 1. The MIME type is inferred from the URI when omitted, unless GENMEDIA_REQUIRE_MIME_TYPES requires it. The Veo API does not currently support weighting, so weights are validated but ignored with a warning. A JSON string encoding the same array is also accepted. Only supported by some models, which cap how many references a request may include (see the model description). Example: [{\"uri\": \"gs://...\", \"type\": \"ASSET\"}]"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"uri":       map[string]any{"type": "string", "description": "GCS URI of the reference image."},
+					"type":      map[string]any{"type": "string", "enum": []string{"ASSET", "STYLE"}},
+					"mime_type": map[string]any{"type": "string", "description": "Optional MIME type of the reference image (e.g., 'image/png'). Inferred from the URI if omitted."},
+					"weight":    map[string]any{"type": "number", "minimum": 0, "maximum": 1, "description": "Optional influence weight. Currently ignored by the Veo API."},
+				},
+				"required": []string{"uri", "type"},
+			}),
+		),
+		mcp.WithString("labels",
+			mcp.Description("Optional. A JSON string representing an object of labels to attach to the generated GCS objects as metadata, e.g. '{\"team\": \"marketing\"}'. Keys and values must follow Google Cloud label rules (lowercase letters, digits, underscores, and dashes; keys must start with a letter)."),
+		),
+		mcp.WithString("storage_class",
+			mcp.Enum("STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"),
+			mcp.Description("Optional. GCS storage class to move the generated video objects to after generation (e.g., NEARLINE). Ignored if no GCS output is produced. If not provided, the bucket's default storage class is kept."),
+		),
+		mcp.WithNumber("retention_days",
+			mcp.Description("Optional. Marks the generated GCS objects for deletion after this many days by setting their Custom-Time. Requires a bucket lifecycle rule with a 'daysSinceCustomTime' condition to take effect. Must be a positive whole number. Ignored if no GCS output is produced."),
+		),
+		mcp.WithString("output_format",
+			mcp.Enum("text", "json"),
+			mcp.DefaultString("text"),
+			mcp.Description("Optional. Format of the tool result: 'text' for a human-readable summary, or 'json' for a JSON object with gcs_uris, local_paths, model, duration, and operation_name."),
+		),
+		mcp.WithString("compression_quality",
+			mcp.Enum("optimized", "lossless"),
+			mcp.Description("Optional. Compression level of the generated video: 'optimized' for smaller files suited to bandwidth-constrained delivery, or 'lossless' for maximum quality. If not provided, the API default is used."),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Optional. Maximum number of seconds this generation may run, including time spent queued and polling, capped by GENMEDIA_MAX_WAIT for the operation itself. Must be positive. If it elapses, the result includes the operation name so the job can be resumed with veo_get_operation."),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, validates and resolves all parameters (model, aspect ratio, duration, reference images, etc.) and returns the resolved request without calling the Veo API. No quota is used."),
+		),
+		mcp.WithBoolean("force_regenerate",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, skips any cached result for an identical request and always calls the Veo API. Has no effect when result caching is disabled."),
+		),
+		mcp.WithString("idempotency_key",
+			mcp.Description("Optional. A client-chosen key, up to 256 characters, that makes retries of this request safe. A request with the same key and arguments as one in flight waits for it, and one repeated within GENMEDIA_IDEMPOTENCY_TTL (default 1h) of a successful request returns its result, instead of starting a new generation. Reusing a key with different arguments is an error, and failed requests can be retried with the same key."),
+		),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project to run the request in. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location to run the request in. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+		mcp.WithString("person_generation",
+			mcp.Enum("allow_adult", "dont_allow", "allow_all"),
+			mcp.Description("Optional. Controls whether people can be generated in the video. Accepted values: 'allow_adult', 'dont_allow', 'allow_all'. Note: the allowed policies are model-dependent. If not provided, the API default is used."),
+		),
+		mcp.WithString("style",
+			mcp.Enum(styleTags...),
+			mcp.Description(fmt.Sprintf("Optional. A named style whose description is appended to the prompt before generation, for a consistent look across requests. Accepted values: [%s]. Styles are configured with GENMEDIA_PROMPT_STYLES. The styled prompt is reported in the result.", strings.Join(styleTags, ", "))),
+		),
 	}
 
 	var textToVideoToolParams []mcp.ToolOption
 	textToVideoToolParams = append(textToVideoToolParams,
 		mcp.WithDescription("Generate a video from a text prompt using Veo. Video is saved to GCS and optionally downloaded locally."),
 		mcp.WithString("prompt",
-			mcp.Required(),
-			mcp.Description("Text prompt for video generation."),
+			mcp.Description("Text prompt for video generation. Required unless prompt_template is provided."),
+		),
+		mcp.WithString("prompt_template",
+			mcp.Description("Optional. A prompt with {{name}} placeholders, used instead of prompt. Each placeholder is replaced with the value of the variable of that name in prompt_vars, and a placeholder without a variable is an error. The expanded prompt is what gets generated and is reported in the result. Example: 'A {{animal}} running through {{place}}, cinematic lighting'."),
+		),
+		mcp.WithString("prompt_vars",
+			mcp.Description("Optional. A JSON string representing an object of variables for prompt_template, with string, number, or boolean values, e.g. '{\"animal\": \"red fox\", \"place\": \"fresh snow\"}'. A native object is also accepted."),
+		),
+		mcp.WithString("negative_prompt",
+			mcp.Description("Optional. Describes content to discourage in the generated video (e.g., 'blurry, text overlays, watermarks')."),
+		),
+		mcp.WithBoolean("enhance_prompt",
+			mcp.Description("Optional. Whether the API should automatically enhance the prompt. Set to false for more literal prompt adherence. If not provided, the API default is used."),
 		),
 	)
 	textToVideoToolParams = append(textToVideoToolParams, commonVideoParams...)
@@ -147,55 +271,99 @@ func main() {
 	textToVideoTool := mcp.NewTool("veo_t2v",
 		textToVideoToolParams...,
 	)
-	s.AddTool(textToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.AddTool(textToVideoTool, withIdempotency("veo_t2v", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoTextToVideoHandler(genAIClient, ctx, request)
-	})
+	}))
+
+	var batchTextToVideoToolParams []mcp.ToolOption
+	batchTextToVideoToolParams = append(batchTextToVideoToolParams,
+		mcp.WithDescription("Generate videos for several text prompts in one call using Veo. Prompts run concurrently, up to max_concurrency at a time, and each is generated like a veo_t2v request. A failed prompt does not abort the batch: the result lists the status, output URIs, and message of every prompt."),
+		mcp.WithArray("prompts",
+			mcp.Required(),
+			mcp.Description(fmt.Sprintf("The prompts to generate, at most %d. Each entry is a prompt string or an object with a 'prompt' and optional 'model', 'duration', and 'aspect_ratio' that override the arguments of the same name for that prompt. A JSON string encoding the same array is also accepted. Example: [\"a red fox in the snow\", {\"prompt\": \"a city at night\", \"aspect_ratio\": \"9:16\"}]", maxBatchPrompts)),
+			mcp.Items(map[string]any{
+				"anyOf": []any{
+					map[string]any{"type": "string"},
+					map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"prompt":       map[string]any{"type": "string"},
+							"model":        map[string]any{"type": "string"},
+							"duration":     map[string]any{"type": "number"},
+							"aspect_ratio": map[string]any{"type": "string"},
+						},
+						"required": []string{"prompt"},
+					},
+				},
+			}),
+		),
+		mcp.WithNumber("max_concurrency",
+			mcp.DefaultNumber(defaultBatchConcurrency),
+			mcp.Description("Optional. Maximum number of prompts generated at the same time. GENMEDIA_MAX_CONCURRENCY still caps the operations running across the whole server."),
+		),
+		mcp.WithString("negative_prompt",
+			mcp.Description("Optional. Describes content to discourage in every generated video (e.g., 'blurry, text overlays, watermarks')."),
+		),
+		mcp.WithBoolean("enhance_prompt",
+			mcp.Description("Optional. Whether the API should automatically enhance the prompts. If not provided, the API default is used."),
+		),
+	)
+	batchTextToVideoToolParams = append(batchTextToVideoToolParams, commonVideoParams...)
+
+	batchTextToVideoTool := mcp.NewTool("veo_batch_t2v",
+		batchTextToVideoToolParams...,
+	)
+	s.AddTool(batchTextToVideoTool, withIdempotency("veo_batch_t2v", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoBatchTextToVideoHandler(genAIClient, ctx, request)
+	}))
 
 	var imageToVideoToolParams []mcp.ToolOption
 	imageToVideoToolParams = append(imageToVideoToolParams,
 		mcp.WithDescription("Generate a video from an input image (and optional prompt) using Veo. Video is saved to GCS and optionally downloaded locally. Supported image MIME types: image/jpeg, image/png."),
 		mcp.WithString("image_uri",
 			mcp.Required(),
-			mcp.Description("GCS URI of the input image for video generation (e.g., gs://your-bucket/input-image.png)."),
+			mcp.Description("The input image for video generation. Can be a GCS URI (e.g., gs://your-bucket/input-image.png), a local file path, or base64-encoded image data (raw or as a data URI). Local and inline images are uploaded under the GCS output location's inputs/ folder when a bucket is available, and sent inline otherwise."),
 		),
 		mcp.WithString("mime_type",
-			mcp.Description("MIME type of the input image. Supported types are 'image/jpeg' and 'image/png'. If not provided, an attempt will be made to infer it from the image_uri extension."),
+			mcp.Description("MIME type of the input image. Supported types are 'image/jpeg' and 'image/png'. If not provided, it is inferred from the GCS URI extension or detected from the image content."),
 		),
 		mcp.WithString("prompt",
 			mcp.Description("Optional text prompt to guide video generation from the image."),
 		),
+		mcp.WithString("negative_prompt",
+			mcp.Description("Optional. Describes content to discourage in the generated video (e.g., 'blurry, text overlays, watermarks')."),
+		),
+		mcp.WithBoolean("enhance_prompt",
+			mcp.Description("Optional. Whether the API should automatically enhance the prompt. Set to false for more literal prompt adherence. If not provided, the API default is used."),
+		),
 	)
 	imageToVideoToolParams = append(imageToVideoToolParams, commonVideoParams...)
 
 	imageToVideoTool := mcp.NewTool("veo_i2v",
 		imageToVideoToolParams...,
 	)
-	s.AddTool(imageToVideoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.AddTool(imageToVideoTool, withIdempotency("veo_i2v", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoImageToVideoHandler(genAIClient, ctx, request)
-	})
+	}))
 
 	var interpolationToolParams []mcp.ToolOption
 	interpolationToolParams = append(interpolationToolParams,
-		mcp.WithDescription("Generate a video by interpolating between a first and last frame, with an optional prompt and reference images. Video is saved to GCS and optionally downloaded locally."),
+		mcp.WithDescription("Generate a video by interpolating between a first and last frame, with an optional prompt and reference images. The first frame may be omitted to generate from the prompt toward the last frame. Video is saved to GCS and optionally downloaded locally."),
 		mcp.WithString("first_frame_uri",
-			mcp.Required(),
-			mcp.Description("GCS URI of the first frame (start image) for video interpolation (e.g., gs://your-bucket/first-frame.png)."),
+			mcp.Description("Optional. GCS URI (e.g., gs://your-bucket/first-frame.png), local file path, or base64 data URI (data:image/png;base64,...) of the first frame (start image) for video interpolation. Local files and inline data are uploaded under the GCS output location's inputs/ folder when a bucket is available. If omitted, the video is generated from the prompt, which is then required, toward the last frame."),
 		),
 		mcp.WithString("last_frame_uri",
 			mcp.Required(),
-			mcp.Description("GCS URI of the last frame (end image) for video interpolation (e.g., gs://your-bucket/last-frame.png)."),
+			mcp.Description("GCS URI (e.g., gs://your-bucket/last-frame.png), local file path, or base64 data URI (data:image/png;base64,...) of the last frame (end image) for video interpolation. Local files and inline data are uploaded under the GCS output location's inputs/ folder when a bucket is available."),
 		),
 		mcp.WithString("first_frame_mime_type",
-			mcp.Description("MIME type of the first frame. Supported types are 'image/jpeg' and 'image/png'. If not provided, it will be inferred from the URI."),
+			mcp.Description("MIME type of the first frame. Supported types are 'image/jpeg' and 'image/png'. If not provided, it is inferred from the URI extension, the data URI header, or the file content."),
 		),
 		mcp.WithString("last_frame_mime_type",
-			mcp.Description("MIME type of the last frame. Supported types are 'image/jpeg' and 'image/png'. If not provided, it will be inferred from the URI."),
-		),
-		mcp.WithString("reference_images",
-			mcp.Description("Optional. A JSON string representing an array of reference image objects. Each object must have a 'uri' (string) and a 'type' (string, either 'ASSET' or 'STYLE'). Example: '[{\"uri\": \"gs://...\", \"type\": \"ASSET\"}]'"),
+			mcp.Description("MIME type of the last frame. Supported types are 'image/jpeg' and 'image/png'. If not provided, it is inferred from the URI extension, the data URI header, or the file content."),
 		),
 		mcp.WithString("prompt",
-			mcp.Description("Optional text prompt to guide video generation."),
+			mcp.Description("Optional text prompt to guide video generation. Required when first_frame_uri is not provided."),
 		),
 	)
 	interpolationToolParams = append(interpolationToolParams, commonVideoParams...)
@@ -203,10 +371,233 @@ func main() {
 	interpolationTool := mcp.NewTool("veo_interpolate",
 		interpolationToolParams...,
 	)
-	s.AddTool(interpolationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.AddTool(interpolationTool, withIdempotency("veo_interpolate", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return veoInterpolationHandler(genAIClient, ctx, request)
+	}))
+
+	extendTool := mcp.NewTool("veo_extend",
+		mcp.WithDescription("Extend an existing video stored in GCS using Veo, optionally guided by a prompt. The result is the source video with the extension appended, saved to GCS and optionally downloaded locally. Only some models support extension."),
+		mcp.WithString("video_uri",
+			mcp.Required(),
+			mcp.Description("GCS URI of the MP4 video to extend (e.g., gs://your-bucket/video.mp4)."),
+		),
+		mcp.WithNumber("extension_duration",
+			mcp.Description("Optional. Number of seconds to add to the video. Note: the supported extension durations are model-dependent. If not provided, the longest extension the model supports is used."),
+		),
+		mcp.WithNumber("source_duration",
+			mcp.Description("Optional. Length of the source video in seconds. If provided, the request is rejected when the extended video would exceed the maximum length."),
+		),
+		mcp.WithString("prompt",
+			mcp.Description("Optional text prompt describing how the video should continue."),
+		),
+		mcp.WithString("model",
+			mcp.DefaultString(defaultVeoModel),
+			mcp.Description(common.BuildVeoModelDescription()),
+		),
+		mcp.WithString("bucket",
+			mcp.Description("Google Cloud Storage bucket where the API will save the extended video(s). If not provided, GENMEDIA_BUCKET env var will be used. If neither is set, the videos are returned inline as base64-encoded data (or saved to output_directory if provided)."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Optional. If provided, specifies a local directory to download the extended video(s) to."),
+		),
+		mcp.WithString("output_filename_prefix",
+			mcp.Description("Optional. Names the extended videos <prefix>_<index>.mp4 in the GCS output folder and in output_directory."),
+		),
+		mcp.WithString("output_uri",
+			mcp.Description("Optional. Full GCS object URI (e.g., gs://your-bucket/path/name.mp4) to save the extended video to. Requires num_videos to be 1 and cannot be combined with bucket or output_filename_prefix."),
+		),
+		mcp.WithNumber("num_videos",
+			mcp.DefaultNumber(1),
+			mcp.Description("Number of extended videos to generate (aliases: number_of_videos, sample_count). Note: the maximum is model-dependent."),
+		),
+		mcp.WithBoolean("generate_audio",
+			mcp.Description("Optional. Generate audio for the extension. Only supported by Veo 3 models. Defaults to whether the model supports audio."),
+		),
+		mcp.WithNumber("seed",
+			mcp.Description("Optional. Seed for reproducible generation. Must be a whole number between 0 and 2147483647."),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Optional. Maximum number of seconds the extension may run, including time spent queued and polling. Must be positive. If it elapses, the result includes the operation name so the job can be resumed with veo_get_operation."),
+		),
+		mcp.WithBoolean("force_regenerate",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, skips any cached result for an identical request and always calls the Veo API. Has no effect when result caching is disabled."),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.DefaultBool(false),
+			mcp.Description("Optional. If true, validates and resolves all parameters (source video, model, extension duration, etc.) and returns the resolved request without calling the Veo API. No quota is used."),
+		),
+		mcp.WithString("idempotency_key",
+			mcp.Description("Optional. A client-chosen key, up to 256 characters, that makes retries of this request safe. A request with the same key and arguments as one in flight waits for it, and one repeated within GENMEDIA_IDEMPOTENCY_TTL (default 1h) of a successful request returns its result, instead of starting a new generation. Reusing a key with different arguments is an error, and failed requests can be retried with the same key."),
+		),
+		mcp.WithString("output_format",
+			mcp.Enum("text", "json"),
+			mcp.DefaultString("text"),
+			mcp.Description("Optional. Format of the tool result: 'text' for a human-readable summary, or 'json' for a JSON object with gcs_uris, local_paths, model, duration, and operation_name."),
+		),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project to run the request in. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location to run the request in. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+	)
+	s.AddTool(extendTool, withIdempotency("veo_extend", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoExtendHandler(genAIClient, ctx, request)
+	}))
+
+	// veo_upscale is only offered once a model supports upscaling, so that clients are not
+	// shown a tool that can only fail. No model does through the genai SDK yet.
+	if len(veoModelsSupportingUpscale()) > 0 {
+		upscaleTool := mcp.NewTool("veo_upscale",
+			mcp.WithDescription("Upscale an existing video stored in GCS to a higher resolution using Veo. Only models whose capabilities report SupportsUpscale can upscale."),
+			mcp.WithString("video_uri",
+				mcp.Required(),
+				mcp.Description("GCS URI of the MP4 video to upscale (e.g., gs://your-bucket/video.mp4)."),
+			),
+			mcp.WithString("resolution",
+				mcp.Required(),
+				mcp.Description("Target resolution of the upscaled video (e.g., '1080p'). Must be one of the model's supported resolutions."),
+			),
+			mcp.WithString("model",
+				mcp.DefaultString(defaultVeoModel),
+				mcp.Description(common.BuildVeoModelDescription()),
+			),
+		)
+		s.AddTool(upscaleTool, veoUpscaleHandler)
+	}
+
+	getOperationTool := mcp.NewTool("veo_get_operation",
+		mcp.WithDescription("Check the status of a previously started Veo video generation operation. Returns the output GCS URIs if the operation has completed, or its current status if it is still running."),
+		mcp.WithString("operation_name",
+			mcp.Required(),
+			mcp.Description("The full name of the operation returned when the video generation was started (e.g., projects/.../operations/...)."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Optional. If provided and the operation has completed, specifies a local directory to download the generated video(s) to."),
+		),
+		mcp.WithString("output_format",
+			mcp.Enum("text", "json"),
+			mcp.DefaultString("text"),
+			mcp.Description("Optional. Format of the tool result: 'text' for a human-readable summary, or 'json' for a JSON object with gcs_uris, local_paths, model, duration, and operation_name."),
+		),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project the operation was started in. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location the operation was started in. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+	)
+	s.AddTool(getOperationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoGetOperationHandler(genAIClient, ctx, request)
+	})
+
+	cancelOperationTool := mcp.NewTool("veo_cancel_operation",
+		mcp.WithDescription("Request cancellation of a running Veo video generation operation, e.g. one started with the wrong prompt. Operations that have already completed or failed are reported as such and left unchanged. Cancellation is best effort; check the final state with veo_get_operation."),
+		mcp.WithString("operation_name",
+			mcp.Required(),
+			mcp.Description("The full name of the operation returned when the video generation was started (e.g., projects/.../operations/...)."),
+		),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project the operation was started in. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location the operation was started in. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+	)
+	s.AddTool(cancelOperationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoCancelOperationHandler(genAIClient, ctx, request)
+	})
+
+	listModelsTool := mcp.NewTool("list_veo_models",
+		mcp.WithDescription("List the supported Veo models and their constraints (durations, max videos, aspect ratios, resolutions, and supported features) as a JSON array."),
+	)
+	s.AddTool(listModelsTool, listVeoModelsHandler)
+
+	modelCapabilitiesTool := mcp.NewTool("veo_model_capabilities",
+		mcp.WithDescription("Return the constraints and supported features of a single Veo model (supported durations, aspect ratios, resolutions, max videos, and flags such as audio, last frame, reference image, and extension support) as a JSON object. Unknown models return a NOT_FOUND error."),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Model name or alias to look up, e.g. 'veo-3.0-generate-001' or 'Veo 3 Fast'."),
+		),
+	)
+	s.AddTool(modelCapabilitiesTool, veoModelCapabilitiesHandler)
+
+	estimateCostTool := mcp.NewTool("veo_estimate_cost",
+		mcp.WithDescription("Estimate the number of generated seconds and a rough cost for a Veo request without starting it. The model, duration, number of videos, and resolution are validated as they would be for generation. Prices are the per-second prices listed in the model description, which GENMEDIA_VEO_PRICE_PER_SECOND can override."),
+		mcp.WithString("model",
+			mcp.DefaultString(defaultVeoModel),
+			mcp.Description(common.BuildVeoModelDescription()),
+		),
+		mcp.WithNumber("num_videos",
+			mcp.DefaultNumber(1),
+			mcp.Description("Number of videos to generate (aliases: number_of_videos, sample_count). Note: the maximum is model-dependent. Requests above the model's maximum are reduced to it, or rejected if GENMEDIA_CLAMP_NUM_VIDEOS is false."),
+		),
+		mcp.WithNumber("duration",
+			mcp.Description("Duration of the generated video in seconds. If not provided, the model's default duration is used."),
+		),
+		mcp.WithString("resolution",
+			mcp.Enum("720p", "1080p"),
+			mcp.Description("Optional. Output resolution. If not provided, the model's native resolution is used."),
+		),
+	)
+	s.AddTool(estimateCostTool, veoEstimateCostHandler)
+
+	moderatePromptTool := mcp.NewTool("veo_moderate_prompt",
+		mcp.WithDescription("Screen a video generation prompt with a Gemini model before generating, without starting a generation. Returns a 'pass' or 'flag' decision, the policy categories that apply to a flagged prompt, and a short reason. When GENMEDIA_MODERATE_PROMPTS is true, the generation tools run the same check themselves and refuse flagged prompts."),
+		mcp.WithString("prompt",
+			mcp.Required(),
+			mcp.Description("The prompt to screen."),
+		),
+		mcp.WithString("model",
+			mcp.Description("Optional. Gemini text model, by name or alias, that screens the prompt. Defaults to GENMEDIA_MODERATION_MODEL (gemini-3-pro-preview if not set)."),
+		),
+	)
+	s.AddTool(moderatePromptTool, veoModeratePromptHandler)
+
+	healthTool := mcp.NewTool("veo_health",
+		mcp.WithDescription("Check that the server can reach Vertex AI with its configured credentials, for deployment readiness probes. Fetches the metadata of a Veo model, an authenticated call that uses no generation quota, and returns OK or the failure (with authentication and permission problems called out) along with the latency."),
+		mcp.WithString("model",
+			mcp.DefaultString("veo-2.0-generate-001"),
+			mcp.Description("Optional. Model whose metadata is fetched. Defaults to veo-2.0-generate-001."),
+		),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project to check. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location to check. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+	)
+	s.AddTool(healthTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return veoHealthHandler(genAIClient, ctx, request)
 	})
 
+	quotaTool := mcp.NewTool("veo_quota",
+		mcp.WithDescription("Report the project's Veo-related Vertex AI quota limits in a location, with their current per-minute usage. Limits are read from the Cloud Quotas API and usage from Cloud Monitoring; if either is unreachable, the result has status 'partial' or 'unavailable' instead of failing."),
+		mcp.WithString("project",
+			mcp.Description("Optional. Google Cloud project whose quotas are read. Defaults to the server's PROJECT_ID."),
+		),
+		mcp.WithString("location",
+			mcp.Description("Optional. Google Cloud location whose quotas are read. Defaults to the server's LOCATION."),
+		),
+		mcp.WithString("impersonate_service_account",
+			mcp.Description("Optional. Service account email (e.g., name@project.iam.gserviceaccount.com) to run the request as. The server's credentials need the Service Account Token Creator role on it. Defaults to the server's own credentials."),
+		),
+	)
+	s.AddTool(quotaTool, veoQuotaHandler)
+
 	s.AddPrompt(mcp.NewPrompt("generate-video",
 		mcp.WithPromptDescription("Generates a video from a text prompt."),
 		mcp.WithArgument("prompt", mcp.ArgumentDescription("The text prompt to generate a video from."), mcp.RequiredArgument()),
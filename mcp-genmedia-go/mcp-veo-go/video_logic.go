@@ -18,12 +18,16 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/vertex-ai-creative-studio/experiments/mcp-genmedia/mcp-genmedia-go/mcp-common"
@@ -31,6 +35,8 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"google.golang.org/genai"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 
@@ -39,6 +45,9 @@ import (
 // It initiates the video generation operation, polls for its completion, and handles
 // progress notifications. Once the video is generated, it can download the file
 // to a local directory if requested. It returns a summary of the operation's outcome.
+// If video is set, the operation extends that video instead of generating a new one.
+// When result caching is enabled, an identical earlier request's outputs are returned
+// without calling the API unless forceRegenerate is set; new results are cached either way.
 func callGenerateVideosAPI(
 	client *genai.Client,
 	parentCtx context.Context, // Renamed from ctx to avoid conflict with operationCtx
@@ -48,51 +57,134 @@ func callGenerateVideosAPI(
 	modelName string,
 	prompt string,
 	image *genai.Image,
+	video *genai.Video,
 	config *genai.GenerateVideosConfig,
+	objectOptions outputObjectOptions,
+	outputFormat string,
+	forceRegenerate bool,
 	callType string,
-) (*mcp.CallToolResult, error) {
+) (result *mcp.CallToolResult, err error) {
 	tr := otel.Tracer(serviceName)
 	ctx, span := tr.Start(parentCtx, "callGenerateVideosAPI")
 	defer span.End()
+	ctx, logger := withLogAttrs(ctx, "call_type", callType)
+
+	startedAt := time.Now()
+	cacheHit := false
+	defer func() {
+		veoMetrics.Record(ctx, modelName, callType, cacheHit, result, err, time.Since(startedAt))
+	}()
 
 	attemptLocalDownload := outputDir != ""
 
+	var cacheKey string
+	if videoResults != nil {
+		key, err := resultCacheKey(callType, modelName, prompt, image, video, config, objectOptions)
+		if err != nil {
+			logger.Warn("Result cache is skipped for this request", "error", err)
+		} else {
+			cacheKey = key
+			span.SetAttributes(attribute.Bool("force_regenerate", forceRegenerate))
+			if forceRegenerate {
+				logger.Info("force_regenerate is set; skipping the result cache lookup")
+			} else if cached, ok := lookupCachedResult(ctx, cacheKey); ok {
+				logger.Info("Returning cached result", "operation_name", cached.OperationName, "gcs_uris", cached.GCSURIs)
+				span.SetAttributes(attribute.Bool("cache_hit", true))
+				cacheHit = true
+				return cachedVideoToolResult(ctx, cached, config, outputDir, outputFormat, callType)
+			}
+			span.SetAttributes(attribute.Bool("cache_hit", false))
+		}
+	}
+
+	// Wait for a free generation slot so that concurrent requests (and the operations a
+	// single request fans out to) stay within GENMEDIA_MAX_CONCURRENCY. The slot is held
+	// until polling finishes, so MaxWait only starts counting once the request is running.
+	queuedAt := time.Now()
+	releaseSlot, err := generationSlots.Acquire(ctx, func() {
+		logger.Info("GenerateVideos is queued: the maximum number of concurrent operations is already running")
+		if progressToken != nil && mcpServer != nil {
+			if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]interface{}{
+				"progressToken": progressToken,
+				"message":       fmt.Sprintf("Video generation (%s) is queued until another generation finishes.", callType),
+				"status":        "queued",
+			}); err != nil {
+				logger.Warn("Failed to send progress notification", "status", "queued", "error", err)
+			}
+		}
+	})
+	if err != nil {
+		if timeoutErr, ok := requestTimedOut(ctx); ok {
+			err = timeoutErr
+		}
+		logger.Warn("GenerateVideos was canceled while queued", "error", err)
+		span.SetAttributes(attribute.Bool("canceled", true))
+		return codedToolResultError(errorCode(err), fmt.Sprintf("video generation (%s) was canceled while waiting for a free generation slot: %v", callType, err)), nil
+	}
+	defer releaseSlot()
+	span.SetAttributes(attribute.Int64("queue_wait_ms", time.Since(queuedAt).Milliseconds()))
+
 	// Context for the entire GenerateVideos operation, including polling.
 	// We derive the operation context from the parent context to ensure that if the
 	// client disconnects or the parent request is canceled, we propagate the
 	// cancellation to the long-running GenAI operation.
-	operationCtx, operationCancel := context.WithTimeout(ctx, 5*time.Minute) // Timeout for the entire GenAI operation + polling
+	maxWait := appConfig.MaxWait
+	operationCtx, operationCancel := context.WithTimeout(ctx, maxWait) // Timeout for the entire GenAI operation + polling
 	defer operationCancel()
 
-	logMsg := fmt.Sprintf("Initiating GenerateVideos (%s) with Model: %s", callType, modelName)
+	logAttrs := []any{"model", modelName, "output_gcs_uri", config.OutputGCSURI, "operation_timeout", maxWait}
 	if image != nil && image.GCSURI != "" {
-		logMsg += fmt.Sprintf(", ImageGCSURI: %s, ImageMIMEType: %s", image.GCSURI, image.MIMEType)
+		logAttrs = append(logAttrs, "image_gcs_uri", image.GCSURI, "image_mime_type", image.MIMEType)
+	}
+	if video != nil {
+		logAttrs = append(logAttrs, "source_video_uri", video.URI)
 	}
 	if prompt != "" {
-		logMsg += fmt.Sprintf(", Prompt: \"%s\"", strings.ReplaceAll(prompt, "\n", " ")) // Sanitize prompt for logging
+		logAttrs = append(logAttrs, "prompt", prompt)
 	}
 	if config.DurationSeconds != nil {
-		logMsg += fmt.Sprintf(", Duration: %ds", *config.DurationSeconds)
+		logAttrs = append(logAttrs, "duration_secs", *config.DurationSeconds)
 	}
-	logMsg += fmt.Sprintf(", OutputGCS: %s. Operation timeout: %v", config.OutputGCSURI, 5*time.Minute)
 	if attemptLocalDownload {
-		logMsg += fmt.Sprintf(". Will attempt to download to local directory: '%s'", outputDir)
+		logAttrs = append(logAttrs, "output_dir", outputDir)
+	}
+	logger.Info("Initiating GenerateVideos", logAttrs...)
+	if config.OutputGCSURI != "" {
+		span.SetAttributes(attribute.String("output_gcs_uri", config.OutputGCSURI))
 	}
-	log.Print(logMsg)
 
 	startTime := time.Now()
 
 	// Use operationCtx for the initial call to GenerateVideos
-	operation, err := client.Models.GenerateVideos(operationCtx, modelName, prompt, image, config)
+	// Transient API errors (e.g., 429, 503) are retried with backoff.
+	operation, err := retryWithBackoff(operationCtx, appConfig.MaxRetryAttempts, fmt.Sprintf("GenerateVideos (%s)", callType), func() (*genai.GenerateVideosOperation, error) {
+		if video != nil {
+			// Extension is only exposed through the source-based entry point of the SDK.
+			return client.Models.GenerateVideosFromSource(operationCtx, modelName, &genai.GenerateVideosSource{Prompt: prompt, Video: video}, config)
+		}
+		return client.Models.GenerateVideos(operationCtx, modelName, prompt, image, config)
+	})
 	if err != nil {
+		if timeoutErr, ok := requestTimedOut(ctx); ok {
+			logger.Warn("GenerateVideos initiation was stopped", "error", timeoutErr)
+			span.SetAttributes(attribute.Bool("timed_out", true))
+			return codedToolResultError(codeDeadlineExceeded, fmt.Sprintf("video generation (%s) was stopped before the operation started: %v", callType, timeoutErr)), nil
+		}
+		if ctx.Err() != nil {
+			logger.Warn("GenerateVideos initiation was canceled by the client", "error", ctx.Err())
+			span.SetAttributes(attribute.Bool("canceled", true))
+			return codedToolResultError(codeCancelled, fmt.Sprintf("video generation (%s) was canceled by the client before the operation started: %v", callType, ctx.Err())), nil
+		}
 		if errors.Is(err, context.DeadlineExceeded) && operationCtx.Err() == context.DeadlineExceeded {
-			log.Printf("GenerateVideos (%s) failed: initial call timed out: %v", callType, err)
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) initiation timed out", callType)), nil
+			logger.Error("GenerateVideos failed: initial call timed out", "error", err)
+			return codedToolResultError(codeDeadlineExceeded, fmt.Sprintf("video generation (%s) initiation timed out", callType)), nil
 		}
-		log.Printf("Error initiating GenerateVideos (%s): %v", callType, err)
-		return mcp.NewToolResultError(fmt.Sprintf("error starting video generation (%s): %v", callType, err)), nil
+		logger.Error("Error initiating GenerateVideos", "error", err)
+		return codedToolResultError(errorCode(err), fmt.Sprintf("error starting video generation (%s): %v", callType, err)), nil
 	}
-	log.Printf("GenerateVideos operation (%s) initiated successfully. Operation Name: %s", callType, operation.Name)
+	ctx, logger = withLogAttrs(ctx, "operation_name", operation.Name)
+	logger.Info("GenerateVideos operation initiated successfully")
+	span.SetAttributes(attribute.String("operation_name", operation.Name))
 
 	if progressToken != nil && mcpServer != nil {
 		if err := mcpServer.SendNotificationToClient(
@@ -104,26 +196,22 @@ func callGenerateVideosAPI(
 				"status":        "initiated", // Add a status field
 			},
 		); err != nil {
-			log.Printf("Warning: Failed to send 'initiated' progress notification: %v", err)
+			logger.Warn("Failed to send progress notification", "status", "initiated", "error", err)
 		}
 	}
 
 	pollingStartTime := time.Now()
-	pollingInterval := 15 * time.Second
+	pollingInterval := appConfig.PollInterval
 	pollingAttempt := 0
 
 	for !operation.Done {
 		select {
-		case <-ctx.Done(): // Check if the original MCP request was canceled
-			log.Printf("Parent context for GenerateVideos (%s) polling canceled: %v. Stopping polling and GenAI operation.", callType, ctx.Err())
-			operationCancel() // Attempt to cancel the GenAI operation
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) was canceled by the client: %v", callType, ctx.Err())), nil
-		case <-operationCtx.Done(): // Check if the GenAI operation itself timed out or was canceled
-			log.Printf("Polling loop for GenerateVideos (%s) canceled/timed out by operationCtx: %v", callType, operationCtx.Err())
-			return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) timed out while waiting for completion", callType)), nil
+		case <-operationCtx.Done(): // Canceled by the client (via the parent context) or max wait exceeded
+			return pollingStoppedResult(ctx, callType, operation.Name, maxWait), nil
 		case <-time.After(pollingInterval): // Time to poll
 			pollingAttempt++
-			log.Printf("Polling GenerateVideos operation (%s): %s (Attempt: %d, Elapsed: %v)", callType, operation.Name, pollingAttempt, time.Since(pollingStartTime).Round(time.Second))
+			elapsed := time.Since(pollingStartTime).Round(time.Second)
+			logger.Info("Polling GenerateVideos operation", "attempt", pollingAttempt, "elapsed", elapsed)
 
 			// Send a proactive heartbeat notification BEFORE making the potentially slow network call.
 			// This resets the client's inactivity timer.
@@ -132,25 +220,33 @@ func callGenerateVideosAPI(
 					ctx,
 					"notifications/progress",
 					map[string]interface{}{
-						"progressToken": progressToken,
-						"message":       fmt.Sprintf("Checking video status (polling attempt %d)...", pollingAttempt),
-						"status":        "polling",
+						"progressToken":  progressToken,
+						"message":        fmt.Sprintf("Checking video status (polling attempt %d, elapsed %v)...", pollingAttempt, elapsed),
+						"status":         "polling",
+						"pollCount":      pollingAttempt,
+						"elapsedSeconds": int(elapsed.Seconds()),
 					},
 				); err != nil {
-					log.Printf("Warning: Failed to send 'polling' progress notification: %v", err)
+					logger.Warn("Failed to send progress notification", "status", "polling", "error", err)
 				}
 			}
 
 			var getOpOpts genai.GetOperationConfig
 			// Use operationCtx for the GetVideosOperation call, as it's part of the GenAI operation lifecycle
-			updatedOp, getErr := client.Operations.GetVideosOperation(operationCtx, operation, &getOpOpts)
+			updatedOp, getErr := retryWithBackoff(operationCtx, appConfig.MaxRetryAttempts, fmt.Sprintf("GetVideosOperation (%s) %s", callType, operation.Name), func() (*genai.GenerateVideosOperation, error) {
+				return client.Operations.GetVideosOperation(operationCtx, operation, &getOpOpts)
+			})
 			if getErr != nil {
-				log.Printf("Error polling GenerateVideos operation (%s) %s: %v", callType, operation.Name, getErr)
-				// If operationCtx is done, it means the GenAI operation itself was canceled or timed out.
-				if errors.Is(getErr, context.Canceled) || errors.Is(getErr, context.DeadlineExceeded) {
-					return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) polling was canceled or timed out during GetOperation", callType)), nil
+				logger.Warn("Error polling GenerateVideos operation", "error", getErr)
+				// If operationCtx is done, the client canceled the request or the max wait was exceeded.
+				if operationCtx.Err() != nil {
+					return pollingStoppedResult(ctx, callType, operation.Name, maxWait), nil
+				}
+				// Non-retryable errors (e.g., 400 or authentication failures) fail fast.
+				if !isRetryableError(getErr) {
+					return codedToolResultError(errorCode(getErr), fmt.Sprintf("error polling video generation (%s): %v. Operation name: %s (use veo_get_operation to check its result later)", callType, getErr, operation.Name)), nil
 				}
-				// For other errors, notify and continue (could be transient)
+				// Transient errors that exhausted their retries: notify and keep polling.
 				if progressToken != nil && mcpServer != nil {
 					if err := mcpServer.SendNotificationToClient(
 						ctx,
@@ -161,48 +257,59 @@ func callGenerateVideosAPI(
 							"status":        "polling_issue",
 						},
 					); err != nil {
-						log.Printf("Warning: Failed to send 'polling_issue' progress notification: %v", err)
+						logger.Warn("Failed to send progress notification", "status", "polling_issue", "error", err)
 					}
 				}
 				continue // Continue polling
 			}
 			operation = updatedOp // Update to the latest operation status
+			span.AddEvent("poll", trace.WithAttributes(
+				attribute.Int("poll_count", pollingAttempt),
+				attribute.Int64("elapsed_ms", time.Since(pollingStartTime).Milliseconds()),
+				attribute.Bool("done", operation.Done),
+			))
 
 			if progressToken != nil && mcpServer != nil {
-				progressMessage := fmt.Sprintf("Video generation (%s) in progress. Polling attempt %d.", callType, pollingAttempt)
+				progressMessage := fmt.Sprintf("Video generation (%s) in progress. Polling attempt %d, elapsed %v.", callType, pollingAttempt, elapsed)
 				progressPercent := -1 // Default to -1 if not available
 
 				if operation.Metadata != nil {
 					if state, ok := operation.Metadata["state"].(string); ok {
-						progressMessage = fmt.Sprintf("Video generation (%s) state: %s. Polling attempt %d.", callType, state, pollingAttempt)
+						progressMessage = fmt.Sprintf("Video generation (%s) state: %s. Polling attempt %d, elapsed %v.", callType, state, pollingAttempt, elapsed)
 					}
 					if p, ok := operation.Metadata["progress_percent"].(float64); ok {
 						progressPercent = int(p)
-						progressMessage = fmt.Sprintf("Video generation (%s) is %d%% complete. Polling attempt %d.", callType, progressPercent, pollingAttempt)
+						progressMessage = fmt.Sprintf("Video generation (%s) is %d%% complete. Polling attempt %d, elapsed %v.", callType, progressPercent, pollingAttempt, elapsed)
 					} else if p, ok := operation.Metadata["progressPercent"].(float64); ok { // Check alternative casing
 						progressPercent = int(p)
-						progressMessage = fmt.Sprintf("Video generation (%s) is %d%% complete. Polling attempt %d.", callType, progressPercent, pollingAttempt)
+						progressMessage = fmt.Sprintf("Video generation (%s) is %d%% complete. Polling attempt %d, elapsed %v.", callType, progressPercent, pollingAttempt, elapsed)
 					}
 				}
 
 				payload := map[string]interface{}{
-					"progressToken": progressToken,
-					"message":       progressMessage,
-					"status":        "processing",
+					"progressToken":  progressToken,
+					"message":        progressMessage,
+					"status":         "processing",
+					"pollCount":      pollingAttempt,
+					"elapsedSeconds": int(elapsed.Seconds()),
 				}
 				if progressPercent != -1 {
 					payload["progress"] = progressPercent
 					payload["total"] = 100
+				} else {
+					// Without a reported percentage, use the poll count as a monotonically
+					// increasing progress value so clients still receive a heartbeat.
+					payload["progress"] = pollingAttempt
 				}
 				if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", payload); err != nil {
-					log.Printf("Warning: Failed to send 'processing' progress notification: %v", err)
+					logger.Warn("Failed to send progress notification", "status", "processing", "error", err)
 				}
 			}
 		}
 	}
 
 	operationDuration := time.Since(startTime)
-	log.Printf("GenerateVideos operation (%s) %s completed. Total duration: %v", callType, operation.Name, operationDuration.Round(time.Second))
+	logger.Info("GenerateVideos operation completed", "duration", operationDuration.Round(time.Second))
 
 	if progressToken != nil && mcpServer != nil {
 		finalStatus := "completed_successfully"
@@ -222,130 +329,783 @@ func callGenerateVideosAPI(
 				"total":         100,
 			},
 		); err != nil {
-			log.Printf("Warning: Failed to send final progress notification: %v", err)
+			logger.Warn("Failed to send final progress notification", "error", err)
 		}
 	}
 
 	if operation.Error != nil {
-		var errMessage string
-		var errCode int32
-
-		// Try to get structured error details if available (e.g., from google.rpc.Status)
-		// The genai.Operation.Error is map[string]interface{}
-		if codeVal, ok := operation.Error["code"]; ok {
-			if c, okFloat := codeVal.(float64); okFloat { // JSON numbers are float64
-				errCode = int32(c)
-			}
-		}
-		if msgVal, ok := operation.Error["message"]; ok {
-			if m, okStr := msgVal.(string); okStr {
-				errMessage = m
-			}
-		}
-
-		if errMessage == "" { // Fallback if direct fields aren't found or not of expected type
-			errorBytes, jsonErr := json.Marshal(operation.Error)
-			if jsonErr != nil {
-				errMessage = fmt.Sprintf("operation failed with unmarshalable error: %v. Original error map: %v", jsonErr, operation.Error)
-			} else {
-				errMessage = string(errorBytes)
-			}
-		}
-		log.Printf("GenerateVideos operation (%s) %s failed with error: %s (Code: %d, FullError: %v)", callType, operation.Name, errMessage, errCode, operation.Error)
-		return mcp.NewToolResultError(fmt.Sprintf("video generation (%s) failed: %s (code: %d)", callType, errMessage, errCode)), nil
-	}
-
-	if operation.Response == nil || len(operation.Response.GeneratedVideos) == 0 {
-		log.Printf("No videos generated (%s) by operation %s, despite successful completion.", callType, operation.Name)
-		return mcp.NewToolResultText(fmt.Sprintf("Sorry, I couldn't generate any videos (%s) for your request (operation completed but no videos found).", callType)), nil
+		errMessage, errCode := operationErrorDetails(operation.Error)
+		logger.Error("GenerateVideos operation failed", "error", errMessage, "code", errCode, "full_error", operation.Error)
+		return codedToolResultError(operationErrorCode(errCode), fmt.Sprintf("video generation (%s) failed: %s (code: %d)", callType, errMessage, errCode)), nil
 	}
 
-	log.Printf("Successfully generated %d videos (%s) by operation %s.", len(operation.Response.GeneratedVideos), callType, operation.Name)
 
-	var gcsVideoURIs []string
-	var downloadedLocalFiles []string
-	var downloadErrors []string
+	var durationSecs int32
+	if config.DurationSeconds != nil {
+		durationSecs = *config.DurationSeconds
+	}
+	summary := videoResult{
+		Status:         "completed",
+		OperationName:  operation.Name,
+		Model:          modelName,
+		AspectRatio:    config.AspectRatio,
+		Duration:       durationSecs,
+		ElapsedSeconds: int(operationDuration.Seconds()),
+	}
 
-	for i, generatedVideo := range operation.Response.GeneratedVideos {
-		videoGCSURI := ""
-		if generatedVideo.Video != nil && generatedVideo.Video.URI != "" {
-			videoGCSURI = generatedVideo.Video.URI
-		}
+	if enhancedPrompt := enhancedPromptFromOperation(operation); enhancedPrompt != "" {
+		logger.Info("Backend reported an enhanced prompt", "enhanced_prompt", enhancedPrompt)
+		span.SetAttributes(attribute.String("enhanced_prompt", enhancedPrompt))
+		summary.EnhancedPrompt = enhancedPrompt
+	}
 
-		if videoGCSURI == "" {
-			log.Printf("Generated video %d (%s) (model: %s, operation: %s) had no retrievable GCS URI.", i, callType, modelName, operation.Name)
-			continue
+	if operation.Response == nil || len(operation.Response.GeneratedVideos) == 0 {
+		var filteredCount int32
+		var filteredReasons []string
+		if operation.Response != nil {
+			filteredCount = operation.Response.RAIMediaFilteredCount
+			filteredReasons = operation.Response.RAIMediaFilteredReasons
 		}
-		gcsVideoURIs = append(gcsVideoURIs, videoGCSURI)
-		log.Printf("Video %d (%s) generated by operation %s is available at GCS URI: %s", i, callType, operation.Name, videoGCSURI)
+		logger.Warn("No videos generated, despite successful completion", "rai_filtered_count", filteredCount, "rai_filtered_reasons", filteredReasons)
+		span.SetAttributes(
+			attribute.Int("output_count", 0),
+			attribute.Int("rai_filtered_count", int(filteredCount)),
+			attribute.StringSlice("rai_filtered_reasons", filteredReasons),
+		)
+		return codedToolResultError(codeContentFiltered, noVideosMessage(callType, modelName, operation.Name, operation.Response)), nil
+	}
 
-		if attemptLocalDownload {
-			// Construct a descriptive filename similar to Imagen
-			localFilename := fmt.Sprintf("veo-%s-%s-%d.mp4", modelName, time.Now().Format("20060102-150405"), i)
-			localFilepath := filepath.Join(outputDir, localFilename)
-			localFilepath = filepath.Clean(localFilepath)
+	logger.Info("Successfully generated videos", "count", len(operation.Response.GeneratedVideos))
+	if summary.Filtered = filteredVideosFromResponse(operation.Response); len(summary.Filtered) > 0 {
+		logger.Warn("Some videos were filtered", "rai_filtered_count", len(summary.Filtered), "rai_filtered_reasons", operation.Response.RAIMediaFilteredReasons)
+		span.SetAttributes(
+			attribute.Int("rai_filtered_count", len(summary.Filtered)),
+			attribute.StringSlice("rai_filtered_reasons", operation.Response.RAIMediaFilteredReasons),
+		)
+	}
 
-			log.Printf("Attempting to download video %d from GCS URI %s to %s", i, videoGCSURI, localFilepath)
-			downloadErr := common.DownloadFromGCS(ctx, videoGCSURI, localFilepath)
-			if downloadErr != nil {
-				errMsg := fmt.Sprintf("Error downloading video %d from %s to %s: %v", i, videoGCSURI, localFilepath, downloadErr)
-				log.Print(errMsg)
-				downloadErrors = append(downloadErrors, errMsg)
-			} else {
-				log.Printf("Successfully downloaded and saved video %d to %s", i, localFilepath)
-				downloadedLocalFiles = append(downloadedLocalFiles, localFilepath)
-			}
-		}
+	outputs := collectGeneratedVideos(ctx, operation, outputDir, objectOptions.FilenamePrefix, objectOptions.OutputURI, modelName)
+	span.SetAttributes(
+		attribute.Int("output_count", outputs.Count()),
+		attribute.StringSlice("output_gcs_uris", outputs.GCSURIs),
+		attribute.Int64("output_total_bytes", outputs.TotalBytes),
+	)
+	objectErrors := applyOutputObjectOptions(ctx, outputs.GCSURIs, objectOptions)
+	span.AddEvent("outputs_written", trace.WithAttributes(
+		attribute.Int("output_count", outputs.Count()),
+		attribute.Int("local_file_count", len(outputs.LocalFiles)),
+		attribute.Int("error_count", len(outputs.Errors)+len(objectErrors)),
+	))
+	// Only results that live entirely in GCS can be returned again later.
+	if cacheKey != "" && len(outputs.GCSURIs) > 0 && outputs.InlineCount == 0 {
+		videoResults.Put(cacheKey, cachedResult{
+			OperationName: operation.Name,
+			Model:         modelName,
+			GCSURIs:       outputs.GCSURIs,
+			CreatedAt:     time.Now(),
+		})
+	}
+	if len(outputs.GCSURIs) > 0 || len(outputs.LocalFiles) > 0 {
+		recentOutputs.Add(recentOutput{
+			Prompt:        prompt,
+			Model:         modelName,
+			CallType:      callType,
+			OperationName: operation.Name,
+			GCSURIs:       outputs.GCSURIs,
+			LocalPaths:    outputs.LocalFiles,
+			CreatedAt:     time.Now(),
+		})
 	}
 
 	var resultText string
 	var saveMessageParts []string
 
-	if len(gcsVideoURIs) > 0 {
-		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Videos saved to GCS: %s.", strings.Join(gcsVideoURIs, ", ")))
+	if len(outputs.GCSURIs) > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Videos saved to GCS: %s.", strings.Join(outputs.GCSURIs, ", ")))
+	}
+
+	if objectOptions.IsSet() && len(outputs.GCSURIs) > 0 {
+		if len(objectErrors) > 0 {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Output object issues: %s.", strings.Join(objectErrors, "; ")))
+		} else {
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Applied %s to the GCS outputs.", objectOptions.Describe()))
+		}
 	}
 
 	if attemptLocalDownload {
-		if len(downloadedLocalFiles) > 0 { // Only mention outputDir if downloads were attempted and successful
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Successfully downloaded locally to '%s': %s.", outputDir, strings.Join(downloadedLocalFiles, ", ")))
+		if len(outputs.LocalFiles) > 0 { // Only mention outputDir if downloads were attempted and successful
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Successfully saved locally to '%s': %s.", outputDir, strings.Join(outputs.LocalFiles, ", ")))
 		} else if outputDir != "" { // If outputDir was specified but no files downloaded (all errors or no videos)
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to download videos to local directory '%s'.", outputDir))
-		}
-		if len(downloadErrors) > 0 {
-			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Local download/save issues: %s.", strings.Join(downloadErrors, "; ")))
+			saveMessageParts = append(saveMessageParts, fmt.Sprintf("Attempted to save videos to local directory '%s'.", outputDir))
 		}
 	}
+	if message := thumbnailsMessage(outputs); message != "" {
+		saveMessageParts = append(saveMessageParts, message)
+	}
+	if len(outputs.Errors) > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("Local download/save issues: %s.", strings.Join(outputs.Errors, "; ")))
+	}
+
+	if len(outputs.InlineVideos) > 0 {
+		saveMessageParts = append(saveMessageParts, fmt.Sprintf("No output GCS bucket was configured, so %d video(s) are returned inline as base64-encoded data.", len(outputs.InlineVideos)))
+	}
+	if message := filteredVideosMessage(summary.Filtered); message != "" {
+		saveMessageParts = append(saveMessageParts, message)
+	}
 
-	if len(gcsVideoURIs) > 0 {
+	if outputs.Count() > 0 {
+		// Veo does not expose a watermark toggle; every generated video carries SynthID.
+		summary.Watermarked = true
+		span.SetAttributes(attribute.Bool("watermarked", true))
+		saveMessageParts = append(saveMessageParts, synthIDVideoNote)
 		resultText = fmt.Sprintf("Generated %d video(s) using model %s. This took about %s. %s",
-			len(gcsVideoURIs),
+			outputs.Count(),
 			modelName,
 			operationDuration.Round(time.Second),
 			strings.Join(saveMessageParts, " "),
 		)
-	} else if operation.Error == nil {
-		resultText = fmt.Sprintf("Processed request (%s) for model %s (took %s), but no video URIs were found in the completed operation %s. No specific error reported by the operation.",
+	} else {
+		resultText = fmt.Sprintf("Processed request (%s) for model %s (took %s), but no videos were found in the completed operation %s. No specific error reported by the operation.",
 			callType,
 			modelName,
 			operationDuration.Round(time.Second),
 			operation.Name,
 		)
-		if len(downloadErrors) > 0 { // If there were download errors even with no GCS URIs (shouldn't happen but good to cover)
+		if len(outputs.Errors) > 0 { // If there were save errors even with no videos (shouldn't happen but good to cover)
 			resultText += " " + strings.Join(saveMessageParts, " ")
 		}
+	}
+
+	if summary.EnhancedPrompt != "" {
+		resultText += fmt.Sprintf(" Enhanced prompt used by Veo: %q.", summary.EnhancedPrompt)
+	}
+
+	summary.Errors = slices.Concat(outputs.Errors, objectErrors)
+	return videoToolResult(outputFormat, strings.TrimSpace(resultText), summary, outputs)
+}
+
+// synthIDVideoNote reports the watermark status of generated videos, which Veo always
+// marks with a SynthID digital watermark.
+const synthIDVideoNote = "SynthID watermark: enabled (always applied by Veo)."
+
+// videoResult is the machine-readable result returned by the Veo tools when
+// output_format is "json".
+type videoResult struct {
+	Status           string   `json:"status"`
+	OperationName    string   `json:"operation_name"`
+	Model            string   `json:"model"`
+	AspectRatio      string   `json:"aspect_ratio,omitempty"`
+	Duration         int32    `json:"duration,omitempty"`
+	GCSURIs          []string `json:"gcs_uris"`
+	LocalPaths       []string `json:"local_paths"`
+	InlineVideoCount int      `json:"inline_video_count"`
+	Watermarked      bool     `json:"watermarked"`
+	ElapsedSeconds   int      `json:"elapsed_seconds,omitempty"`
+	EnhancedPrompt   string   `json:"enhanced_prompt,omitempty"`
+	Cached           bool     `json:"cached,omitempty"`
+	// ThumbnailPaths and ThumbnailGCSURIs list the poster frames extracted when
+	// GENMEDIA_VIDEO_THUMBNAILS is enabled.
+	ThumbnailPaths   []string `json:"thumbnail_paths,omitempty"`
+	ThumbnailGCSURIs []string `json:"thumbnail_gcs_uris,omitempty"`
+	// Filtered lists the videos removed by the safety filters, if any.
+	Filtered []filteredVideo `json:"filtered,omitempty"`
+	Errors   []string        `json:"errors,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// filteredVideo is a requested video that the safety filters removed from the output.
+type filteredVideo struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// filteredVideosFromResponse returns an entry for each video the safety filters removed from
+// response. The API reports a count and a list of reasons rather than per-video records; the
+// reasons are assigned to the entries in order, and entries without a reported reason are left
+// blank.
+func filteredVideosFromResponse(response *genai.GenerateVideosResponse) []filteredVideo {
+	if response == nil {
+		return nil
+	}
+	count := max(int(response.RAIMediaFilteredCount), len(response.RAIMediaFilteredReasons))
+	if count == 0 {
+		return nil
+	}
+	filtered := make([]filteredVideo, count)
+	for i, reason := range response.RAIMediaFilteredReasons {
+		filtered[i].Reason = reason
+	}
+	return filtered
+}
+
+// filteredVideosMessage describes the videos removed by the safety filters, or returns an
+// empty string if there were none.
+func filteredVideosMessage(filtered []filteredVideo) string {
+	if len(filtered) == 0 {
+		return ""
+	}
+	var reasons []string
+	for _, video := range filtered {
+		if video.Reason != "" {
+			reasons = append(reasons, video.Reason)
+		}
+	}
+	if len(reasons) == 0 {
+		return fmt.Sprintf("%d video(s) were removed by the safety filters.", len(filtered))
+	}
+	return fmt.Sprintf("%d video(s) were removed by the safety filters: %s.", len(filtered), strings.Join(reasons, "; "))
+}
+
+// videoToolResult builds the tool result for a Veo operation. In "json" mode the content is
+// the JSON-encoded result (also set as structured content); otherwise it is the message text.
+// Any inline videos are appended as embedded resources in both modes.
+func videoToolResult(outputFormat, message string, result videoResult, outputs *videoOutputs) (*mcp.CallToolResult, error) {
+	var toolResult *mcp.CallToolResult
+	if outputFormat == "json" {
+		result.Message = message
+		if outputs != nil {
+			result.GCSURIs = outputs.GCSURIs
+			result.LocalPaths = outputs.LocalFiles
+			result.ThumbnailPaths = outputs.Thumbnails
+			result.ThumbnailGCSURIs = outputs.ThumbnailURIs
+			result.InlineVideoCount = len(outputs.InlineVideos)
+		}
+		if result.GCSURIs == nil {
+			result.GCSURIs = []string{}
+		}
+		if result.LocalPaths == nil {
+			result.LocalPaths = []string{}
+		}
+		resultJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal result: %v", err)), nil
+		}
+		toolResult = mcp.NewToolResultStructured(result, string(resultJSON))
 	} else {
-		// This case should ideally be caught by the operation.Error check earlier.
-		// If we reach here, it implies operation.Error was non-nil but didn't lead to an early return.
-		resultText = fmt.Sprintf("Video generation request (%s) for model %s (took %s) did not yield videos and encountered an issue with operation %s.",
-			callType,
-			modelName,
-			operationDuration.Round(time.Second),
-			operation.Name,
-		)
-		if len(downloadErrors) > 0 {
-			resultText += " " + strings.Join(saveMessageParts, " ")
+		toolResult = mcp.NewToolResultText(message)
+	}
+	if outputs != nil {
+		toolResult.Content = append(toolResult.Content, outputs.InlineVideos...)
+	}
+	return toolResult, nil
+}
+
+// pollingStoppedResult builds the tool result returned when polling stops before the
+// operation completes, distinguishing a client cancellation (ctx is done) from the max
+// wait being exceeded. Polling stopping does not cancel the operation, so it may still
+// finish server-side; the operation name is returned so its result can be retrieved later
+// with veo_get_operation, or so it can be stopped with veo_cancel_operation. A stop caused by the request's
+// timeout_seconds is reported as a timeout rather than a cancellation.
+func pollingStoppedResult(ctx context.Context, callType, operationName string, maxWait time.Duration) *mcp.CallToolResult {
+	if timeoutErr, ok := requestTimedOut(ctx); ok {
+		loggerFromContext(ctx).Warn("GenerateVideos operation was stopped; stopped polling", "error", timeoutErr)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("timed_out", true))
+		return codedToolResultError(codeDeadlineExceeded, fmt.Sprintf("video generation (%s) did not complete within %v set by timeout_seconds. Operation name: %s (use veo_get_operation to check its result later)", callType, timeoutErr.timeout, operationName))
+	}
+	if ctx.Err() != nil {
+		loggerFromContext(ctx).Warn("GenerateVideos operation was canceled by the client; stopped polling", "error", ctx.Err())
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("canceled", true))
+		return codedToolResultError(codeCancelled, fmt.Sprintf("video generation (%s) was canceled by the client: %v. Operation name: %s (use veo_get_operation to check its result later)", callType, ctx.Err(), operationName))
+	}
+	loggerFromContext(ctx).Warn("GenerateVideos operation did not complete within the maximum wait; stopped polling", "max_wait", maxWait)
+	return codedToolResultError(codeDeadlineExceeded, fmt.Sprintf("video generation (%s) did not complete within the maximum wait of %v. Operation name: %s (use veo_get_operation to check its result later)", callType, maxWait, operationName))
+}
+
+// requestTimedOut reports whether ctx was canceled by the request's timeout_seconds, returning
+// the timeout error if so.
+func requestTimedOut(ctx context.Context) (requestTimeoutError, bool) {
+	var timeoutErr requestTimeoutError
+	ok := errors.As(context.Cause(ctx), &timeoutErr)
+	return timeoutErr, ok
+}
+
+// dryRunConfig describes the fully resolved request returned by a dry run.
+type dryRunConfig struct {
+	Status               string            `json:"status"`
+	CallType             string            `json:"call_type"`
+	Model                string            `json:"model"`
+	Prompt               string            `json:"prompt,omitempty"`
+	NegativePrompt       string            `json:"negative_prompt,omitempty"`
+	InputImage           string            `json:"input_image,omitempty"`
+	SourceVideo          string            `json:"source_video,omitempty"`
+	LastFrame            string            `json:"last_frame,omitempty"`
+	AspectRatio          string            `json:"aspect_ratio"`
+	Resolution           string            `json:"resolution,omitempty"`
+	NumberOfVideos       int32             `json:"num_videos"`
+	DurationSecs         int32             `json:"duration"`
+	GenerateAudio        *bool             `json:"generate_audio,omitempty"`
+	EnhancePrompt        bool              `json:"enhance_prompt,omitempty"`
+	Seed                 *int32            `json:"seed,omitempty"`
+	PersonGeneration     string            `json:"person_generation,omitempty"`
+	CompressionQuality   string            `json:"compression_quality,omitempty"`
+	ReferenceImageCount  int               `json:"reference_image_count"`
+	OutputGCSURI         string            `json:"output_gcs_uri,omitempty"`
+	OutputDir            string            `json:"output_directory,omitempty"`
+	OutputFilenamePrefix string            `json:"output_filename_prefix,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+}
+
+// describeVideo returns a short description of a source video for a dry run result.
+func describeVideo(video *genai.Video) string {
+	if video == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", video.URI, video.MIMEType)
+}
+
+// describeImage returns a short description of an input image for a dry run result.
+func describeImage(image *genai.Image) string {
+	if image == nil {
+		return ""
+	}
+	if image.GCSURI != "" {
+		return fmt.Sprintf("%s (%s)", image.GCSURI, image.MIMEType)
+	}
+	return fmt.Sprintf("inline image, %d bytes (%s)", len(image.ImageBytes), image.MIMEType)
+}
+
+// dryRunResult builds the tool result for a request made with dry_run set. All parameters have
+// already been validated and resolved into config, so this describes exactly what would have been
+// sent to GenerateVideos, without calling the API or spending quota.
+func dryRunResult(ctx context.Context, callType, prompt string, image *genai.Image, video *genai.Video, config *genai.GenerateVideosConfig, params *VideoParams) (*mcp.CallToolResult, error) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("dry_run", true))
+	loggerFromContext(ctx).Info("Dry run: request validated; GenerateVideos was not called", "call_type", callType)
+
+	resolved := dryRunConfig{
+		Status:               "dry_run",
+		CallType:             callType,
+		Model:                params.Model,
+		Prompt:               prompt,
+		NegativePrompt:       config.NegativePrompt,
+		InputImage:           describeImage(image),
+		SourceVideo:          describeVideo(video),
+		LastFrame:            describeImage(config.LastFrame),
+		AspectRatio:          config.AspectRatio,
+		Resolution:           config.Resolution,
+		NumberOfVideos:       config.NumberOfVideos,
+		DurationSecs:         params.DurationSecs,
+		GenerateAudio:        config.GenerateAudio,
+		EnhancePrompt:        config.EnhancePrompt,
+		Seed:                 config.Seed,
+		PersonGeneration:     config.PersonGeneration,
+		CompressionQuality:   string(config.CompressionQuality),
+		ReferenceImageCount:  len(config.ReferenceImages),
+		OutputGCSURI:         config.OutputGCSURI,
+		OutputDir:            params.OutputDir,
+		OutputFilenamePrefix: params.OutputFilenamePrefix,
+		Labels:               params.Labels,
+	}
+
+	resolvedJSON, err := json.MarshalIndent(resolved, "", "  ")
+	if err != nil {
+		return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal dry run result: %v", err)), nil
+	}
+	if params.OutputFormat == "json" {
+		return mcp.NewToolResultStructured(resolved, string(resolvedJSON)), nil
+	}
+
+	// Extensions keep the aspect ratio of the source video, so they have none to report.
+	aspectRatio := ""
+	if config.AspectRatio != "" {
+		aspectRatio = " at aspect ratio " + config.AspectRatio
+	}
+	summary := fmt.Sprintf("Dry run (%s): the request is valid. It would generate %d video(s) of %ds with model %s%s. The Veo API was not called.", callType, config.NumberOfVideos, params.DurationSecs, params.Model, aspectRatio)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: summary},
+			mcp.TextContent{Type: "text", Text: string(resolvedJSON)},
+		},
+	}, nil
+}
+
+// aspectRatioResult is the outcome for one aspect ratio of a batch generation.
+type aspectRatioResult struct {
+	AspectRatio string `json:"aspect_ratio"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	Result      any    `json:"result,omitempty"`
+}
+
+// aspectRatioBatchResult is the machine-readable result of a batch generation. Status is
+// "completed" if every aspect ratio succeeded, "partial" if some failed, or "failed".
+type aspectRatioBatchResult struct {
+	Status  string              `json:"status"`
+	Results []aspectRatioResult `json:"results"`
+}
+
+// generateAspectRatioBatch runs generate once per aspect ratio, concurrently, and aggregates
+// the per-ratio results into a single tool result. A failure for one aspect ratio is reported
+// alongside the others rather than aborting the batch; the aggregate is only an error result
+// if every aspect ratio failed.
+func generateAspectRatioBatch(ctx context.Context, aspectRatios []string, outputFormat string, generate func(aspectRatio string) (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	results := make([]*mcp.CallToolResult, len(aspectRatios))
+	var wg sync.WaitGroup
+	for i, aspectRatio := range aspectRatios {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := generate(aspectRatio)
+			if err != nil {
+				result = codedToolResultError(errorCode(err), err.Error())
+			} else if result == nil {
+				result = codedToolResultError(codeInternal, "no result was returned")
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	batch := aspectRatioBatchResult{Results: make([]aspectRatioResult, len(aspectRatios))}
+	var summaries, inlineVideos []mcp.Content
+	failed := 0
+	for i, result := range results {
+		entry := aspectRatioResult{AspectRatio: aspectRatios[i], Status: "completed"}
+		if result.IsError {
+			entry.Status = "failed"
+			failed++
+		}
+		var texts []string
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			} else {
+				inlineVideos = append(inlineVideos, content)
+			}
+		}
+		if result.StructuredContent != nil && !result.IsError {
+			entry.Result = result.StructuredContent
+		} else {
+			entry.Message = strings.Join(texts, " ")
+		}
+		batch.Results[i] = entry
+		if outputFormat != "json" {
+			summaries = append(summaries, mcp.NewTextContent(fmt.Sprintf("Aspect ratio %s (%s): %s", entry.AspectRatio, entry.Status, strings.Join(texts, " "))))
+		}
+	}
+
+	switch failed {
+	case 0:
+		batch.Status = "completed"
+	case len(aspectRatios):
+		batch.Status = "failed"
+	default:
+		batch.Status = "partial"
+	}
+	loggerFromContext(ctx).Info("Aspect ratio batch finished", "succeeded", len(aspectRatios)-failed, "failed", failed)
+
+	var toolResult *mcp.CallToolResult
+	if outputFormat == "json" {
+		batchJSON, err := json.MarshalIndent(batch, "", "  ")
+		if err != nil {
+			return codedToolResultError(codeInternal, fmt.Sprintf("failed to marshal batch result: %v", err)), nil
+		}
+		toolResult = mcp.NewToolResultStructured(batch, string(batchJSON))
+	} else {
+		toolResult = mcp.NewToolResultText(fmt.Sprintf("Generated videos for %d of %d aspect ratio(s): %s.", len(aspectRatios)-failed, len(aspectRatios), strings.Join(aspectRatios, ", ")))
+	}
+	toolResult.Content = append(toolResult.Content, summaries...)
+	toolResult.Content = append(toolResult.Content, inlineVideos...)
+	toolResult.IsError = failed == len(aspectRatios)
+	return toolResult, nil
+}
+
+// operationErrorDetails extracts a human-readable message and error code from a
+// failed operation's error map. The genai.Operation.Error is a map[string]interface{},
+// typically mirroring a google.rpc.Status.
+func operationErrorDetails(opError map[string]interface{}) (string, int32) {
+	var errMessage string
+	var errCode int32
+
+	if codeVal, ok := opError["code"]; ok {
+		if c, okFloat := codeVal.(float64); okFloat { // JSON numbers are float64
+			errCode = int32(c)
+		}
+	}
+	if msgVal, ok := opError["message"]; ok {
+		if m, okStr := msgVal.(string); okStr {
+			errMessage = m
+		}
+	}
+
+	if errMessage == "" { // Fallback if direct fields aren't found or not of expected type
+		errorBytes, jsonErr := json.Marshal(opError)
+		if jsonErr != nil {
+			errMessage = fmt.Sprintf("operation failed with unmarshalable error: %v. Original error map: %v", jsonErr, opError)
+		} else {
+			errMessage = string(errorBytes)
 		}
 	}
+	return errMessage, errCode
+}
+
+// enhancedPromptKeys are the operation metadata keys under which the backend may report the
+// prompt it actually used after rewriting it (when enhance_prompt is on).
+var enhancedPromptKeys = []string{"enhancedPrompt", "enhanced_prompt", "rewrittenPrompt", "rewritten_prompt"}
+
+// enhancedPromptFromOperation returns the enhanced prompt recorded in a completed operation's
+// metadata, or an empty string if the backend did not report one.
+func enhancedPromptFromOperation(operation *genai.GenerateVideosOperation) string {
+	if operation == nil || operation.Metadata == nil {
+		return ""
+	}
+	for _, key := range enhancedPromptKeys {
+		if p, ok := operation.Metadata[key].(string); ok && strings.TrimSpace(p) != "" {
+			return strings.TrimSpace(p)
+		}
+	}
+	return ""
+}
+
+// noVideosMessage describes an operation that completed without returning any videos. The
+// Veo API does this when the safety filters remove every output, so the message reports the
+// filtered count and reasons from the response when present.
+func noVideosMessage(callType, modelName, operationName string, response *genai.GenerateVideosResponse) string {
+	message := fmt.Sprintf("video generation (%s) with model %s completed (operation %s) but returned no videos; the content was likely filtered by the Responsible AI safety filters.", callType, modelName, operationName)
+	if response == nil || (response.RAIMediaFilteredCount == 0 && len(response.RAIMediaFilteredReasons) == 0) {
+		return message + " The API reported no filter details."
+	}
+	message += fmt.Sprintf(" Videos filtered: %d.", response.RAIMediaFilteredCount)
+	if len(response.RAIMediaFilteredReasons) > 0 {
+		message += fmt.Sprintf(" Reasons: %s.", strings.Join(response.RAIMediaFilteredReasons, "; "))
+	}
+	return message + " Try rephrasing the prompt or changing the input images."
+}
+
+// outputObjectOptions are applied to each generated GCS object after generation.
+type outputObjectOptions struct {
+	// Labels are applied as custom metadata.
+	Labels map[string]string
+	// StorageClass, if set, is the storage class the objects are moved to.
+	StorageClass string
+	// RetentionDays, if positive, sets each object's Custom-Time that many days ahead,
+	// for use with a bucket lifecycle rule that deletes objects after their Custom-Time.
+	RetentionDays int
+	// FilenamePrefix, if set, names the outputs <prefix>_<index>.mp4, both in GCS and in the
+	// local output directory. It is applied while collecting the outputs, not by
+	// applyOutputObjectOptions, and is not reported by IsSet.
+	FilenamePrefix string
+	// OutputURI, if set, is the GCS object the single output is moved to. Like FilenamePrefix,
+	// it is applied while collecting the outputs and is not reported by IsSet.
+	OutputURI string
+}
+
+// IsSet reports whether any option needs to be applied to the outputs.
+func (o outputObjectOptions) IsSet() bool {
+	return len(o.Labels) > 0 || o.StorageClass != "" || o.RetentionDays > 0
+}
+
+// Describe returns a short description of the options, e.g. "2 label(s), storage class NEARLINE".
+func (o outputObjectOptions) Describe() string {
+	var parts []string
+	if len(o.Labels) > 0 {
+		parts = append(parts, fmt.Sprintf("%d label(s)", len(o.Labels)))
+	}
+	if o.StorageClass != "" {
+		parts = append(parts, fmt.Sprintf("storage class %s", o.StorageClass))
+	}
+	if o.RetentionDays > 0 {
+		parts = append(parts, fmt.Sprintf("a %d-day retention", o.RetentionDays))
+	}
+	return strings.Join(parts, ", ")
+}
 
-	return mcp.NewToolResultText(strings.TrimSpace(resultText)), nil
+// applyOutputObjectOptions applies the storage class, labels, and retention to each generated
+// GCS object. The genai video API does not accept these settings, so they are applied to the
+// outputs after generation; nothing is done if no GCS output was produced. The storage class
+// is changed first because it rewrites the object. It returns a description of each failure.
+func applyOutputObjectOptions(ctx context.Context, gcsURIs []string, opts outputObjectOptions) []string {
+	var objectErrors []string
+	if !opts.IsSet() {
+		return objectErrors
+	}
+	logger := loggerFromContext(ctx)
+	var expiresAt time.Time
+	if opts.RetentionDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, opts.RetentionDays)
+	}
+	for _, gcsURI := range gcsURIs {
+		if opts.StorageClass != "" {
+			if err := common.SetGCSObjectStorageClass(ctx, gcsURI, opts.StorageClass); err != nil {
+				errMsg := fmt.Sprintf("Error setting storage class of %s: %v", gcsURI, err)
+				logger.Warn(errMsg)
+				objectErrors = append(objectErrors, errMsg)
+			}
+		}
+		if len(opts.Labels) > 0 {
+			if err := common.SetGCSObjectMetadata(ctx, gcsURI, opts.Labels); err != nil {
+				errMsg := fmt.Sprintf("Error applying labels to %s: %v", gcsURI, err)
+				logger.Warn(errMsg)
+				objectErrors = append(objectErrors, errMsg)
+			}
+		}
+		if opts.RetentionDays > 0 {
+			if err := common.SetGCSObjectCustomTime(ctx, gcsURI, expiresAt); err != nil {
+				errMsg := fmt.Sprintf("Error setting retention of %s: %v", gcsURI, err)
+				logger.Warn(errMsg)
+				objectErrors = append(objectErrors, errMsg)
+			}
+		}
+	}
+	return objectErrors
+}
+
+// videoOutputs records where the videos produced by a completed operation ended up.
+type videoOutputs struct {
+	GCSURIs    []string
+	LocalFiles []string
+	// InlineVideos holds videos the API returned as bytes (because no output GCS URI was
+	// set) and that were not saved to a local directory, as base64 embedded resources.
+	InlineVideos []mcp.Content
+	// InlineCount is the number of videos returned as bytes, whether saved locally or not.
+	InlineCount int
+	// TotalBytes is the combined size of the videos, where it could be determined.
+	TotalBytes int64
+	// Thumbnails lists the local JPEG poster frames extracted when GENMEDIA_VIDEO_THUMBNAILS
+	// is enabled, and ThumbnailURIs the copies uploaded next to videos that are in GCS.
+	Thumbnails    []string
+	ThumbnailURIs []string
+	Errors        []string
+}
+
+// Count returns the number of videos that were retrieved from the operation.
+func (o *videoOutputs) Count() int {
+	return len(o.GCSURIs) + o.InlineCount
+}
+
+// downloadOptions returns the options used to download outputs to a local directory, from
+// GENMEDIA_DOWNLOAD_CHUNK_SIZE and GENMEDIA_DOWNLOAD_MAX_ATTEMPTS.
+func downloadOptions() common.DownloadOptions {
+	if appConfig == nil {
+		return common.DefaultDownloadOptions
+	}
+	return common.DownloadOptions{ChunkSize: int64(appConfig.DownloadChunkSize), MaxAttempts: appConfig.DownloadMaxAttempts}
+}
+
+// collectGeneratedVideos gathers the videos produced by a completed operation. Videos
+// written to GCS are recorded by URI and, if outputDir is set, downloaded to that directory.
+// Videos returned as bytes are saved to outputDir if set, or otherwise returned inline.
+// If filenamePrefix is set, outputs are named <prefix>_<index>.mp4: GCS objects are renamed
+// within the folder the API wrote them to, and local files are saved under that name.
+// If outputURI is set, the single output is instead moved to that GCS object and saved
+// locally under its base name.
+func collectGeneratedVideos(ctx context.Context, operation *genai.GenerateVideosOperation, outputDir, filenamePrefix, outputURI, modelName string) *videoOutputs {
+	outputs := &videoOutputs{}
+
+	if operation.Response == nil {
+		return outputs
+	}
+	logger := loggerFromContext(ctx)
+
+	if outputDir != "" {
+		if err := common.EnsureWritableDir(outputDir); err != nil {
+			// The videos were generated successfully; only the local copy is unavailable.
+			errMsg := fmt.Sprintf("Videos were generated but could not be saved locally: %v", err)
+			logger.Warn(errMsg)
+			outputs.Errors = append(outputs.Errors, errMsg)
+			outputDir = ""
+		}
+	}
+
+	for i, generatedVideo := range operation.Response.GeneratedVideos {
+		if generatedVideo.Video == nil {
+			logger.Warn("Generated video had no video data", "index", i)
+			continue
+		}
+		// Construct a descriptive filename similar to Imagen
+		localFilename := fmt.Sprintf("veo-%s-%s-%d.mp4", modelName, time.Now().Format("20060102-150405"), i)
+		if filenamePrefix != "" {
+			localFilename = prefixedFilename(filenamePrefix, i, ".mp4")
+		}
+		if outputURI != "" {
+			localFilename = path.Base(outputURI)
+		}
+
+		videoGCSURI := generatedVideo.Video.URI
+		if videoGCSURI == "" {
+			videoBytes := generatedVideo.Video.VideoBytes
+			if len(videoBytes) == 0 {
+				logger.Warn("Generated video had no retrievable GCS URI or bytes", "index", i)
+				continue
+			}
+			outputs.InlineCount++
+			outputs.TotalBytes += int64(len(videoBytes))
+			mimeType := generatedVideo.Video.MIMEType
+			if mimeType == "" {
+				mimeType = "video/mp4"
+			}
+			if outputDir != "" {
+				localFilepath := filepath.Clean(filepath.Join(outputDir, localFilename))
+				if err := os.WriteFile(localFilepath, videoBytes, 0644); err != nil {
+					errMsg := fmt.Sprintf("Error saving video %d to %s: %v", i, localFilepath, err)
+					logger.Warn(errMsg)
+					outputs.Errors = append(outputs.Errors, errMsg)
+				} else {
+					logger.Info("Saved video", "index", i, "size", common.FormatBytes(int64(len(videoBytes))), "path", localFilepath)
+					outputs.LocalFiles = append(outputs.LocalFiles, localFilepath)
+					if videoThumbnailsEnabled() {
+						outputs.addThumbnail(ctx, i, localFilepath, "")
+					}
+					continue
+				}
+			}
+			logger.Info("Returning video inline as base64 data", "index", i, "size", common.FormatBytes(int64(len(videoBytes))))
+			outputs.InlineVideos = append(outputs.InlineVideos, mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+				URI:      localFilename,
+				MIMEType: mimeType,
+				Blob:     base64.StdEncoding.EncodeToString(videoBytes),
+			}))
+			continue
+		}
+
+		if filenamePrefix != "" || outputURI != "" {
+			renamedURI := outputURI
+			if renamedURI == "" {
+				ext := path.Ext(videoGCSURI)
+				if ext == "" {
+					ext = ".mp4"
+				}
+				// path.Dir would collapse the "gs://" scheme, so keep everything up to the last slash.
+				renamedURI = videoGCSURI[:strings.LastIndex(videoGCSURI, "/")+1] + prefixedFilename(filenamePrefix, i, ext)
+			}
+			if renamedURI != videoGCSURI {
+				if err := common.MoveGCSObject(ctx, videoGCSURI, renamedURI); err != nil {
+					errMsg := fmt.Sprintf("Error renaming video %d from %s to %s: %v", i, videoGCSURI, renamedURI, err)
+					logger.Warn(errMsg)
+					outputs.Errors = append(outputs.Errors, errMsg)
+				} else {
+					videoGCSURI = renamedURI
+				}
+			}
+		}
+
+		outputs.GCSURIs = append(outputs.GCSURIs, videoGCSURI)
+		logger.Info("Video is available in GCS", "index", i, "gcs_uri", videoGCSURI)
+
+		if outputDir != "" {
+			localFilepath := filepath.Clean(filepath.Join(outputDir, localFilename))
+
+			logger.Info("Downloading video", "index", i, "gcs_uri", videoGCSURI, "path", localFilepath)
+			downloadErr := common.DownloadFromGCSWithOptions(ctx, videoGCSURI, localFilepath, downloadOptions())
+			if downloadErr != nil {
+				errMsg := fmt.Sprintf("Error downloading video %d from %s to %s: %v", i, videoGCSURI, localFilepath, downloadErr)
+				logger.Warn(errMsg)
+				outputs.Errors = append(outputs.Errors, errMsg)
+			} else {
+				logger.Info("Downloaded and saved video", "index", i, "path", localFilepath)
+				outputs.LocalFiles = append(outputs.LocalFiles, localFilepath)
+				if videoThumbnailsEnabled() {
+					outputs.addThumbnail(ctx, i, localFilepath, videoGCSURI)
+				}
+				if info, err := os.Stat(localFilepath); err == nil {
+					outputs.TotalBytes += info.Size()
+					continue
+				}
+			}
+		}
+		if size, err := common.GCSObjectSize(ctx, videoGCSURI); err != nil {
+			logger.Warn("Could not determine the size of video", "index", i, "gcs_uri", videoGCSURI, "error", err)
+		} else {
+			outputs.TotalBytes += size
+		}
+	}
+	return outputs
 }
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"google.golang.org/genai"
+)
+
+func TestPollingStoppedResult(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"client canceled", canceledCtx, "was canceled by the client"},
+		{"max wait exceeded", context.Background(), "did not complete within the maximum wait of 5m0s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := pollingStoppedResult(tc.ctx, "t2v", "operations/123", 5*time.Minute)
+			if !result.IsError {
+				t.Fatal("expected an error result")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if !strings.Contains(text, tc.expected) || !strings.Contains(text, "operations/123") {
+				t.Errorf("expected result containing '%s' and the operation name, but got '%s'", tc.expected, text)
+			}
+		})
+	}
+}
+
+func TestCollectGeneratedVideosInline(t *testing.T) {
+	operation := &genai.GenerateVideosOperation{
+		Name: "operations/123",
+		Response: &genai.GenerateVideosResponse{
+			GeneratedVideos: []*genai.GeneratedVideo{
+				{Video: &genai.Video{VideoBytes: []byte("video-bytes"), MIMEType: "video/mp4"}},
+				{Video: &genai.Video{}},
+			},
+		},
+	}
+
+	t.Run("returned inline", func(t *testing.T) {
+		outputs := collectGeneratedVideos(t.Context(), operation, "", "", "", "veo-2.0-generate-001")
+		if outputs.Count() != 1 || len(outputs.InlineVideos) != 1 {
+			t.Fatalf("expected 1 inline video, but got count %d and %d inline", outputs.Count(), len(outputs.InlineVideos))
+		}
+		if outputs.TotalBytes != int64(len("video-bytes")) {
+			t.Errorf("expected %d total bytes, but got %d", len("video-bytes"), outputs.TotalBytes)
+		}
+		resource := outputs.InlineVideos[0].(mcp.EmbeddedResource).Resource.(mcp.BlobResourceContents)
+		if resource.Blob != base64.StdEncoding.EncodeToString([]byte("video-bytes")) {
+			t.Errorf("expected base64-encoded video bytes, but got '%s'", resource.Blob)
+		}
+	})
+
+	t.Run("saved locally", func(t *testing.T) {
+		outputDir := t.TempDir()
+		outputs := collectGeneratedVideos(t.Context(), operation, outputDir, "", "", "veo-2.0-generate-001")
+		if outputs.Count() != 1 || len(outputs.LocalFiles) != 1 || len(outputs.InlineVideos) != 0 {
+			t.Fatalf("expected 1 local file and no inline videos, but got %+v", outputs)
+		}
+		data, err := os.ReadFile(outputs.LocalFiles[0])
+		if err != nil || string(data) != "video-bytes" {
+			t.Errorf("expected saved file to contain the video bytes, but got '%s' (err: %v)", data, err)
+		}
+	})
+
+	t.Run("saved locally with prefix", func(t *testing.T) {
+		outputDir := t.TempDir()
+		outputs := collectGeneratedVideos(t.Context(), operation, outputDir, "campaign", "", "veo-2.0-generate-001")
+		if len(outputs.LocalFiles) != 1 {
+			t.Fatalf("expected 1 local file, but got %+v", outputs)
+		}
+		if expected := filepath.Join(outputDir, "campaign_0.mp4"); outputs.LocalFiles[0] != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, outputs.LocalFiles[0])
+		}
+	})
+
+	t.Run("saved locally with output URI", func(t *testing.T) {
+		outputDir := t.TempDir()
+		outputs := collectGeneratedVideos(t.Context(), operation, outputDir, "", "gs://bucket/renders/final.mp4", "veo-2.0-generate-001")
+		if len(outputs.LocalFiles) != 1 {
+			t.Fatalf("expected 1 local file, but got %+v", outputs)
+		}
+		if expected := filepath.Join(outputDir, "final.mp4"); outputs.LocalFiles[0] != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, outputs.LocalFiles[0])
+		}
+	})
+}
+
+func TestVideoToolResultJSON(t *testing.T) {
+	outputs := &videoOutputs{GCSURIs: []string{"gs://bucket/video.mp4"}}
+	summary := videoResult{Status: "completed", OperationName: "operations/123", Model: "veo-2.0-generate-001", Duration: 8}
+
+	result, err := videoToolResult("json", "Generated 1 video(s).", summary, outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &decoded); err != nil {
+		t.Fatalf("expected JSON content, but got error: %v", err)
+	}
+	for _, field := range []string{"gcs_uris", "local_paths", "model", "duration", "operation_name"} {
+		if _, ok := decoded[field]; !ok {
+			t.Errorf("expected field '%s' in %v", field, decoded)
+		}
+	}
+	if decoded["operation_name"] != "operations/123" {
+		t.Errorf("expected 'operations/123', but got '%v'", decoded["operation_name"])
+	}
+}
+
+func TestDryRunResult(t *testing.T) {
+	params := &VideoParams{
+		Model:          "veo-3.0-generate-001",
+		AspectRatio:    "16:9",
+		Resolution:     "1080p",
+		NumberOfVideos: 2,
+		DurationSecs:   8,
+		GenerateAudio:  true,
+		OutputFormat:   "json",
+	}
+	config := params.GenerateVideosConfig()
+	image := &genai.Image{GCSURI: "gs://bucket/first.png", MIMEType: "image/png"}
+
+	result, err := dryRunResult(context.Background(), "i2v", "a cat", image, nil, config, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected a success result")
+	}
+
+	var resolved dryRunConfig
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &resolved); err != nil {
+		t.Fatalf("failed to unmarshal dry run result: %v", err)
+	}
+	if resolved.Status != "dry_run" {
+		t.Errorf("expected 'dry_run', but got '%s'", resolved.Status)
+	}
+	if resolved.Model != params.Model {
+		t.Errorf("expected '%s', but got '%s'", params.Model, resolved.Model)
+	}
+	if resolved.NumberOfVideos != 2 || resolved.DurationSecs != 8 {
+		t.Errorf("expected 2 video(s) of 8s, but got %d video(s) of %ds", resolved.NumberOfVideos, resolved.DurationSecs)
+	}
+	if resolved.InputImage != "gs://bucket/first.png (image/png)" {
+		t.Errorf("expected 'gs://bucket/first.png (image/png)', but got '%s'", resolved.InputImage)
+	}
+}
+
+func TestGenerateAspectRatioBatch(t *testing.T) {
+	generate := func(failing ...string) func(string) (*mcp.CallToolResult, error) {
+		return func(aspectRatio string) (*mcp.CallToolResult, error) {
+			for _, f := range failing {
+				if f == aspectRatio {
+					return mcp.NewToolResultError("generation failed for " + aspectRatio), nil
+				}
+			}
+			return mcp.NewToolResultText("generated " + aspectRatio), nil
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		failing        []string
+		expectedStatus string
+		expectedError  bool
+	}{
+		{"all succeeded", nil, "completed", false},
+		{"partial failure", []string{"9:16"}, "partial", false},
+		{"all failed", []string{"16:9", "9:16"}, "failed", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := generateAspectRatioBatch(t.Context(), []string{"16:9", "9:16"}, "json", generate(tc.failing...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsError != tc.expectedError {
+				t.Errorf("expected IsError to be %v, but got %v", tc.expectedError, result.IsError)
+			}
+			batch, ok := result.StructuredContent.(aspectRatioBatchResult)
+			if !ok {
+				t.Fatalf("expected structured content of type aspectRatioBatchResult, but got %T", result.StructuredContent)
+			}
+			if batch.Status != tc.expectedStatus {
+				t.Errorf("expected '%s', but got '%s'", tc.expectedStatus, batch.Status)
+			}
+			if len(batch.Results) != 2 || batch.Results[0].AspectRatio != "16:9" || batch.Results[1].AspectRatio != "9:16" {
+				t.Errorf("expected results in request order, but got %+v", batch.Results)
+			}
+		})
+	}
+}
+
+func TestPollingStoppedResultRequestTimeout(t *testing.T) {
+	params := &VideoParams{Timeout: time.Millisecond}
+	ctx, cancel := params.WithTimeout(t.Context())
+	defer cancel()
+	<-ctx.Done()
+
+	result := pollingStoppedResult(ctx, "t2v", "projects/p/operations/op-1", time.Minute)
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "did not complete within 1ms set by timeout_seconds") || !strings.Contains(text, "projects/p/operations/op-1") {
+		t.Errorf("expected a timeout error with the operation name, but got '%s'", text)
+	}
+
+	canceledCtx, cancelClient := context.WithCancel(t.Context())
+	cancelClient()
+	text = pollingStoppedResult(canceledCtx, "t2v", "projects/p/operations/op-1", time.Minute).Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "canceled by the client") {
+		t.Errorf("expected a client cancellation error, but got '%s'", text)
+	}
+}
+
+func TestVideoParamsWithoutTimeout(t *testing.T) {
+	ctx, cancel := (&VideoParams{}).WithTimeout(t.Context())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without timeout_seconds")
+	}
+}
+
+func TestEnhancedPromptFromOperation(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation *genai.GenerateVideosOperation
+		expected  string
+	}{
+		{"nil operation", nil, ""},
+		{"no metadata", &genai.GenerateVideosOperation{}, ""},
+		{"camel case", &genai.GenerateVideosOperation{Metadata: map[string]any{"enhancedPrompt": " A red fox runs through snow. "}}, "A red fox runs through snow."},
+		{"rewritten", &genai.GenerateVideosOperation{Metadata: map[string]any{"rewritten_prompt": "A city at night."}}, "A city at night."},
+		{"empty", &genai.GenerateVideosOperation{Metadata: map[string]any{"enhancedPrompt": "  "}}, ""},
+		{"wrong type", &genai.GenerateVideosOperation{Metadata: map[string]any{"enhancedPrompt": 1.0}}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := enhancedPromptFromOperation(tc.operation); actual != tc.expected {
+				t.Errorf("expected '%s', but got '%s'", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNoVideosMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response *genai.GenerateVideosResponse
+		expected []string
+	}{
+		{"no response", nil, []string{"likely filtered", "no filter details"}},
+		{"empty response", &genai.GenerateVideosResponse{}, []string{"no filter details"}},
+		{"filtered with reasons", &genai.GenerateVideosResponse{
+			RAIMediaFilteredCount:   2,
+			RAIMediaFilteredReasons: []string{"violates the usage guidelines", "contains a celebrity"},
+		}, []string{"Videos filtered: 2.", "Reasons: violates the usage guidelines; contains a celebrity.", "rephrasing"}},
+		{"filtered without reasons", &genai.GenerateVideosResponse{RAIMediaFilteredCount: 1}, []string{"Videos filtered: 1."}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message := noVideosMessage("t2v", "veo-3.0-generate-001", "operations/1", tc.response)
+			if !strings.Contains(message, "operations/1") {
+				t.Errorf("expected the message to name the operation, but got '%s'", message)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(message, expected) {
+					t.Errorf("expected the message to contain '%s', but got '%s'", expected, message)
+				}
+			}
+		})
+	}
+}
+
+func TestFilteredVideosFromResponse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response *genai.GenerateVideosResponse
+		expected []filteredVideo
+		message  string
+	}{
+		{"no response", nil, nil, ""},
+		{"nothing filtered", &genai.GenerateVideosResponse{}, nil, ""},
+		{"count and reasons", &genai.GenerateVideosResponse{RAIMediaFilteredCount: 2, RAIMediaFilteredReasons: []string{"unsafe content", "celebrity likeness"}},
+			[]filteredVideo{{Reason: "unsafe content"}, {Reason: "celebrity likeness"}}, "2 video(s) were removed by the safety filters: unsafe content; celebrity likeness."},
+		{"more filtered than reasons", &genai.GenerateVideosResponse{RAIMediaFilteredCount: 2, RAIMediaFilteredReasons: []string{"unsafe content"}},
+			[]filteredVideo{{Reason: "unsafe content"}, {}}, "2 video(s) were removed by the safety filters: unsafe content."},
+		{"count only", &genai.GenerateVideosResponse{RAIMediaFilteredCount: 1},
+			[]filteredVideo{{}}, "1 video(s) were removed by the safety filters."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := filteredVideosFromResponse(tc.response)
+			if !slices.Equal(filtered, tc.expected) {
+				t.Errorf("expected %+v, but got %+v", tc.expected, filtered)
+			}
+			if message := filteredVideosMessage(filtered); message != tc.message {
+				t.Errorf("expected message '%s', but got '%s'", tc.message, message)
+			}
+		})
+	}
+}